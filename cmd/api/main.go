@@ -1 +1,62 @@
-package api
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/api"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/config"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/database"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/logger"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/service"
+)
+
+func main() {
+	envFile := flag.String("env", ".env", "path to env file")
+	addr := flag.String("addr", ":8080", "address to listen on")
+	flag.Parse()
+
+	// ─── Load config ───────────────────────────────────────────
+	cfg, err := config.Load(*envFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// ─── Init logger ───────────────────────────────────────────
+	log, err := logger.New(cfg.Log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to init logger: %v\n", err)
+		os.Exit(1)
+	}
+	defer log.Sync()
+	sugar := log.Sugar()
+
+	// ─── Connect to DB (GORM) ─────────────────────────────────
+	db, err := database.Open(cfg)
+	if err != nil {
+		sugar.Fatalf("db connection failed: %v", err)
+	}
+
+	classifier, err := service.NewClassifier(cfg.ReviewClassifier)
+	if err != nil {
+		sugar.Fatalf("review classifier setup failed: %v", err)
+	}
+	repos := database.NewRepositories(cfg, db)
+	products := service.NewProductService(repos.Products, repos.ProductURLs, repos.CrawlRuns)
+	reviews := service.NewReviewService(repos.Reviews, repos.Products, classifier)
+
+	// ─── Serve ────────────────────────────────────────────────
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           api.NewServer(products, reviews, sugar).Routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	sugar.Infof("API listening on %s", *addr)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		sugar.Fatalf("api server failed: %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"html"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/config"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/database"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/service"
+)
+
+const usage = `usage: cli [-env FILE] <command> [arguments]
+
+commands:
+  search [-limit N] <query>   full-text search over products and reviews
+  keyword [-limit N] <word>   list products linked to a keyword
+  reindex-keywords            re-extract the keywords of every product
+  analyze-reviews             re-tag every review with sentiment and topics
+  diff <code> <runA> <runB>   show how a product changed between two crawl runs
+  enqueue-coordinated <code>  queue the coordinated products not crawled yet
+`
+
+func main() {
+	envFile := flag.String("env", ".env", "path to env file")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// ─── Load config ───────────────────────────────────────────
+	cfg, err := config.Load(*envFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// ─── Connect to DB (GORM) ─────────────────────────────────
+	db, err := database.Open(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "db connection failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	repos := database.NewRepositories(cfg, db)
+	products := service.NewProductService(repos.Products, repos.ProductURLs, repos.CrawlRuns)
+
+	ctx := context.Background()
+	cmd, args := flag.Arg(0), flag.Args()[1:]
+	switch cmd {
+	case "search":
+		err = runSearch(ctx, products, args)
+	case "analyze-reviews":
+		err = runAnalyzeReviews(ctx, repos.Reviews, repos.Products, cfg.ReviewClassifier)
+	case "keyword":
+		err = runKeyword(ctx, products, args)
+	case "diff":
+		err = runDiff(ctx, products, args)
+	case "enqueue-coordinated":
+		err = runEnqueueCoordinated(ctx, products, args)
+	case "reindex-keywords":
+		var n int
+		if n, err = products.ReindexKeywords(ctx); err == nil {
+			fmt.Printf("reindexed keywords of %d products\n", n)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", cmd)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cmd, err)
+		os.Exit(1)
+	}
+}
+
+// runSearch prints the products matching the query, best first, with the
+// matched terms of each highlight shown in brackets.
+func runSearch(ctx context.Context, products *service.ProductService, args []string) error {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	limit := fs.Int("limit", 20, "max number of results")
+	fs.Parse(args)
+
+	q := strings.Join(fs.Args(), " ")
+	if strings.TrimSpace(q) == "" {
+		return fmt.Errorf("missing query")
+	}
+
+	results, err := products.Search(ctx, q, *limit)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		fmt.Println("no matches")
+		return nil
+	}
+
+	marks := strings.NewReplacer("<mark>", "[", "</mark>", "]")
+	for i, r := range results {
+		fmt.Printf("%2d. %s  %s  ¥%.0f  (score %.2f, %d reviews)\n", i+1, r.ProductCode, r.Name, r.PriceYen, r.Score, r.ReviewMatches)
+		for _, field := range []string{"name", "description", "review"} {
+			if s, ok := r.Highlights[field]; ok {
+				fmt.Printf("    %-11s %s\n", field+":", html.UnescapeString(marks.Replace(s)))
+			}
+		}
+	}
+	return nil
+}
+
+// runKeyword prints the products linked to a keyword.
+func runKeyword(ctx context.Context, products *service.ProductService, args []string) error {
+	fs := flag.NewFlagSet("keyword", flag.ExitOnError)
+	limit := fs.Int("limit", 50, "max number of products")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("want exactly one keyword")
+	}
+
+	linked, err := products.ByKeyword(ctx, fs.Arg(0), *limit)
+	if err != nil {
+		return err
+	}
+	if len(linked) == 0 {
+		fmt.Println("no products")
+		return nil
+	}
+	for _, p := range linked {
+		fmt.Printf("%s  %s  ¥%.0f  %s\n", p.ProductCode, p.Name, p.PriceYen, p.DetailsURL)
+	}
+	return nil
+}
+
+// runDiff prints the field-level changes of a product's attributes between
+// the end of two crawl runs.
+func runDiff(ctx context.Context, products *service.ProductService, args []string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("want a product code and two crawl run IDs")
+	}
+	var runs [2]uint
+	for i, arg := range args[1:] {
+		id, err := strconv.ParseUint(arg, 10, 0)
+		if err != nil {
+			return fmt.Errorf("invalid crawl run ID %q", arg)
+		}
+		runs[i] = uint(id)
+	}
+
+	changes, err := products.DiffRuns(ctx, args[0], runs[0], runs[1])
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Println("no changes")
+		return nil
+	}
+	for _, c := range changes {
+		if c.Added != nil || c.Removed != nil {
+			fmt.Printf("%s:\n", c.Field)
+			for _, v := range c.Removed {
+				fmt.Printf("  - %s\n", v)
+			}
+			for _, v := range c.Added {
+				fmt.Printf("  + %s\n", v)
+			}
+			continue
+		}
+		fmt.Printf("%s: %v -> %v\n", c.Field, c.From, c.To)
+	}
+	return nil
+}
+
+// runEnqueueCoordinated records the coordinated products of a product that
+// are not stored yet as product URLs, for the next incremental crawl.
+func runEnqueueCoordinated(ctx context.Context, products *service.ProductService, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("want exactly one product code")
+	}
+	queued, err := products.EnqueueCoordinated(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if len(queued) == 0 {
+		fmt.Println("nothing to enqueue")
+		return nil
+	}
+	for _, u := range queued {
+		fmt.Printf("%s  %s\n", u.Code, u.URL)
+	}
+	fmt.Printf("queued %d products for the next incremental crawl\n", len(queued))
+	return nil
+}
+
+// runAnalyzeReviews re-tags the reviews of every stored product.
+func runAnalyzeReviews(ctx context.Context, reviews service.ReviewRepository, products service.ProductRepository, classifierName string) error {
+	classifier, err := service.NewClassifier(classifierName)
+	if err != nil {
+		return err
+	}
+	n, err := service.NewReviewService(reviews, products, classifier).AnalyzeAll(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("tagged %d reviews with %s\n", n, classifier.Name())
+	return nil
+}
@@ -1,23 +1,32 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"github.com/jakib01/web-crawiling-golang-colly/internal/crawler/adidas"
 	"os"
-
-	_ "github.com/lib/pq"
+	"time"
 
 	"github.com/jakib01/web-crawiling-golang-colly/internal/config"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/database"
 	"github.com/jakib01/web-crawiling-golang-colly/internal/logger"
-	//"github.com/jakib01/web-crawiling-golang-colly/internal/repository/postgres"
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/metrics"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/proxy"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/service"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/tracing"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/worker"
+	"gorm.io/datatypes"
 )
 
 func main() {
 	envFile := flag.String("env", ".env", "path to env file")
 	limit := flag.Int("limit", 10, "max number of products to crawl")
+	mode := flag.String("mode", "full", "crawl mode: full (discover and fetch) or incremental (fetch due URLs only)")
+	budget := flag.Int("budget", 100, "max number of due URLs to fetch in incremental mode")
+	detectDelisted := flag.Bool("detect-delisted", false, "after a full crawl, check products missing from the listings and mark gone ones delisted")
 	flag.Parse()
 
 	// ─── Load config ───────────────────────────────────────────
@@ -28,7 +37,7 @@ func main() {
 	}
 
 	// ─── Init logger ───────────────────────────────────────────
-	log, err := logger.New(cfg.LogLevel)
+	log, err := logger.New(cfg.Log)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to init logger: %v\n", err)
 		os.Exit(1)
@@ -37,36 +46,132 @@ func main() {
 
 	// get a SugaredLogger for fmt-style methods
 	sugar := log.Sugar()
-	sugar.Infof("Starting crawler with limit=%d", *limit)
-
-	// ─── Build DB connection string ───────────────────────────
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%d sslmode=%s",
-		cfg.DBHost,
-		cfg.DBUser,
-		cfg.DBPassword,
-		cfg.DBName,
-		cfg.DBPort,
-		cfg.DBSSLMode,
-	)
+	sugar.Infof("Starting %s crawler with limit=%d budget=%d", *mode, *limit, *budget)
+
+	if *mode != "full" && *mode != "incremental" {
+		sugar.Fatalf("unknown mode %q (want full or incremental)", *mode)
+	}
+
+	metrics.Serve(cfg.MetricsAddr, func(err error) {
+		sugar.Errorf("metrics listener failed: %v", err)
+	})
+
+	// ─── Init tracing ─────────────────────────────────────────
+	ctx := context.Background()
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing, "crawler")
+	if err != nil {
+		sugar.Fatalf("tracing setup failed: %v", err)
+	}
+	flushTraces := func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			sugar.Warnf("failed to flush traces: %v", err)
+		}
+	}
+	defer flushTraces()
 
 	// ─── Connect to DB (GORM) ─────────────────────────────────
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := database.Open(cfg)
 	if err != nil {
 		sugar.Fatalf("db connection failed: %v", err)
 	}
 
+	proxies, err := proxy.NewPoolFromConfig(cfg.Crawler.Proxy)
+	if err != nil {
+		sugar.Fatalf("proxy setup failed: %v", err)
+	}
+	if proxies.Len() > 0 {
+		sugar.Infof("Using %d proxies", proxies.Len())
+	}
+
+	classifier, err := service.NewClassifier(cfg.ReviewClassifier)
+	if err != nil {
+		sugar.Fatalf("review classifier setup failed: %v", err)
+	}
+	repos := database.NewRepositories(cfg, db)
+	products := service.NewProductService(repos.Products, repos.ProductURLs, repos.CrawlRuns)
+	reviews := service.NewReviewService(repos.Reviews, repos.Products, classifier)
+
+	c := adidas.NewAdidasCrawler(products, cfg.Crawler, cfg.BrowserProfiles[cfg.Crawler.BrowserProfile], proxies, sugar)
+
+	// changed products get their reviews re-tagged
+	products.Subscribe(reviews.OnProductEvent(func(ev service.ProductEvent, err error) {
+		sugar.Warnw("failed to analyze reviews", "product_code", ev.ProductCode, "stage", "reviews", "error", err)
+		c.Stats().Error("reviews")
+	}))
+	products.Subscribe(products.RecordSnapshots(func(ev service.ProductEvent, err error) {
+		sugar.Warnw("failed to record product snapshot", "product_code", ev.ProductCode, "stage", "snapshot", "error", err)
+		c.Stats().Error("snapshot")
+	}))
+
+	// ─── Open run ledger entry ────────────────────────────────
+	run := &model.CrawlRun{
+		StartedAt:      time.Now(),
+		Mode:           *mode,
+		Status:         model.CrawlRunRunning,
+		ConfigSnapshot: toJSON(cfg.Crawler.Redacted()),
+		Seeds:          toJSON(c.Seeds()),
+		Errors:         datatypes.JSON("{}"),
+	}
+	if err := products.StartRun(ctx, run); err != nil {
+		sugar.Fatalf("failed to record crawl run: %v", err)
+	}
+
+	// every log line of this run carries its run_id, every snapshot its run
+	ctx, sugar = logger.With(ctx, sugar, "run_id", run.ID)
+	ctx = service.WithCrawlRun(ctx, run.ID)
+
 	// ─── Start crawl ──────────────────────────────────────────
-	c := adidas.NewAdidasCrawler(db, sugar)
-	products, err := c.CrawlProducts(*limit)
+	if *mode == "full" {
+		_, err = c.CrawlProducts(ctx, *limit)
+	} else {
+		_, err = c.CrawlDue(ctx, *budget)
+	}
+
+	if err == nil && *detectDelisted && *mode == "full" {
+		if _, derr := c.DetectDelisted(ctx, run.StartedAt, *budget); derr != nil {
+			sugar.Errorf("delisting check failed: %v", derr)
+		}
+	}
+
+	finishRun(run, c.Stats(), err)
+	if serr := products.SaveRun(ctx, run); serr != nil {
+		sugar.Errorf("failed to update crawl run %d: %v", run.ID, serr)
+	}
+
 	if err != nil {
-		sugar.Fatalf("crawl failed: %v", err)
+		flushTraces()
+		sugar.Fatalf("crawl %d failed: %v", run.ID, err)
+	}
+
+	sugar.Infof("✅ Crawl %d finished: discovered=%d fetched=%d parsed=%d failed=%d stored=%d",
+		run.ID, run.Discovered, run.Fetched, run.Parsed, run.Failed, run.Stored)
+}
+
+// finishRun copies the crawler's statistics onto the ledger entry.
+func finishRun(run *model.CrawlRun, stats *worker.Aggregator, crawlErr error) {
+	now := time.Now()
+	run.FinishedAt = &now
+	run.Status = model.CrawlRunSucceeded
+	if crawlErr != nil {
+		run.Status = model.CrawlRunFailed
+		run.ErrorMessage = crawlErr.Error()
 	}
 
-	//repo := postgres.NewProductRepository(db)
-	//if err := repo.BulkInsert(products); err != nil {
-	//	sugar.Fatalf("insert failed: %v", err)
-	//}
+	run.Discovered = stats.Count("discovered")
+	run.Fetched = stats.Count("fetched")
+	run.Parsed = stats.Count("parsed")
+	run.Failed = stats.Count("failed")
+	run.Stored = stats.Count("stored")
+	run.Errors = toJSON(stats.Errors())
+	run.DurationP50Ms = stats.Percentile(50).Milliseconds()
+	run.DurationP90Ms = stats.Percentile(90).Milliseconds()
+	run.DurationP99Ms = stats.Percentile(99).Milliseconds()
+}
 
-	sugar.Infof("✅ Successfully crawled and stored %d products", len(products))
+func toJSON(v interface{}) datatypes.JSON {
+	out, err := json.Marshal(v)
+	if err != nil {
+		return datatypes.JSON("null")
+	}
+	return out
 }
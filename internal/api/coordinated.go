@@ -0,0 +1,19 @@
+package api
+
+import (
+	"net/http"
+)
+
+// coordinatedProducts serves GET /products/{code}/coordinated?limit=N: the
+// products most frequently coordinated with the product, best first. The
+// product itself need not have been crawled.
+func (s *Server) coordinatedProducts(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	ranked, err := s.products.FrequentlyCoordinated(r.Context(), code, queryLimit(r, 20))
+	if err != nil {
+		s.logger.Errorf("coordinated products %s: %v", code, err)
+		s.writeError(w, http.StatusInternalServerError, "failed to rank coordinated products")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, ranked)
+}
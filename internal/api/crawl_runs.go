@@ -0,0 +1,41 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/repository"
+)
+
+// listCrawlRuns serves GET /crawl-runs?limit=N, newest first.
+func (s *Server) listCrawlRuns(w http.ResponseWriter, r *http.Request) {
+	runs, err := s.products.Runs(r.Context(), queryLimit(r, 20))
+	if err != nil {
+		s.logger.Errorf("list crawl runs: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "failed to list crawl runs")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, runs)
+}
+
+// getCrawlRun serves GET /crawl-runs/{id}.
+func (s *Server) getCrawlRun(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid crawl run id")
+		return
+	}
+
+	run, err := s.products.Run(r.Context(), uint(id))
+	if errors.Is(err, repository.ErrNotFound) {
+		s.writeError(w, http.StatusNotFound, "crawl run not found")
+		return
+	}
+	if err != nil {
+		s.logger.Errorf("find crawl run %d: %v", id, err)
+		s.writeError(w, http.StatusInternalServerError, "failed to load crawl run")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, run)
+}
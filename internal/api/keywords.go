@@ -0,0 +1,19 @@
+package api
+
+import (
+	"net/http"
+)
+
+// productsByKeyword serves GET /keywords/{keyword}/products?limit=N. The
+// keyword is normalized like extracted keywords, so "ＳＡＭＢＡ" finds
+// products linked to "samba".
+func (s *Server) productsByKeyword(w http.ResponseWriter, r *http.Request) {
+	kw := r.PathValue("keyword")
+	products, err := s.products.ByKeyword(r.Context(), kw, queryLimit(r, 50))
+	if err != nil {
+		s.logger.Errorf("products by keyword %q: %v", kw, err)
+		s.writeError(w, http.StatusInternalServerError, "failed to load products")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, products)
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/repository"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/snapshot"
+)
+
+// listProducts serves GET /products?category=&status=&group=&min_price=
+// &max_price=&limit=N&offset=N&details=1, ordered by product code.
+func (s *Server) listProducts(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := model.ProductFilter{
+		Category:    q.Get("category"),
+		Status:      q.Get("status"),
+		GroupKey:    q.Get("group"),
+		Limit:       queryLimit(r, 50),
+		Offset:      queryInt(r, "offset", 0),
+		WithDetails: q.Get("details") == "1" || q.Get("details") == "true",
+	}
+	var err error
+	if f.MinPrice, err = queryPrice(r, "min_price"); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid min_price")
+		return
+	}
+	if f.MaxPrice, err = queryPrice(r, "max_price"); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid max_price")
+		return
+	}
+
+	products, err := s.products.Products(r.Context(), f)
+	if err != nil {
+		s.logger.Errorf("list products: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "failed to list products")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, products)
+}
+
+// getProduct serves GET /products/{code}.
+func (s *Server) getProduct(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	product, err := s.products.Product(r.Context(), code)
+	if errors.Is(err, repository.ErrNotFound) {
+		s.writeError(w, http.StatusNotFound, "product not found")
+		return
+	}
+	if err != nil {
+		s.logger.Errorf("find product %s: %v", code, err)
+		s.writeError(w, http.StatusInternalServerError, "failed to load product")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, product)
+}
+
+// productVariants serves GET /products/{code}/variants, every colorway of
+// the product's model ordered by product code, the product included. A
+// product whose detail page had no productGroupID is not grouped and has
+// no variants.
+func (s *Server) productVariants(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	if _, err := s.products.Product(r.Context(), code); errors.Is(err, repository.ErrNotFound) {
+		s.writeError(w, http.StatusNotFound, "product not found")
+		return
+	} else if err != nil {
+		s.logger.Errorf("find product %s: %v", code, err)
+		s.writeError(w, http.StatusInternalServerError, "failed to load product")
+		return
+	}
+
+	variants, err := s.products.Variants(r.Context(), code)
+	if err != nil {
+		s.logger.Errorf("variants %s: %v", code, err)
+		s.writeError(w, http.StatusInternalServerError, "failed to load variants")
+		return
+	}
+	if variants == nil {
+		variants = []model.Product{}
+	}
+	s.writeJSON(w, http.StatusOK, variants)
+}
+
+// productStatusHistory serves GET /products/{code}/status-history, the
+// product's lifecycle transitions oldest first.
+func (s *Server) productStatusHistory(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	history, err := s.products.StatusHistory(r.Context(), code)
+	if errors.Is(err, repository.ErrNotFound) {
+		s.writeError(w, http.StatusNotFound, "product not found")
+		return
+	}
+	if err != nil {
+		s.logger.Errorf("status history %s: %v", code, err)
+		s.writeError(w, http.StatusInternalServerError, "failed to load status history")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, history)
+}
+
+// productHistory serves GET /products/{code}/history, the stored versions
+// of the product's attributes oldest first with their field-level changes.
+// With from=<run>&to=<run> it returns only the changes between the end of
+// those two crawl runs.
+func (s *Server) productHistory(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	q := r.URL.Query()
+	if q.Has("from") || q.Has("to") {
+		from, ferr := strconv.ParseUint(q.Get("from"), 10, 0)
+		to, terr := strconv.ParseUint(q.Get("to"), 10, 0)
+		if ferr != nil || terr != nil {
+			s.writeError(w, http.StatusBadRequest, "from and to must both be crawl run IDs")
+			return
+		}
+		changes, err := s.products.DiffRuns(r.Context(), code, uint(from), uint(to))
+		if errors.Is(err, repository.ErrNotFound) {
+			s.writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			s.logger.Errorf("diff %s runs %d..%d: %v", code, from, to, err)
+			s.writeError(w, http.StatusInternalServerError, "failed to diff product history")
+			return
+		}
+		if changes == nil {
+			changes = []snapshot.Change{}
+		}
+		s.writeJSON(w, http.StatusOK, changes)
+		return
+	}
+
+	versions, err := s.products.History(r.Context(), code)
+	if errors.Is(err, repository.ErrNotFound) {
+		s.writeError(w, http.StatusNotFound, "product not found")
+		return
+	}
+	if err != nil {
+		s.logger.Errorf("history %s: %v", code, err)
+		s.writeError(w, http.StatusInternalServerError, "failed to load product history")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, versions)
+}
+
+// queryPrice reads an optional non-negative price query parameter.
+func queryPrice(r *http.Request, name string) (float64, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	price, err := strconv.ParseFloat(v, 64)
+	if err == nil && price < 0 {
+		err = errors.New("negative price")
+	}
+	return price, err
+}
@@ -0,0 +1,69 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/api"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/repository/memory"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/service"
+	"go.uber.org/zap"
+)
+
+func TestProductVariants(t *testing.T) {
+	ctx := context.Background()
+	products := memory.NewProductRepository()
+	svc := service.NewProductService(products, memory.NewProductURLRepository(products), memory.NewCrawlRunRepository())
+	samba := &model.ProductGroup{GroupKey: "pg:IE3437", Name: "サンバ OG / Samba OG"}
+	for _, p := range []*model.Product{
+		{ProductCode: "JI2734", Name: "サンバ OG / Samba OG", ProductGroup: samba},
+		{ProductCode: "B75806", Name: "サンバ OG / Samba OG", ProductGroup: samba},
+		{ProductCode: "IG1025", Name: "ガゼル / Gazelle", ProductGroup: &model.ProductGroup{GroupKey: "pg:IG1025", Name: "ガゼル / Gazelle"}},
+		{ProductCode: "HQ8717", Name: "スタンスミス / Stan Smith"},
+	} {
+		if _, err := svc.Upsert(ctx, p); err != nil {
+			t.Fatalf("Upsert %s: %v", p.ProductCode, err)
+		}
+	}
+	routes := api.NewServer(svc, nil, zap.NewNop().Sugar()).Routes()
+
+	tests := []struct {
+		code   string
+		status int
+		want   []string
+	}{
+		{"JI2734", http.StatusOK, []string{"B75806", "JI2734"}},
+		{"IG1025", http.StatusOK, []string{"IG1025"}},
+		{"HQ8717", http.StatusOK, []string{}},
+		{"XX0000", http.StatusNotFound, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			routes.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/products/"+tt.code+"/variants", nil))
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.want == nil {
+				return
+			}
+
+			var got []model.Product
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || got == nil {
+				t.Fatalf("decode %q: %v", rec.Body, err)
+			}
+			codes := []string{}
+			for _, p := range got {
+				codes = append(codes, p.ProductCode)
+			}
+			if !reflect.DeepEqual(codes, tt.want) {
+				t.Errorf("variants = %v, want %v", codes, tt.want)
+			}
+		})
+	}
+}
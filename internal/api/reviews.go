@@ -0,0 +1,25 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/repository"
+)
+
+// reviewSummary serves GET /products/{code}/reviews/summary: review counts
+// by sentiment and, per topic, how many reviews mention it and how they felt.
+func (s *Server) reviewSummary(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	summary, err := s.reviews.ProductSummary(r.Context(), code)
+	if errors.Is(err, repository.ErrNotFound) {
+		s.writeError(w, http.StatusNotFound, "product not found")
+		return
+	}
+	if err != nil {
+		s.logger.Errorf("review summary %s: %v", code, err)
+		s.writeError(w, http.StatusInternalServerError, "failed to load review summary")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, summary)
+}
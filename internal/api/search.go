@@ -0,0 +1,24 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// searchProducts serves GET /search?q=...&limit=N. Terms are separated by
+// spaces, full-width included, and must all match.
+func (s *Server) searchProducts(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		s.writeError(w, http.StatusBadRequest, "missing query parameter q")
+		return
+	}
+
+	results, err := s.products.Search(r.Context(), q, queryLimit(r, 20))
+	if err != nil {
+		s.logger.Errorf("search %q: %v", q, err)
+		s.writeError(w, http.StatusInternalServerError, "search failed")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, results)
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/metrics"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/service"
+	"go.uber.org/zap"
+)
+
+// Server exposes the crawled data over HTTP.
+type Server struct {
+	products *service.ProductService
+	reviews  *service.ReviewService
+	logger   *zap.SugaredLogger
+}
+
+func NewServer(products *service.ProductService, reviews *service.ReviewService, logger *zap.SugaredLogger) *Server {
+	return &Server{products: products, reviews: reviews, logger: logger}
+}
+
+// Routes returns the HTTP handler serving every API endpoint.
+func (s *Server) Routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler())
+	mux.HandleFunc("GET /crawl-runs", s.listCrawlRuns)
+	mux.HandleFunc("GET /crawl-runs/{id}", s.getCrawlRun)
+	mux.HandleFunc("GET /products", s.listProducts)
+	mux.HandleFunc("GET /products/{code}", s.getProduct)
+	mux.HandleFunc("GET /products/{code}/variants", s.productVariants)
+	mux.HandleFunc("GET /products/{code}/status-history", s.productStatusHistory)
+	mux.HandleFunc("GET /products/{code}/history", s.productHistory)
+	mux.HandleFunc("GET /products/{code}/stock-history", s.stockHistory)
+	mux.HandleFunc("GET /stock/sell-through", s.sellThrough)
+	mux.HandleFunc("GET /products/{code}/coordinated", s.coordinatedProducts)
+	mux.HandleFunc("GET /search", s.searchProducts)
+	mux.HandleFunc("GET /keywords/{keyword}/products", s.productsByKeyword)
+	mux.HandleFunc("GET /products/{code}/reviews/summary", s.reviewSummary)
+	return metrics.Middleware(mux)
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.logger.Warnf("failed to write response: %v", err)
+	}
+}
+
+func (s *Server) writeError(w http.ResponseWriter, status int, msg string) {
+	s.writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// maxLimit caps the limit query parameter of every listing endpoint.
+const maxLimit = 200
+
+// queryLimit reads the limit query parameter, falling back to def and
+// capped at maxLimit.
+func queryLimit(r *http.Request, def int) int {
+	return min(queryInt(r, "limit", def), maxLimit)
+}
+
+// queryInt reads a positive integer query parameter, falling back to def.
+func queryInt(r *http.Request, name string, def int) int {
+	if v, err := strconv.Atoi(r.URL.Query().Get(name)); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
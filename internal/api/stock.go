@@ -0,0 +1,47 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/repository"
+)
+
+// stockHistory serves GET /products/{code}/stock-history: per size, the
+// stretches of time it was in or out of stock.
+func (s *Server) stockHistory(w http.ResponseWriter, r *http.Request) {
+	code := r.PathValue("code")
+	periods, err := s.products.StockHistory(r.Context(), code)
+	if errors.Is(err, repository.ErrNotFound) {
+		s.writeError(w, http.StatusNotFound, "product not found")
+		return
+	}
+	if err != nil {
+		s.logger.Errorf("stock history %s: %v", code, err)
+		s.writeError(w, http.StatusInternalServerError, "failed to load stock history")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, periods)
+}
+
+// Sell-through covers the last sellThroughDays days unless the days query
+// parameter asks for more, up to maxSellThroughDays.
+const (
+	sellThroughDays    = 90
+	maxSellThroughDays = 365
+)
+
+// sellThrough serves GET /stock/sell-through: per size label, how often and
+// how fast it sold out and how long restocks took, the sizes that sell out
+// first first.
+func (s *Server) sellThrough(w http.ResponseWriter, r *http.Request) {
+	days := min(queryInt(r, "days", sellThroughDays), maxSellThroughDays)
+	stats, err := s.products.SellThrough(r.Context(), time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		s.logger.Errorf("sell-through: %v", err)
+		s.writeError(w, http.StatusInternalServerError, "failed to compute sell-through")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, stats)
+}
@@ -0,0 +1,93 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/config"
+)
+
+// AllocatorOptions turns a profile into Chrome launch options. extra, such
+// as chromedp.ProxyServer, is applied last and wins over the profile.
+func AllocatorOptions(profile config.BrowserProfile, extra ...chromedp.ExecAllocatorOption) []chromedp.ExecAllocatorOption {
+	opts := append([]chromedp.ExecAllocatorOption{}, chromedp.DefaultExecAllocatorOptions[:]...)
+	opts = append(opts, chromedp.Flag("headless", profile.Headless))
+	if profile.UserAgent != "" {
+		opts = append(opts, chromedp.UserAgent(profile.UserAgent))
+	}
+	if profile.ViewportWidth > 0 && profile.ViewportHeight > 0 {
+		opts = append(opts, chromedp.WindowSize(profile.ViewportWidth, profile.ViewportHeight))
+	}
+	if profile.AcceptLanguage != "" {
+		opts = append(opts,
+			chromedp.Flag("lang", primaryLanguage(profile.AcceptLanguage)),
+			chromedp.Flag("accept-lang", profile.AcceptLanguage),
+		)
+	}
+	for _, flag := range profile.ExtraFlags {
+		name, value, ok := strings.Cut(flag, "=")
+		if ok {
+			opts = append(opts, chromedp.Flag(strings.TrimLeft(name, "-"), value))
+		} else {
+			opts = append(opts, chromedp.Flag(strings.TrimLeft(name, "-"), true))
+		}
+	}
+	return append(opts, extra...)
+}
+
+// Setup applies the parts of a profile that are set per tab rather than
+// at launch: the Accept-Language header and the timezone.
+func Setup(profile config.BrowserProfile) chromedp.Tasks {
+	var tasks chromedp.Tasks
+	if profile.UserAgent != "" {
+		override := emulation.SetUserAgentOverride(profile.UserAgent)
+		if profile.AcceptLanguage != "" {
+			override = override.WithAcceptLanguage(profile.AcceptLanguage)
+		}
+		tasks = append(tasks, override)
+	}
+	if profile.Timezone != "" {
+		tasks = append(tasks, emulation.SetTimezoneOverride(profile.Timezone))
+	}
+	return tasks
+}
+
+// Start launches a browser with the given profile and returns a tab context
+// ready to navigate, with requests filtered by rules. With proxyURL set,
+// all traffic goes through that proxy, and its credentials, which Chrome
+// ignores in --proxy-server, answer the proxy's authentication challenges.
+// Cancelling the returned func closes the browser.
+func Start(ctx context.Context, profile config.BrowserProfile, rules Rules, proxyURL *url.URL, extra ...chromedp.ExecAllocatorOption) (context.Context, context.CancelFunc, error) {
+	var proxyUser *url.Userinfo
+	if proxyURL != nil {
+		extra = append(extra, chromedp.ProxyServer(proxyURL.Scheme+"://"+proxyURL.Host))
+		proxyUser = proxyURL.User
+	}
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(ctx, AllocatorOptions(profile, extra...)...)
+	tabCtx, cancelTab := chromedp.NewContext(allocCtx)
+	cancel := func() {
+		cancelTab()
+		cancelAlloc()
+	}
+
+	tasks := Setup(profile)
+	if !rules.Empty() || proxyUser != nil {
+		tasks = append(tasks, intercept(rules, proxyUser))
+	}
+	if err := chromedp.Run(tabCtx, tasks); err != nil {
+		cancel()
+		return nil, nil, fmt.Errorf("start browser: %w", err)
+	}
+	return tabCtx, cancel, nil
+}
+
+// primaryLanguage returns the first language of an Accept-Language value.
+func primaryLanguage(acceptLanguage string) string {
+	lang, _, _ := strings.Cut(acceptLanguage, ",")
+	lang, _, _ = strings.Cut(lang, ";")
+	return strings.TrimSpace(lang)
+}
@@ -0,0 +1,133 @@
+package browser
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// Response is a JSON response body recorded by a Capture.
+type Response struct {
+	URL    string
+	Status int
+	Body   []byte
+}
+
+// Capture records the JSON responses of a tab whose URLs match any of its
+// patterns, so that data the page loads over XHR can be read directly
+// instead of from the rendered DOM.
+type Capture struct {
+	patterns []*regexp.Regexp
+
+	mu        sync.Mutex
+	pending   map[network.RequestID]*network.Response
+	responses []Response
+	inflight  sync.WaitGroup
+}
+
+// NewCapture returns a Capture for responses matching any of patterns. Nil
+// patterns are skipped.
+func NewCapture(patterns ...*regexp.Regexp) *Capture {
+	c := &Capture{pending: map[network.RequestID]*network.Response{}}
+	for _, p := range patterns {
+		if p != nil {
+			c.patterns = append(c.patterns, p)
+		}
+	}
+	return c
+}
+
+// Listen starts recording on the tab it runs in. It must run before the
+// navigation whose responses should be captured.
+func (c *Capture) Listen() chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if len(c.patterns) == 0 {
+			return nil
+		}
+		chromedp.ListenTarget(ctx, func(ev interface{}) {
+			switch ev := ev.(type) {
+			case *network.EventResponseReceived:
+				if c.wants(ev.Response) {
+					c.mu.Lock()
+					c.pending[ev.RequestID] = ev.Response
+					c.mu.Unlock()
+				}
+			case *network.EventLoadingFailed:
+				c.mu.Lock()
+				delete(c.pending, ev.RequestID)
+				c.mu.Unlock()
+			case *network.EventLoadingFinished:
+				c.mu.Lock()
+				resp, ok := c.pending[ev.RequestID]
+				delete(c.pending, ev.RequestID)
+				if ok {
+					c.inflight.Add(1)
+				}
+				c.mu.Unlock()
+				if !ok {
+					return
+				}
+				// the body is only available once loading finished, and the
+				// event handler must not block on fetching it
+				go func() {
+					defer c.inflight.Done()
+					body, err := network.GetResponseBody(ev.RequestID).Do(ctx)
+					if err != nil {
+						return
+					}
+					c.mu.Lock()
+					c.responses = append(c.responses, Response{URL: resp.URL, Status: int(resp.Status), Body: body})
+					c.mu.Unlock()
+				}()
+			}
+		})
+		return network.Enable().Do(ctx)
+	})
+}
+
+// Wait blocks until the bodies of finished responses were read or ctx is
+// done.
+func (c *Capture) Wait(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		c.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+// Find returns the successful responses whose URL matches pattern, in the
+// order they finished loading.
+func (c *Capture) Find(pattern *regexp.Regexp) []Response {
+	if pattern == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out []Response
+	for _, r := range c.responses {
+		if r.Status >= 200 && r.Status < 300 && pattern.MatchString(r.URL) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func (c *Capture) wants(resp *network.Response) bool {
+	if resp == nil || !strings.Contains(resp.MimeType, "json") {
+		return false
+	}
+	for _, p := range c.patterns {
+		if p.MatchString(resp.URL) {
+			return true
+		}
+	}
+	return false
+}
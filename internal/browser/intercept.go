@@ -0,0 +1,164 @@
+package browser
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/config"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/metrics"
+)
+
+// Rules decide which requests a headless session lets through.
+type Rules struct {
+	blockTypes   map[network.ResourceType]bool
+	blockDomains []string
+	allowDomains []string
+}
+
+// NewRules combines the resource config with the image and font blocking
+// switches of a browser profile.
+func NewRules(cfg config.ResourceConfig, profile config.BrowserProfile) Rules {
+	r := Rules{
+		blockTypes:   map[network.ResourceType]bool{},
+		blockDomains: normalizeDomains(cfg.BlockDomains),
+		allowDomains: normalizeDomains(cfg.AllowDomains),
+	}
+	for _, t := range cfg.BlockTypes {
+		r.blockTypes[resourceType(t)] = true
+	}
+	if profile.BlockImages {
+		r.blockTypes[network.ResourceTypeImage] = true
+	}
+	if profile.BlockFonts {
+		r.blockTypes[network.ResourceTypeFont] = true
+	}
+	return r
+}
+
+// Empty reports whether the rules let everything through, in which case
+// interception is not worth its round trips.
+func (r Rules) Empty() bool {
+	return len(r.blockTypes) == 0 && len(r.blockDomains) == 0 && len(r.allowDomains) == 0
+}
+
+// Blocks reports whether a request for rawURL of type t should be dropped.
+// Documents are never blocked.
+func (r Rules) Blocks(t network.ResourceType, rawURL string) bool {
+	if t == network.ResourceTypeDocument {
+		return false
+	}
+	if r.blockTypes[t] {
+		return true
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		// data: and blob: URLs never leave the browser
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if matchDomain(host, r.blockDomains) {
+		return true
+	}
+	return len(r.allowDomains) > 0 && !matchDomain(host, r.allowDomains)
+}
+
+// intercept pauses every request of the tab and fails or continues it
+// according to rules. With proxyUser set it also answers proxy
+// authentication challenges with those credentials, once per request so
+// that rejected credentials fail the request instead of looping. It must
+// run before the first navigation.
+func intercept(rules Rules, proxyUser *url.Userinfo) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		var (
+			mu       sync.Mutex
+			answered = map[fetch.RequestID]bool{}
+		)
+		chromedp.ListenTarget(ctx, func(ev interface{}) {
+			switch ev := ev.(type) {
+			case *fetch.EventRequestPaused:
+				// the event handler must not block, so answer from a goroutine
+				go func() {
+					blocked := rules.Blocks(ev.ResourceType, ev.Request.URL)
+					metrics.ObserveIntercept(string(ev.ResourceType), blocked)
+					if blocked {
+						_ = fetch.FailRequest(ev.RequestID, network.ErrorReasonBlockedByClient).Do(ctx)
+						return
+					}
+					_ = fetch.ContinueRequest(ev.RequestID).Do(ctx)
+				}()
+			case *fetch.EventAuthRequired:
+				mu.Lock()
+				retry := answered[ev.RequestID]
+				answered[ev.RequestID] = true
+				mu.Unlock()
+				go func() {
+					_ = fetch.ContinueWithAuth(ev.RequestID, authResponse(ev.AuthChallenge, proxyUser, retry)).Do(ctx)
+				}()
+			}
+		})
+		return fetch.Enable().WithHandleAuthRequests(proxyUser != nil).Do(ctx)
+	})
+}
+
+// authResponse answers a proxy's first challenge for a request with
+// proxyUser and cancels a repeated one. Challenges of the site itself get
+// Chrome's default handling.
+func authResponse(challenge *fetch.AuthChallenge, proxyUser *url.Userinfo, retry bool) *fetch.AuthChallengeResponse {
+	switch {
+	case challenge.Source != fetch.AuthChallengeSourceProxy:
+		return &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseDefault}
+	case retry || proxyUser == nil:
+		return &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseCancelAuth}
+	}
+	password, _ := proxyUser.Password()
+	return &fetch.AuthChallengeResponse{
+		Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+		Username: proxyUser.Username(),
+		Password: password,
+	}
+}
+
+// resourceType maps a configured name such as "image" to Chrome's
+// capitalized resource type.
+func resourceType(name string) network.ResourceType {
+	name = strings.TrimSpace(name)
+	for _, t := range []network.ResourceType{
+		network.ResourceTypeDocument, network.ResourceTypeStylesheet, network.ResourceTypeImage,
+		network.ResourceTypeMedia, network.ResourceTypeFont, network.ResourceTypeScript,
+		network.ResourceTypeTextTrack, network.ResourceTypeXHR, network.ResourceTypeFetch,
+		network.ResourceTypePrefetch, network.ResourceTypeEventSource, network.ResourceTypeWebSocket,
+		network.ResourceTypeManifest, network.ResourceTypePing, network.ResourceTypeOther,
+	} {
+		if strings.EqualFold(string(t), name) {
+			return t
+		}
+	}
+	return network.ResourceType(name)
+}
+
+func normalizeDomains(domains []string) []string {
+	out := make([]string, 0, len(domains))
+	for _, d := range domains {
+		d = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(d), "."))
+		if d != "" {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// matchDomain reports whether host is one of domains or a subdomain of one.
+func matchDomain(host string, domains []string) bool {
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
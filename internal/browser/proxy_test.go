@@ -0,0 +1,96 @@
+package browser
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/chromedp"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/config"
+)
+
+// chromePath returns the Chrome binary named by CHROME_PATH or found on
+// PATH, skipping the test when there is none.
+func chromePath(t *testing.T) string {
+	t.Helper()
+	if p := os.Getenv("CHROME_PATH"); p != "" {
+		return p
+	}
+	for _, name := range []string{"headless_shell", "chromium", "chromium-browser", "google-chrome", "chrome"} {
+		if p, err := exec.LookPath(name); err == nil {
+			return p
+		}
+	}
+	t.Skip("no Chrome found; set CHROME_PATH to run")
+	return ""
+}
+
+func TestStartAuthenticatesAgainstProxy(t *testing.T) {
+	chrome := chromePath(t)
+
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte("crawler:s3cret"))
+	var rejected, served atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Authorization") != want {
+			rejected.Add(1)
+			w.Header().Set("Proxy-Authenticate", `Basic realm="stand-in"`)
+			http.Error(w, "proxy auth required", http.StatusProxyAuthRequired)
+			return
+		}
+		served.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, `<html><head><title>via proxy</title></head><body></body></html>`)
+	}))
+	defer srv.Close()
+
+	proxyURL, _ := url.Parse(srv.URL)
+	proxyURL.User = url.UserPassword("crawler", "s3cret")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	profile := config.BrowserProfile{Headless: true}
+	tab, closeBrowser, err := Start(ctx, profile, Rules{}, proxyURL,
+		chromedp.ExecPath(chrome), chromedp.NoSandbox)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeBrowser()
+
+	var title string
+	if err := chromedp.Run(tab, chromedp.Navigate("http://shop.test/JI2734.html"), chromedp.Title(&title)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(title, "via proxy") || served.Load() == 0 {
+		t.Fatalf("title %q after %d rejected and %d served requests", title, rejected.Load(), served.Load())
+	}
+}
+
+func TestAuthResponse(t *testing.T) {
+	user := url.UserPassword("crawler", "s3cret")
+	proxy := &fetch.AuthChallenge{Source: fetch.AuthChallengeSourceProxy}
+	site := &fetch.AuthChallenge{Source: fetch.AuthChallengeSourceServer}
+
+	got := authResponse(proxy, user, false)
+	if got.Response != fetch.AuthChallengeResponseResponseProvideCredentials || got.Username != "crawler" || got.Password != "s3cret" {
+		t.Errorf("first proxy challenge: %+v", got)
+	}
+	if got := authResponse(proxy, user, true); got.Response != fetch.AuthChallengeResponseResponseCancelAuth {
+		t.Errorf("repeated proxy challenge: %+v, want CancelAuth", got)
+	}
+	if got := authResponse(proxy, nil, false); got.Response != fetch.AuthChallengeResponseResponseCancelAuth {
+		t.Errorf("proxy challenge without credentials: %+v, want CancelAuth", got)
+	}
+	if got := authResponse(site, user, false); got.Response != fetch.AuthChallengeResponseResponseDefault {
+		t.Errorf("site challenge: %+v, want Default", got)
+	}
+}
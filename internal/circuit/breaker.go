@@ -0,0 +1,101 @@
+package circuit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Breaker pauses all callers of Wait for a cooldown once Threshold
+// consecutive failures (blocks) were recorded. A success resets the count.
+// Once the cooldown has passed the breaker is half-open: the next outcome
+// decides alone, a failure reopening it at once and a success closing it.
+type Breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	halfOpen  bool
+	onChange  func(open bool)
+	now       func() time.Time
+}
+
+// New returns a breaker; onChange, if not nil, is called whenever it opens
+// or closes.
+func New(threshold int, cooldown time.Duration, onChange func(open bool)) *Breaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &Breaker{threshold: threshold, cooldown: cooldown, onChange: onChange, now: time.Now}
+}
+
+// Record registers the outcome of one request.
+func (b *Breaker) Record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	b.endCooldown(now)
+
+	if !failed {
+		b.failures = 0
+		b.halfOpen = false
+		return
+	}
+	if now.Before(b.openUntil) {
+		// requests started before the breaker opened
+		return
+	}
+	b.failures++
+	if b.halfOpen || b.failures >= b.threshold {
+		b.openUntil = now.Add(b.cooldown)
+		b.failures = 0
+		b.halfOpen = false
+		if b.onChange != nil {
+			b.onChange(true)
+		}
+	}
+}
+
+// Open reports whether the breaker currently pauses requests.
+func (b *Breaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	b.endCooldown(now)
+	return now.Before(b.openUntil)
+}
+
+// Wait blocks while the breaker is open, or until ctx is done.
+func (b *Breaker) Wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := b.now()
+		b.endCooldown(now)
+		remaining := b.openUntil.Sub(now)
+		b.mu.Unlock()
+		if remaining <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(remaining)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// endCooldown moves the breaker to half-open, firing onChange(false) once,
+// when an open period has passed. b.mu must be held.
+func (b *Breaker) endCooldown(now time.Time) {
+	if !b.openUntil.IsZero() && !now.Before(b.openUntil) {
+		b.openUntil = time.Time{}
+		b.halfOpen = true
+		if b.onChange != nil {
+			b.onChange(false)
+		}
+	}
+}
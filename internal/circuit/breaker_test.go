@@ -0,0 +1,125 @@
+package circuit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// newTestBreaker returns a breaker with a threshold of 3 and a 5 minute
+// cooldown on a clock that only moves when advance is called, and the
+// onChange calls it made.
+func newTestBreaker() (b *Breaker, advance func(time.Duration), changes *[]bool) {
+	changes = new([]bool)
+	b = New(3, 5*time.Minute, func(open bool) { *changes = append(*changes, open) })
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return now }
+	return b, func(d time.Duration) { now = now.Add(d) }, changes
+}
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	b, _, changes := newTestBreaker()
+
+	b.Record(true)
+	b.Record(true)
+	if b.Open() {
+		t.Fatal("open after 2 of 3 failures")
+	}
+	b.Record(false)
+	b.Record(true)
+	b.Record(true)
+	if b.Open() {
+		t.Fatal("open although a success reset the count")
+	}
+	b.Record(true)
+	if !b.Open() {
+		t.Fatal("closed after 3 consecutive failures")
+	}
+	if fmt.Sprint(*changes) != "[true]" {
+		t.Errorf("onChange calls = %v, want [true]", *changes)
+	}
+}
+
+func TestBreakerCooldown(t *testing.T) {
+	b, advance, changes := newTestBreaker()
+	for range 3 {
+		b.Record(true)
+	}
+
+	// outcomes of requests already in flight don't extend the cooldown
+	advance(4 * time.Minute)
+	b.Record(true)
+	b.Record(false)
+	if !b.Open() {
+		t.Fatal("closed before the cooldown passed")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := b.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Wait while open = %v, want context.Canceled", err)
+	}
+
+	advance(time.Minute)
+	if b.Open() {
+		t.Fatal("still open once the cooldown passed")
+	}
+	if err := b.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait after the cooldown = %v", err)
+	}
+	if fmt.Sprint(*changes) != "[true false]" {
+		t.Errorf("onChange calls = %v, want [true false]", *changes)
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	tests := []struct {
+		name     string
+		outcomes []bool // recorded after the cooldown
+		open     bool
+		changes  string
+	}{
+		{"failure reopens at once", []bool{true}, true, "[true false true]"},
+		{"success closes", []bool{false}, false, "[true false]"},
+		{"closed again after a success", []bool{false, true, true}, false, "[true false]"},
+		{"threshold applies after a success", []bool{false, true, true, true}, true, "[true false true]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, advance, changes := newTestBreaker()
+			for range 3 {
+				b.Record(true)
+			}
+			advance(5 * time.Minute)
+
+			for _, failed := range tt.outcomes {
+				b.Record(failed)
+			}
+			if b.Open() != tt.open {
+				t.Errorf("Open = %v, want %v", b.Open(), tt.open)
+			}
+			if fmt.Sprint(*changes) != tt.changes {
+				t.Errorf("onChange calls = %v, want %s", *changes, tt.changes)
+			}
+		})
+	}
+}
+
+func TestBreakerReopensForAFullCooldown(t *testing.T) {
+	b, advance, _ := newTestBreaker()
+	for range 3 {
+		b.Record(true)
+	}
+	advance(6 * time.Minute)
+	b.Record(true)
+
+	advance(4 * time.Minute)
+	if !b.Open() {
+		t.Fatal("reopened breaker closed before a full cooldown")
+	}
+	advance(time.Minute)
+	if b.Open() {
+		t.Fatal("reopened breaker still open after a full cooldown")
+	}
+}
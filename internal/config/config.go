@@ -2,6 +2,10 @@ package config
 
 import (
 	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -9,11 +13,167 @@ import (
 // CrawlerConfig holds settings specific to the crawler
 type CrawlerConfig struct {
 	StartURL    string
+	SeedURLs    []string // listing pages to discover products from; defaults to StartURL
 	Concurrency int
+	Discovery   string // "listing" (paginate SeedURLs) or "sitemap"
+	Sitemap     SitemapConfig
+	Recrawl     RecrawlConfig
+	Proxy       ProxyConfig
+	Block       BlockConfig
+	Resources   ResourceConfig
+	Capture     CaptureConfig
+	Write       WriteConfig
+	// EnqueueCoordinated records the coordinated products of every fetched
+	// product that are not stored yet as product URLs, so that incremental
+	// crawls fetch them.
+	EnqueueCoordinated bool
+	// BrowserProfile names the entry of Config.BrowserProfiles this
+	// crawler's headless sessions use.
+	BrowserProfile string
+	// DelistingDelay paces the detail page requests of the delisting check.
+	DelistingDelay time.Duration
+}
+
+// BrowserProfile describes how a headless Chrome session presents itself.
+type BrowserProfile struct {
+	UserAgent      string   `mapstructure:"user_agent"`
+	AcceptLanguage string   `mapstructure:"accept_language"`
+	ViewportWidth  int      `mapstructure:"viewport_width"`
+	ViewportHeight int      `mapstructure:"viewport_height"`
+	Timezone       string   `mapstructure:"timezone"`
+	Headless       bool     `mapstructure:"headless"`
+	BlockImages    bool     `mapstructure:"block_images"`
+	BlockFonts     bool     `mapstructure:"block_fonts"`
+	ExtraFlags     []string `mapstructure:"extra_flags"` // "name" or "name=value"
+}
+
+// defaultBrowserProfiles are always available; BROWSER_PROFILES_FILE can
+// override them or add more.
+func defaultBrowserProfiles() map[string]BrowserProfile {
+	return map[string]BrowserProfile{
+		"desktop-ja": {
+			UserAgent:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/138.0.0.0 Safari/537.36",
+			AcceptLanguage: "ja-JP,ja;q=0.9,en-US;q=0.6,en;q=0.4",
+			ViewportWidth:  1920,
+			ViewportHeight: 1080,
+			Timezone:       "Asia/Tokyo",
+			Headless:       true,
+			BlockFonts:     true,
+		},
+		"mobile-ja": {
+			UserAgent:      "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+			AcceptLanguage: "ja-JP,ja;q=0.9",
+			ViewportWidth:  390,
+			ViewportHeight: 844,
+			Timezone:       "Asia/Tokyo",
+			Headless:       true,
+			BlockFonts:     true,
+		},
+	}
+}
+
+// loadBrowserProfiles merges the profiles defined in a YAML or JSON file
+// (a map of name to profile) over the defaults.
+func loadBrowserProfiles(path string) (map[string]BrowserProfile, error) {
+	profiles := defaultBrowserProfiles()
+	if path == "" {
+		return profiles, nil
+	}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("error reading browser profiles: %w", err)
+	}
+	var fromFile map[string]BrowserProfile
+	if err := v.Unmarshal(&fromFile); err != nil {
+		return nil, fmt.Errorf("error decoding browser profiles: %w", err)
+	}
+	for name, p := range fromFile {
+		profiles[name] = p
+	}
+	return profiles, nil
+}
+
+// BlockConfig tunes block-page detection and the crawl-wide circuit breaker.
+type BlockConfig struct {
+	MinHTMLBytes int           // pages shorter than this count as blocked
+	Threshold    int           // consecutive blocks that pause the crawl
+	Cooldown     time.Duration // how long the crawl stays paused
+}
+
+// WriteConfig controls how fetched products are written. With BatchSize 0
+// every product is stored as soon as it is parsed; otherwise products are
+// buffered and written BatchSize at a time, or after FlushInterval.
+type WriteConfig struct {
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+// ResourceConfig decides which subresources headless sessions load.
+// Documents are always loaded. A request is blocked when its resource type
+// is in BlockTypes, its host is in BlockDomains, or AllowDomains is set and
+// its host is not in it. Domains match their subdomains too.
+type ResourceConfig struct {
+	BlockTypes   []string // Chrome resource types, e.g. Image, Font, Media
+	BlockDomains []string
+	AllowDomains []string
+}
+
+// CaptureConfig holds the regexps that pick out a detail page's JSON API
+// responses. An empty pattern leaves that data to DOM parsing.
+type CaptureConfig struct {
+	AvailabilityPattern string // per-size stock
+	ReviewsPattern      string
+	RatingsPattern      string // overall rating, review count, aspect ratings
+}
+
+// ProxyConfig lists upstream proxies for fetches. URLs and the entries of
+// File are combined; with neither set, requests go out directly.
+type ProxyConfig struct {
+	URLs       []string
+	File       string
+	StickyTTL  time.Duration // how long a product keeps its proxy
+	Quarantine time.Duration // how long a blocked proxy is left out
+}
+
+// Redacted returns a copy of c whose proxy URLs have their passwords
+// replaced by "xxxxx", for logging and for crawl run records.
+func (c CrawlerConfig) Redacted() CrawlerConfig {
+	urls := make([]string, len(c.Proxy.URLs))
+	for i, raw := range c.Proxy.URLs {
+		u, err := url.Parse(strings.TrimSpace(raw))
+		if err != nil {
+			urls[i] = "<invalid>"
+			continue
+		}
+		urls[i] = u.Redacted()
+	}
+	c.Proxy.URLs = urls
+	return c
+}
+
+// RecrawlConfig bounds the adaptive intervals used by incremental crawls.
+type RecrawlConfig struct {
+	InitialInterval time.Duration
+	MinInterval     time.Duration
+	MaxInterval     time.Duration
+}
+
+// SitemapConfig controls sitemap-based product discovery.
+type SitemapConfig struct {
+	RobotsURL   string   // robots.txt whose Sitemap: entries are read
+	URLs        []string // explicit sitemaps; used instead of RobotsURL when set
+	Locale      string   // only child sitemaps and product URLs for this locale (or listed in a sitemap that is) are read, e.g. "ja"; see sitemap.MatchesLocale
+	PathPattern string   // regexp a product URL must match
 }
 
 // Config is the application configuration
 type Config struct {
+	// DBDriver selects the storage backend: postgres, or sqlite for a
+	// single-file database at DBPath that needs no server.
+	DBDriver   string
+	DBPath     string
 	DBHost     string
 	DBPort     int
 	DBUser     string
@@ -21,7 +181,37 @@ type Config struct {
 	DBName     string
 	DBSSLMode  string
 	Crawler    CrawlerConfig
-	LogLevel   string
+	Log        LogConfig
+	// BrowserProfiles are the named headless browser profiles sites pick from.
+	BrowserProfiles map[string]BrowserProfile
+	// MetricsAddr is where cmd/crawl serves /metrics; empty disables it.
+	MetricsAddr string
+	Tracing     TracingConfig
+	// ReviewClassifier names the classifier that tags reviews with
+	// sentiment and topics (see service.NewClassifier).
+	ReviewClassifier string
+}
+
+// LogConfig controls log level, optional rotated JSON file output and
+// sampling of repeated messages.
+type LogConfig struct {
+	Level            string
+	Format           string // json or console; debug level always uses console
+	File             string // JSON log file; empty disables file output
+	MaxSizeMB        int
+	MaxBackups       int
+	MaxAgeDays       int
+	SampleInitial    int // per second, log the first N of each debug or info message...
+	SampleThereafter int // ...then every Mth; SampleInitial 0 disables sampling
+}
+
+// TracingConfig selects where OpenTelemetry spans are exported.
+type TracingConfig struct {
+	Exporter     string // none, otlp, stdout or file
+	OTLPEndpoint string // host:port of an OTLP/HTTP collector
+	OTLPInsecure bool
+	File         string // output path for the file exporter
+	SampleRatio  float64
 }
 
 // Load reads configuration from the given env file (e.g. ".env") and environment variables.
@@ -30,11 +220,55 @@ func Load(envFile string) (*Config, error) {
 	viper.AutomaticEnv()
 
 	// Default values
+	viper.SetDefault("DB_DRIVER", "postgres")
+	viper.SetDefault("DB_PATH", "crawler.db")
 	viper.SetDefault("DB_PORT", 5432)
 	viper.SetDefault("DB_SSLMODE", "disable")
-	viper.SetDefault("CRAWLER_START_URL", "https://shop.adidas.jp/men/")
+	viper.SetDefault("CRAWLER_START_URL", "https://www.adidas.jp/メンズ")
+	viper.SetDefault("CRAWLER_SEED_URLS", "")
 	viper.SetDefault("CRAWLER_CONCURRENCY", 4)
+	viper.SetDefault("CRAWLER_DISCOVERY", "listing")
+	viper.SetDefault("CRAWLER_SITEMAP_ROBOTS_URL", "https://www.adidas.jp/robots.txt")
+	viper.SetDefault("CRAWLER_SITEMAP_URLS", "")
+	viper.SetDefault("CRAWLER_SITEMAP_LOCALE", "")
+	viper.SetDefault("CRAWLER_SITEMAP_PATH_PATTERN", `/[A-Za-z0-9]{6}\.html$`)
+	viper.SetDefault("CRAWLER_RECRAWL_INITIAL_INTERVAL", "24h")
+	viper.SetDefault("CRAWLER_RECRAWL_MIN_INTERVAL", "6h")
+	viper.SetDefault("CRAWLER_RECRAWL_MAX_INTERVAL", "336h")
+	viper.SetDefault("PROXY_URLS", "")
+	viper.SetDefault("PROXY_FILE", "")
+	viper.SetDefault("PROXY_STICKY_TTL", "30m")
+	viper.SetDefault("PROXY_QUARANTINE", "15m")
+	viper.SetDefault("CRAWLER_BLOCK_MIN_HTML_BYTES", 5000)
+	viper.SetDefault("CRAWLER_BLOCK_THRESHOLD", 3)
+	viper.SetDefault("CRAWLER_BLOCK_COOLDOWN", "5m")
+	viper.SetDefault("CRAWLER_WRITE_BATCH_SIZE", 0)
+	viper.SetDefault("CRAWLER_WRITE_FLUSH_INTERVAL", "2s")
+	viper.SetDefault("CRAWLER_ENQUEUE_COORDINATED", false)
+	viper.SetDefault("CRAWLER_BLOCK_RESOURCE_TYPES", "Image,Font,Media")
+	viper.SetDefault("CRAWLER_BLOCK_DOMAINS", "google-analytics.com,googletagmanager.com,doubleclick.net,facebook.net,criteo.com,criteo.net,hotjar.com,tiktok.com")
+	viper.SetDefault("CRAWLER_ALLOW_DOMAINS", "")
+	viper.SetDefault("CRAWLER_CAPTURE_AVAILABILITY_PATTERN", `/api/products/[A-Za-z0-9]+/availability`)
+	viper.SetDefault("CRAWLER_CAPTURE_REVIEWS_PATTERN", `/api/models/[^/]+/reviews`)
+	viper.SetDefault("CRAWLER_CAPTURE_RATINGS_PATTERN", `/api/models/[^/]+/ratings`)
+	viper.SetDefault("CRAWLER_BROWSER_PROFILE", "desktop-ja")
+	viper.SetDefault("CRAWLER_DELISTING_DELAY", "2s")
+	viper.SetDefault("BROWSER_PROFILES_FILE", "")
 	viper.SetDefault("LOG_LEVEL", "info")
+	viper.SetDefault("LOG_FORMAT", "json")
+	viper.SetDefault("LOG_FILE", "")
+	viper.SetDefault("LOG_MAX_SIZE_MB", 100)
+	viper.SetDefault("LOG_MAX_BACKUPS", 5)
+	viper.SetDefault("LOG_MAX_AGE_DAYS", 14)
+	viper.SetDefault("LOG_SAMPLE_INITIAL", 100)
+	viper.SetDefault("LOG_SAMPLE_THEREAFTER", 100)
+	viper.SetDefault("METRICS_ADDR", "")
+	viper.SetDefault("REVIEW_CLASSIFIER", "lexicon")
+	viper.SetDefault("TRACING_EXPORTER", "none")
+	viper.SetDefault("TRACING_OTLP_ENDPOINT", "localhost:4318")
+	viper.SetDefault("TRACING_OTLP_INSECURE", true)
+	viper.SetDefault("TRACING_FILE", "traces.jsonl")
+	viper.SetDefault("TRACING_SAMPLE_RATIO", 1.0)
 
 	// Read from file (if present)
 	if err := viper.ReadInConfig(); err != nil {
@@ -45,6 +279,8 @@ func Load(envFile string) (*Config, error) {
 	}
 
 	cfg := &Config{
+		DBDriver:   viper.GetString("DB_DRIVER"),
+		DBPath:     viper.GetString("DB_PATH"),
 		DBHost:     viper.GetString("DB_HOST"),
 		DBPort:     viper.GetInt("DB_PORT"),
 		DBUser:     viper.GetString("DB_USER"),
@@ -53,15 +289,129 @@ func Load(envFile string) (*Config, error) {
 		DBSSLMode:  viper.GetString("DB_SSLMODE"),
 		Crawler: CrawlerConfig{
 			StartURL:    viper.GetString("CRAWLER_START_URL"),
+			SeedURLs:    splitList(viper.GetString("CRAWLER_SEED_URLS")),
 			Concurrency: viper.GetInt("CRAWLER_CONCURRENCY"),
+			Discovery:   viper.GetString("CRAWLER_DISCOVERY"),
+			Sitemap: SitemapConfig{
+				RobotsURL:   viper.GetString("CRAWLER_SITEMAP_ROBOTS_URL"),
+				URLs:        splitList(viper.GetString("CRAWLER_SITEMAP_URLS")),
+				Locale:      viper.GetString("CRAWLER_SITEMAP_LOCALE"),
+				PathPattern: viper.GetString("CRAWLER_SITEMAP_PATH_PATTERN"),
+			},
+			Recrawl: RecrawlConfig{
+				InitialInterval: viper.GetDuration("CRAWLER_RECRAWL_INITIAL_INTERVAL"),
+				MinInterval:     viper.GetDuration("CRAWLER_RECRAWL_MIN_INTERVAL"),
+				MaxInterval:     viper.GetDuration("CRAWLER_RECRAWL_MAX_INTERVAL"),
+			},
+			Proxy: ProxyConfig{
+				URLs:       splitList(viper.GetString("PROXY_URLS")),
+				File:       viper.GetString("PROXY_FILE"),
+				StickyTTL:  viper.GetDuration("PROXY_STICKY_TTL"),
+				Quarantine: viper.GetDuration("PROXY_QUARANTINE"),
+			},
+			Block: BlockConfig{
+				MinHTMLBytes: viper.GetInt("CRAWLER_BLOCK_MIN_HTML_BYTES"),
+				Threshold:    viper.GetInt("CRAWLER_BLOCK_THRESHOLD"),
+				Cooldown:     viper.GetDuration("CRAWLER_BLOCK_COOLDOWN"),
+			},
+			Resources: ResourceConfig{
+				BlockTypes:   splitList(viper.GetString("CRAWLER_BLOCK_RESOURCE_TYPES")),
+				BlockDomains: splitList(viper.GetString("CRAWLER_BLOCK_DOMAINS")),
+				AllowDomains: splitList(viper.GetString("CRAWLER_ALLOW_DOMAINS")),
+			},
+			Capture: CaptureConfig{
+				AvailabilityPattern: viper.GetString("CRAWLER_CAPTURE_AVAILABILITY_PATTERN"),
+				ReviewsPattern:      viper.GetString("CRAWLER_CAPTURE_REVIEWS_PATTERN"),
+				RatingsPattern:      viper.GetString("CRAWLER_CAPTURE_RATINGS_PATTERN"),
+			},
+			Write: WriteConfig{
+				BatchSize:     viper.GetInt("CRAWLER_WRITE_BATCH_SIZE"),
+				FlushInterval: viper.GetDuration("CRAWLER_WRITE_FLUSH_INTERVAL"),
+			},
+			EnqueueCoordinated: viper.GetBool("CRAWLER_ENQUEUE_COORDINATED"),
+			BrowserProfile:     viper.GetString("CRAWLER_BROWSER_PROFILE"),
+			DelistingDelay:     viper.GetDuration("CRAWLER_DELISTING_DELAY"),
+		},
+		Log: LogConfig{
+			Level:            viper.GetString("LOG_LEVEL"),
+			Format:           viper.GetString("LOG_FORMAT"),
+			File:             viper.GetString("LOG_FILE"),
+			MaxSizeMB:        viper.GetInt("LOG_MAX_SIZE_MB"),
+			MaxBackups:       viper.GetInt("LOG_MAX_BACKUPS"),
+			MaxAgeDays:       viper.GetInt("LOG_MAX_AGE_DAYS"),
+			SampleInitial:    viper.GetInt("LOG_SAMPLE_INITIAL"),
+			SampleThereafter: viper.GetInt("LOG_SAMPLE_THEREAFTER"),
 		},
-		LogLevel: viper.GetString("LOG_LEVEL"),
+		MetricsAddr:      viper.GetString("METRICS_ADDR"),
+		ReviewClassifier: viper.GetString("REVIEW_CLASSIFIER"),
+		Tracing: TracingConfig{
+			Exporter:     viper.GetString("TRACING_EXPORTER"),
+			OTLPEndpoint: viper.GetString("TRACING_OTLP_ENDPOINT"),
+			OTLPInsecure: viper.GetBool("TRACING_OTLP_INSECURE"),
+			File:         viper.GetString("TRACING_FILE"),
+			SampleRatio:  viper.GetFloat64("TRACING_SAMPLE_RATIO"),
+		},
+	}
+
+	for _, pattern := range []string{
+		cfg.Crawler.Capture.AvailabilityPattern,
+		cfg.Crawler.Capture.ReviewsPattern,
+		cfg.Crawler.Capture.RatingsPattern,
+	} {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid capture pattern %q: %w", pattern, err)
+		}
+	}
+
+	profiles, err := loadBrowserProfiles(viper.GetString("BROWSER_PROFILES_FILE"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.BrowserProfiles = profiles
+	if _, ok := cfg.BrowserProfiles[cfg.Crawler.BrowserProfile]; !ok {
+		return nil, fmt.Errorf("unknown browser profile %q", cfg.Crawler.BrowserProfile)
+	}
+
+	if len(cfg.Crawler.SeedURLs) == 0 && cfg.Crawler.StartURL != "" {
+		cfg.Crawler.SeedURLs = []string{cfg.Crawler.StartURL}
+	}
+
+	if d := cfg.Crawler.Discovery; d != "listing" && d != "sitemap" {
+		return nil, fmt.Errorf("invalid CRAWLER_DISCOVERY %q (want listing or sitemap)", d)
+	}
+
+	if r := cfg.Crawler.Recrawl; r.MinInterval <= 0 || r.MinInterval > r.MaxInterval {
+		return nil, fmt.Errorf("invalid recrawl intervals: min=%s max=%s", r.MinInterval, r.MaxInterval)
+	}
+
+	if w := cfg.Crawler.Write; w.BatchSize < 0 || (w.BatchSize > 0 && w.FlushInterval <= 0) {
+		return nil, fmt.Errorf("invalid write batching: size=%d flush interval=%s", w.BatchSize, w.FlushInterval)
 	}
 
 	// basic validation
-	if cfg.DBHost == "" || cfg.DBUser == "" || cfg.DBPassword == "" || cfg.DBName == "" {
-		return nil, fmt.Errorf("missing one or more required DB credentials")
+	switch cfg.DBDriver {
+	case "postgres":
+		if cfg.DBHost == "" || cfg.DBUser == "" || cfg.DBPassword == "" || cfg.DBName == "" {
+			return nil, fmt.Errorf("missing one or more required DB credentials")
+		}
+	case "sqlite":
+		if cfg.DBPath == "" {
+			return nil, fmt.Errorf("DB_PATH is required with DB_DRIVER=sqlite")
+		}
+	default:
+		return nil, fmt.Errorf("invalid DB_DRIVER %q (want postgres or sqlite)", cfg.DBDriver)
 	}
 
 	return cfg, nil
 }
+
+// splitList parses a comma-separated env value, dropping empty entries.
+func splitList(raw string) []string {
+	var out []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
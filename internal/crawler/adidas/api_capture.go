@@ -0,0 +1,179 @@
+package adidas
+
+import (
+	"encoding/json"
+	"regexp"
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/browser"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/config"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"go.uber.org/zap"
+)
+
+// CapturePatterns are the compiled config.CaptureConfig patterns. A nil
+// pattern disables capturing that response; the zero value captures nothing.
+type CapturePatterns struct {
+	availability *regexp.Regexp
+	reviews      *regexp.Regexp
+	ratings      *regexp.Regexp
+}
+
+// NewCapturePatterns compiles cfg; config.Load has already rejected invalid
+// patterns.
+func NewCapturePatterns(cfg config.CaptureConfig) CapturePatterns {
+	compile := func(pattern string) *regexp.Regexp {
+		if pattern == "" {
+			return nil
+		}
+		re, _ := regexp.Compile(pattern)
+		return re
+	}
+	return CapturePatterns{
+		availability: compile(cfg.AvailabilityPattern),
+		reviews:      compile(cfg.ReviewsPattern),
+		ratings:      compile(cfg.RatingsPattern),
+	}
+}
+
+func (p CapturePatterns) all() []*regexp.Regexp {
+	return []*regexp.Regexp{p.availability, p.reviews, p.ratings}
+}
+
+// availabilityResponse is /api/products/{code}/availability.
+type availabilityResponse struct {
+	ID                 string `json:"id"`
+	AvailabilityStatus string `json:"availability_status"`
+	VariationList      []struct {
+		SKU                string `json:"sku"`
+		Size               string `json:"size"`
+		Availability       int    `json:"availability"`
+		AvailabilityStatus string `json:"availability_status"`
+	} `json:"variation_list"`
+}
+
+// reviewsResponse is one page of /api/models/{model}/reviews. The detail
+// page only requests the first page; later pages load when a visitor asks
+// for more, so captured reviews are that first page and TotalResults is the
+// full count.
+type reviewsResponse struct {
+	TotalResults int `json:"totalResults"`
+	Reviews      []struct {
+		ID             string  `json:"id"`
+		Title          string  `json:"title"`
+		Text           string  `json:"text"`
+		Rating         float64 `json:"rating"`
+		SubmissionTime string  `json:"submissionTime"`
+	} `json:"reviews"`
+}
+
+// ratingsResponse is /api/models/{model}/ratings.
+type ratingsResponse struct {
+	OverallRating    float64 `json:"overallRating"`
+	ReviewCount      int     `json:"reviewCount"`
+	SecondaryRatings []struct {
+		ID         string  `json:"id"`
+		Label      string  `json:"label"`
+		Value      float64 `json:"value"`
+		ValueRange float64 `json:"valueRange"`
+	} `json:"secondaryRatings"`
+}
+
+// capturedData is what the JSON APIs told us about a product. A nil slice
+// means no usable response was captured and the DOM has to be parsed.
+type capturedData struct {
+	Sizes         []model.ProductSize
+	Reviews       []model.Review
+	AspectRatings []model.ReviewAspectRating
+	OverallRating float64
+	ReviewCount   int
+}
+
+// capturedResponses is the part of *browser.Capture decodeCaptured reads.
+type capturedResponses interface {
+	Find(pattern *regexp.Regexp) []browser.Response
+}
+
+// decodeCaptured turns the recorded API responses into model values. Only
+// the review pages the detail page itself requested are decoded, normally
+// the first; ReviewCount still reports the total. Responses that fail to
+// decode are logged and skipped.
+func decodeCaptured(capture capturedResponses, patterns CapturePatterns, log *zap.SugaredLogger) capturedData {
+	var data capturedData
+
+	for _, resp := range capture.Find(patterns.availability) {
+		var body availabilityResponse
+		if err := json.Unmarshal(resp.Body, &body); err != nil {
+			log.Debugw("undecodable availability response", "url", resp.URL, "error", err)
+			continue
+		}
+		if len(body.VariationList) == 0 {
+			continue
+		}
+		sizes := make([]model.ProductSize, 0, len(body.VariationList))
+		for _, v := range body.VariationList {
+			sizes = append(sizes, model.ProductSize{
+				SizeLabel: v.Size,
+				// numeric(5,2); adidas caps the reported stock well below that
+				Availability: float64(min(v.Availability, 999)),
+			})
+		}
+		data.Sizes = sizes
+	}
+
+	seenReviews := map[string]bool{}
+	for _, resp := range capture.Find(patterns.reviews) {
+		var body reviewsResponse
+		if err := json.Unmarshal(resp.Body, &body); err != nil {
+			log.Debugw("undecodable reviews response", "url", resp.URL, "error", err)
+			continue
+		}
+		if data.Reviews == nil {
+			data.Reviews = []model.Review{}
+		}
+		if body.TotalResults > data.ReviewCount {
+			data.ReviewCount = body.TotalResults
+		}
+		for _, r := range body.Reviews {
+			if seenReviews[r.ID] {
+				continue // pages can overlap when the page re-sorts
+			}
+			seenReviews[r.ID] = true
+			date, _ := time.Parse(time.RFC3339, r.SubmissionTime)
+			data.Reviews = append(data.Reviews, model.Review{
+				Title:      r.Title,
+				Body:       r.Text,
+				Rating:     r.Rating,
+				ReviewDate: date,
+			})
+		}
+	}
+
+	for _, resp := range capture.Find(patterns.ratings) {
+		var body ratingsResponse
+		if err := json.Unmarshal(resp.Body, &body); err != nil {
+			log.Debugw("undecodable ratings response", "url", resp.URL, "error", err)
+			continue
+		}
+		data.OverallRating = body.OverallRating
+		if body.ReviewCount > data.ReviewCount {
+			data.ReviewCount = body.ReviewCount
+		}
+		aspects := make([]model.ReviewAspectRating, 0, len(body.SecondaryRatings))
+		for _, r := range body.SecondaryRatings {
+			aspect := r.Label
+			if aspect == "" {
+				aspect = r.ID
+			}
+			// the DOM bars report a position in percent; match that scale
+			rating := r.Value
+			if r.ValueRange > 0 {
+				rating = r.Value / r.ValueRange * 100
+			}
+			aspects = append(aspects, model.ReviewAspectRating{Aspect: aspect, Rating: rating})
+		}
+		data.AspectRatings = aspects
+	}
+
+	return data
+}
@@ -0,0 +1,106 @@
+package adidas
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/browser"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/config"
+	"go.uber.org/zap"
+)
+
+// recorded stands in for a browser.Capture that saw the given responses.
+type recorded []browser.Response
+
+func (r recorded) Find(pattern *regexp.Regexp) []browser.Response {
+	var out []browser.Response
+	for _, resp := range r {
+		if pattern != nil && resp.Status >= 200 && resp.Status < 300 && pattern.MatchString(resp.URL) {
+			out = append(out, resp)
+		}
+	}
+	return out
+}
+
+func response(t *testing.T, url, file string) browser.Response {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return browser.Response{URL: url, Status: 200, Body: body}
+}
+
+func testPatterns() CapturePatterns {
+	return NewCapturePatterns(config.CaptureConfig{
+		AvailabilityPattern: `/api/products/[A-Za-z0-9]+/availability`,
+		ReviewsPattern:      `/api/models/[^/]+/reviews`,
+		RatingsPattern:      `/api/models/[^/]+/ratings`,
+	})
+}
+
+func TestDecodeCaptured(t *testing.T) {
+	capture := recorded{
+		response(t, "https://www.adidas.jp/api/products/JI2734/availability", "availability_JI2734.json"),
+		response(t, "https://www.adidas.jp/api/models/IKM07/reviews?limit=5&offset=0", "reviews_JI2734_page1.json"),
+		response(t, "https://www.adidas.jp/api/models/IKM07/reviews?limit=5&offset=0&sort=newest", "reviews_JI2734_page1_resorted.json"),
+		response(t, "https://www.adidas.jp/api/models/IKM07/ratings", "ratings_JI2734.json"),
+		{URL: "https://www.adidas.jp/api/models/IKM07/reviews?offset=5", Status: 200, Body: []byte(`<html>`)},
+		{URL: "https://www.adidas.jp/api/products/JI2734/availability", Status: 500, Body: []byte(`{}`)},
+	}
+	data := decodeCaptured(capture, testPatterns(), zap.NewNop().Sugar())
+
+	wantSizes := []struct {
+		label string
+		avail float64
+	}{{"24.5cm", 15}, {"25.5cm", 2}, {"26.5cm", 0}, {"27.5cm", 999}}
+	if len(data.Sizes) != len(wantSizes) {
+		t.Fatalf("sizes = %+v", data.Sizes)
+	}
+	for i, w := range wantSizes {
+		if s := data.Sizes[i]; s.SizeLabel != w.label || s.Availability != w.avail {
+			t.Errorf("size %d = %s/%v, want %s/%v", i, s.SizeLabel, s.Availability, w.label, w.avail)
+		}
+	}
+
+	// the re-sorted page overlaps the first one in r-1002
+	if len(data.Reviews) != 3 {
+		t.Fatalf("decoded %d reviews, want 3: %+v", len(data.Reviews), data.Reviews)
+	}
+	first := data.Reviews[0]
+	if first.Title != "最高の一足" || first.Rating != 5 || !first.ReviewDate.Equal(time.Date(2024, 4, 28, 0, 15, 0, 0, time.UTC)) {
+		t.Errorf("first review = %+v", first)
+	}
+	if !data.Reviews[2].ReviewDate.IsZero() {
+		t.Errorf("unparsable submission time gave %v", data.Reviews[2].ReviewDate)
+	}
+
+	if data.OverallRating != 4.6 || data.ReviewCount != 131 {
+		t.Errorf("rating %v from %d reviews, want 4.6 from 131", data.OverallRating, data.ReviewCount)
+	}
+	wantAspects := map[string]float64{"サイズ": 60, "Comfort": 90, "品質": 80}
+	if len(data.AspectRatings) != len(wantAspects) {
+		t.Fatalf("aspects = %+v", data.AspectRatings)
+	}
+	for _, a := range data.AspectRatings {
+		if want, ok := wantAspects[a.Aspect]; !ok || a.Rating != want {
+			t.Errorf("aspect %s = %v, want %v", a.Aspect, a.Rating, want)
+		}
+	}
+}
+
+func TestDecodeCapturedNothingCaptured(t *testing.T) {
+	data := decodeCaptured(recorded{}, testPatterns(), zap.NewNop().Sugar())
+	if data.Sizes != nil || data.Reviews != nil || data.AspectRatings != nil {
+		t.Errorf("decoded %+v from no responses; nil slices fall back to the DOM", data)
+	}
+
+	// a capture switched off by config decodes nothing even if responses exist
+	capture := recorded{response(t, "https://www.adidas.jp/api/products/JI2734/availability", "availability_JI2734.json")}
+	if data := decodeCaptured(capture, CapturePatterns{}, zap.NewNop().Sugar()); data.Sizes != nil {
+		t.Errorf("zero CapturePatterns decoded sizes %+v", data.Sizes)
+	}
+}
@@ -0,0 +1,114 @@
+package adidas
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// ErrBlocked is matched (via errors.Is) by every BlockError.
+var ErrBlocked = errors.New("blocked by bot protection")
+
+// BlockError describes why a page was judged to be a block or challenge
+// page rather than real content.
+type BlockError struct {
+	Reason     string // "status", "marker", "no_json_ld" or "short_dom"
+	StatusCode int
+	Detail     string
+}
+
+func (e *BlockError) Error() string {
+	return fmt.Sprintf("%v: %s (status %d) %s", ErrBlocked, e.Reason, e.StatusCode, e.Detail)
+}
+
+func (e *BlockError) Is(target error) bool { return target == ErrBlocked }
+
+// titleMarkers are fragments of the <title> of known bot-challenge and
+// denial pages.
+var titleMarkers = []string{
+	"Access Denied",
+	"Pardon Our Interruption",
+	"Request unsuccessful",
+	"Just a moment",
+	"Attention Required",
+	"ボットではありません",
+	"アクセスが拒否されました",
+}
+
+// challengeMarkers are fragments of challenge pages that real pages may
+// contain too, such as the captcha script of a review form. They only count
+// on a page without product content.
+var challengeMarkers = []string{
+	"Access Denied",
+	"Pardon Our Interruption",
+	"Request unsuccessful. Incapsula",
+	"_Incapsula_Resource",
+	"px-captcha",
+	"g-recaptcha",
+	"hcaptcha",
+	"cf-challenge",
+	"/_sec/cp_challenge/",
+	"ボットではありません",
+	"アクセスが拒否されました",
+}
+
+var (
+	titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	// productLinkPattern matches a link to a product detail page, the
+	// content of a listing page
+	productLinkPattern = regexp.MustCompile(`href="[^"]*/[A-Za-z0-9]{6}\.html`)
+)
+
+// BlockDetector judges whether a fetched page is a block page.
+type BlockDetector struct {
+	// MinHTMLBytes is the smallest document a real page can be.
+	MinHTMLBytes int
+}
+
+// Check returns a *BlockError if the page looks blocked: a challenge
+// status, a challenge title, challenge markers on a page without product
+// content, or an abnormally short document. detailPage is set for detail
+// pages, which always embed Product JSON-LD; listing pages have product
+// links instead, except past their last page.
+func (d BlockDetector) Check(status int, html string, detailPage bool) error {
+	if status == http.StatusForbidden || status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable {
+		return &BlockError{Reason: "status", StatusCode: status}
+	}
+	if m := titlePattern.FindStringSubmatch(html); m != nil {
+		for _, marker := range titleMarkers {
+			if strings.Contains(m[1], marker) {
+				return &BlockError{Reason: "marker", StatusCode: status, Detail: "title: " + marker}
+			}
+		}
+	}
+	hasJSONLD := strings.Contains(html, "application/ld+json")
+	hasContent := hasJSONLD
+	if !detailPage {
+		hasContent = productLinkPattern.MatchString(html)
+	}
+	if !hasContent {
+		for _, marker := range challengeMarkers {
+			if strings.Contains(html, marker) {
+				return &BlockError{Reason: "marker", StatusCode: status, Detail: marker}
+			}
+		}
+	}
+	if len(html) < d.MinHTMLBytes {
+		return &BlockError{Reason: "short_dom", StatusCode: status, Detail: fmt.Sprintf("%d bytes", len(html))}
+	}
+	if detailPage && !hasJSONLD {
+		return &BlockError{Reason: "no_json_ld", StatusCode: status}
+	}
+	return nil
+}
+
+// blockReason returns the BlockError reason in err's chain, or "".
+func blockReason(err error) string {
+	var be *BlockError
+	if errors.As(err, &be) {
+		return be.Reason
+	}
+	return ""
+}
@@ -0,0 +1,59 @@
+package adidas
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBlockDetectorCheck(t *testing.T) {
+	padding := strings.Repeat("<div>content</div>", 400)
+	detail := func(body string) string {
+		return `<html><head><title>サンバ OG / Samba OG | アディダス公式通販</title>
+<script type="application/ld+json">{"@type":"Product","sku":"JI2734"}</script></head><body>` + body + padding + `</body></html>`
+	}
+	listing := func(body string) string {
+		return `<html><head><title>メンズ スニーカー | アディダス公式通販</title></head><body>` + body + padding + `</body></html>`
+	}
+
+	d := BlockDetector{MinHTMLBytes: 5000}
+	tests := []struct {
+		name       string
+		status     int
+		html       string
+		detailPage bool
+		reason     string
+	}{
+		{"product page", 200, detail(""), true, ""},
+		{"product page with review captcha", 200, detail(`<script src="https://www.google.com/recaptcha/api.js"></script><div class="g-recaptcha"></div>`), true, ""},
+		{"product page with hcaptcha", 200, detail(`<script src="https://js.hcaptcha.com/1/api.js"></script>`), true, ""},
+		{"product page mentioning access denied", 200, detail(`<p>Access Denied? Contact support.</p>`), true, ""},
+		{"listing with captcha script", 200, listing(`<a href="/samba-og/JI2734.html">Samba</a><div class="g-recaptcha"></div>`), false, ""},
+		{"listing past its last page", 200, listing(""), false, ""},
+		{"forbidden", 403, detail(""), true, "status"},
+		{"too many requests", 429, listing(`<a href="/samba-og/JI2734.html">Samba</a>`), false, "status"},
+		{"access denied title", 200, `<html><head><title>Access Denied</title></head><body>You don't have permission` + padding + `</body></html>`, true, "marker"},
+		{"japanese challenge title", 200, `<html><head><title>ボットではありません</title></head><body></body></html>`, false, "marker"},
+		{"captcha without product content", 200, `<html><head><title>adidas</title></head><body><div id="px-captcha"></div>` + padding + `</body></html>`, true, "marker"},
+		{"listing challenge", 200, listing(`<script src="/_Incapsula_Resource?SWJIYLWA=1"></script>`), false, "marker"},
+		{"short document", 200, `<html><head><script type="application/ld+json">{}</script></head></html>`, true, "short_dom"},
+		{"detail without json-ld", 200, listing(""), true, "no_json_ld"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := d.Check(tt.status, tt.html, tt.detailPage)
+			if tt.reason == "" {
+				if err != nil {
+					t.Fatalf("Check = %v, want not blocked", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrBlocked) {
+				t.Fatalf("Check = %v, want ErrBlocked", err)
+			}
+			if got := blockReason(err); got != tt.reason {
+				t.Errorf("reason = %q, want %q (%v)", got, tt.reason, err)
+			}
+		})
+	}
+}
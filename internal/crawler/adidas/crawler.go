@@ -1,62 +1,489 @@
 package adidas
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/browser"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/circuit"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/config"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/fingerprint"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/lifecycle"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/logger"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/metrics"
 	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
-	"github.com/jakib01/web-crawiling-golang-colly/internal/repository/postgres"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/proxy"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/repository"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/scheduler"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/service"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/tracing"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/worker"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
-	"gorm.io/gorm"
 	"os"
+	"sync"
+	"time"
 )
 
+// site labels this crawler's metrics.
+const site = "adidas"
+
 type AdidasCrawler struct {
-	db     *gorm.DB
-	logger *zap.SugaredLogger
+	products  *service.ProductService
+	cfg       config.CrawlerConfig
+	profile   config.BrowserProfile
+	resources browser.Rules
+	capture   CapturePatterns
+	policy    scheduler.Policy
+	proxies   *proxy.Pool // may be empty; fetches then go out directly
+	breaker   *circuit.Breaker
+	blocks    BlockDetector
+	stats     *worker.Aggregator
+	logger    *zap.SugaredLogger
+
+	// listedSeeds are the seeds the last discovery read to the end: the
+	// listing pages paginated to their last page, or the sitemaps walked in
+	// full. A product of theirs that was not discovered is no longer listed.
+	listedSeeds []string
+}
+
+func NewAdidasCrawler(products *service.ProductService, cfg config.CrawlerConfig, profile config.BrowserProfile, proxies *proxy.Pool, logger *zap.SugaredLogger) *AdidasCrawler {
+	policy := scheduler.Policy{
+		Initial: cfg.Recrawl.InitialInterval,
+		Min:     cfg.Recrawl.MinInterval,
+		Max:     cfg.Recrawl.MaxInterval,
+	}
+	c := &AdidasCrawler{
+		products:  products,
+		cfg:       cfg,
+		profile:   profile,
+		resources: browser.NewRules(cfg.Resources, profile),
+		capture:   NewCapturePatterns(cfg.Capture),
+		policy:    policy,
+		proxies:   proxies,
+		blocks:    BlockDetector{MinHTMLBytes: cfg.Block.MinHTMLBytes},
+		stats:     worker.NewAggregator(),
+		logger:    logger,
+	}
+	c.breaker = circuit.New(cfg.Block.Threshold, cfg.Block.Cooldown, func(open bool) {
+		if open {
+			metrics.CircuitOpen.WithLabelValues(site).Set(1)
+			logger.Warnw("repeated blocks, pausing crawl", "site", site, "cooldown", cfg.Block.Cooldown)
+		} else {
+			metrics.CircuitOpen.WithLabelValues(site).Set(0)
+			logger.Infow("resuming crawl after block cooldown", "site", site)
+		}
+	})
+	return c
 }
 
-func NewAdidasCrawler(db *gorm.DB, logger *zap.SugaredLogger) *AdidasCrawler {
-	return &AdidasCrawler{db: db, logger: logger}
+// Stats returns the counters collected so far: "discovered", "fetched",
+// "parsed", "failed" and "stored", an error breakdown by stage and the
+// per-product fetch durations.
+func (c *AdidasCrawler) Stats() *worker.Aggregator {
+	return c.stats
 }
 
-func (c *AdidasCrawler) CrawlProducts(limit int) ([]model.ProductURL, error) {
-	products, err := collectProductURLs(limit, c.logger)
+// CrawlProducts discovers up to limit products and fetches them, together
+// with sibling colorways found on their detail pages as long as fewer than
+// limit products were queued in all.
+func (c *AdidasCrawler) CrawlProducts(ctx context.Context, limit int) ([]model.ProductURL, error) {
+	ctx, span := tracing.Start(ctx, "adidas.CrawlProducts", trace.WithAttributes(attribute.Int("limit", limit)))
+	products, err := c.crawlProducts(ctx, limit)
+	tracing.End(span, err)
+	return products, err
+}
+
+func (c *AdidasCrawler) crawlProducts(ctx context.Context, limit int) ([]model.ProductURL, error) {
+	ctx, _ = logger.With(ctx, c.logger, "site", site)
+	products, err := c.discover(ctx, limit)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := postgres.StoreProductURLs(c.db, products); err != nil {
+	if err := c.storeURLs(ctx, products); err != nil {
 		return nil, err
 	}
 
-	var allDetails []model.Product
+	return c.crawlQueue(ctx, products, limit)
+}
+
+// CrawlDue fetches only the known URLs whose recrawl is due, at most budget
+// of them. Newly seen colorways are recorded but left for a later run.
+func (c *AdidasCrawler) CrawlDue(ctx context.Context, budget int) ([]model.ProductURL, error) {
+	ctx, span := tracing.Start(ctx, "adidas.CrawlDue", trace.WithAttributes(attribute.Int("budget", budget)))
+	products, err := c.crawlDue(ctx, budget)
+	tracing.End(span, err)
+	return products, err
+}
 
+func (c *AdidasCrawler) crawlDue(ctx context.Context, budget int) ([]model.ProductURL, error) {
+	ctx, _ = logger.With(ctx, c.logger, "site", site)
+	due, err := c.products.DueURLs(ctx, time.Now(), budget)
+	if err != nil {
+		return nil, err
+	}
+	c.log(ctx).Infow("product URLs due for recrawl", "due", len(due), "budget", budget)
+
+	return c.crawlQueue(ctx, due, 0)
+}
+
+// crawlQueue fetches, stores and exports products on cfg.Concurrency
+// workers. Sibling colorways found on a detail page are recorded, and
+// queued as well while fewer than limit products are queued, so that the
+// variants of a model get crawled without one seed fanning out unbounded.
+func (c *AdidasCrawler) crawlQueue(ctx context.Context, products []model.ProductURL, limit int) ([]model.ProductURL, error) {
+	var (
+		mu       sync.Mutex
+		exported []model.Product
+	)
+
+	queued := make(map[string]bool, len(products))
 	for _, p := range products {
-		detail, err := FetchAndParseDetailPage(p.URL, p.Code)
+		queued[p.URL] = true
+	}
+	c.stats.Add("discovered", len(products))
+
+	// export keeps a stored product for all_products.json, which is
+	// written once the queue is done
+	export := func(detail model.Product) {
+		mu.Lock()
+		exported = append(exported, detail)
+		mu.Unlock()
+	}
+
+	// with a write batch size, products go through a buffered writer whose
+	// full buffer blocks the workers until the next flush
+	var writer *service.ProductWriter
+	if w := c.cfg.Write; w.BatchSize > 0 {
+		writer = service.NewProductWriter(c.products, w.BatchSize, w.FlushInterval,
+			func(ctx context.Context, detail *model.Product, res service.UpsertResult, err error) {
+				if err != nil {
+					c.stored(ctx, detail, false, err)
+				} else {
+					c.stored(ctx, detail, res.Outcome != repository.Unchanged, res.Err)
+				}
+				export(*detail)
+			})
+	}
+
+	pool := worker.NewPool(c.cfg.Concurrency)
+
+	var crawlOne func(ctx context.Context, p model.ProductURL)
+	crawlOne = func(ctx context.Context, p model.ProductURL) {
+		ctx, span := tracing.Start(ctx, "adidas.crawlProduct", trace.WithAttributes(
+			attribute.String("product.code", p.Code),
+			attribute.String("product.url", p.URL),
+		))
+		defer span.End()
+		ctx, _ = logger.With(ctx, c.logger, "product_code", p.Code, "url", p.URL)
+
+		detail, err := c.fetchDetail(ctx, p)
 		if err != nil {
-			c.logger.Warnf("failed to fetch detail for %s: %v", p.URL, err)
-			continue
+			span.RecordError(err)
+			return
 		}
-		allDetails = append(allDetails, detail)
 
-		fmt.Println(detail)
+		mu.Lock()
+		var variants, follow []model.ProductURL
+		for _, v := range detail.Variants {
+			if queued[v.URL] {
+				continue
+			}
+			queued[v.URL] = true
+			v.Seed, v.CategoryPath = p.Seed, p.CategoryPath
+			variants = append(variants, v)
+			if len(products) < limit {
+				products = append(products, v)
+				follow = append(follow, v)
+			}
+		}
+		mu.Unlock()
 
-		// Marshal the entire slice as pretty-printed JSON
-		out, err := json.MarshalIndent(allDetails, "", "  ")
-		if err != nil {
-			return nil, err
+		if len(variants) > 0 {
+			// the product itself is still stored, and the variants are
+			// fetched all the same; they only miss a recrawl schedule
+			if err := c.storeURLs(ctx, variants); err != nil {
+				c.log(ctx).Warnw("failed to store variant URLs", "stage", "store", "error", err)
+				c.stats.Error("store")
+			}
+			c.stats.Add("discovered", len(follow))
+			for _, v := range follow {
+				pool.Submit(ctx, func(ctx context.Context) { crawlOne(ctx, v) })
+			}
 		}
 
-		// Write to a single file
-		if err := os.WriteFile("all_products.json", out, 0644); err != nil {
-			return nil, err
+		if writer == nil {
+			c.storeDetail(ctx, &detail)
+			export(detail)
+			return
+		}
+		if err := writer.Add(ctx, &detail); err != nil {
+			// canceled while the buffer was full: the product is lost to
+			// this run
+			span.RecordError(err)
+			c.log(ctx).Warnw("product dropped before batch write", "stage", "store", "error", err)
+			c.stats.Add("failed", 1)
+			c.stats.Error("store")
 		}
+	}
 
-		//// save detail to DB (use GORM create or update)
-		//if err := postgres.StoreProductDetail(c.db, detail); err != nil {
-		//	c.logger.Warnf("failed to store detail for %s: %v", p.URL, err)
-		//}
+	for _, p := range products {
+		pool.Submit(ctx, func(ctx context.Context) { crawlOne(ctx, p) })
+	}
+	pool.Wait()
+	if writer != nil {
+		writer.Close()
+		st := writer.Stats()
+		c.log(ctx).Infow("batched product writes",
+			"batches", st.Batches, "inserted", st.Inserted, "updated", st.Updated,
+			"unchanged", st.Unchanged, "failed", st.Failed)
 	}
 
+	out, err := json.MarshalIndent(exported, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile("all_products.json", out, 0644); err != nil {
+		return nil, err
+	}
 	return products, nil
 }
+
+// storeDetail saves a parsed product; unchanged products only get
+// last_seen_at bumped.
+func (c *AdidasCrawler) storeDetail(ctx context.Context, detail *model.Product) {
+	ctx, span := tracing.Start(ctx, "service.ProductService.Upsert")
+	changed, err := c.products.Upsert(ctx, detail)
+	tracing.End(span, err)
+	c.stored(ctx, detail, changed, err)
+}
+
+// stored records the result of upserting a product and advances its
+// status. An error with changed set means the product is stored and only
+// relinking its keywords failed.
+func (c *AdidasCrawler) stored(ctx context.Context, detail *model.Product, changed bool, err error) {
+	switch {
+	case err != nil && !changed:
+		c.log(ctx).Warnw("failed to store detail", "stage", "store", "error", err)
+		c.stats.Error("store")
+		return
+	case err != nil:
+		c.log(ctx).Warnw("failed to store keywords", "stage", "keywords", "error", err)
+		c.stats.Error("keywords")
+	}
+	if changed {
+		c.stats.Add("stored", 1)
+	} else {
+		c.log(ctx).Debugw("product unchanged since last crawl", "stage", "store")
+	}
+	if err := c.products.RecordStock(ctx, *detail); err != nil {
+		c.log(ctx).Warnw("failed to record size stock", "stage", "stock", "error", err)
+		c.stats.Error("stock")
+	}
+	c.recordCoordinations(ctx, *detail)
+	c.updateStatus(ctx, *detail)
+}
+
+func (c *AdidasCrawler) storeURLs(ctx context.Context, urls []model.ProductURL) error {
+	ctx, span := tracing.Start(ctx, "service.ProductService.StoreURLs", trace.WithAttributes(attribute.Int("count", len(urls))))
+	err := c.products.StoreURLs(ctx, urls)
+	tracing.End(span, err)
+	return err
+}
+
+// fetchDetail fetches and parses a detail page and records the outcome in
+// the URL's recrawl schedule.
+func (c *AdidasCrawler) fetchDetail(ctx context.Context, p model.ProductURL) (model.Product, error) {
+	var schedule *model.CrawlSchedule
+	if p.ID != 0 {
+		var err error
+		schedule, err = c.products.Schedule(ctx, p.ID)
+		if err != nil {
+			c.log(ctx).Warnw("failed to load crawl schedule", "stage", "schedule", "error", err)
+			schedule = nil
+		}
+	}
+
+	attempt := 1
+	if schedule != nil {
+		attempt += schedule.FailCount
+	}
+	ctx, log := logger.With(ctx, c.logger, "attempt", attempt)
+
+	// every worker waits here while the crawl is paused after blocks
+	if err := c.breaker.Wait(ctx); err != nil {
+		return model.Product{}, stageErr("canceled", err)
+	}
+
+	// the same product keeps its proxy across pages and retries
+	opts := FetchOptions{Profile: c.profile, Resources: c.resources, Blocks: c.blocks, Capture: c.capture}
+	px := c.proxies.Pick(p.Code)
+	if px != nil {
+		opts.Proxy = px.URL
+		ctx, log = logger.With(ctx, log, "proxy", px.String())
+	} else if c.proxies.Len() > 0 {
+		c.stats.Add("failed", 1)
+		c.stats.Error("proxy")
+		log.Warnw("no healthy proxy available", "stage", "proxy")
+		return model.Product{}, stageErr("proxy", proxy.ErrNoProxy)
+	}
+
+	started := time.Now()
+	detail, fetchErr := FetchAndParseDetailPage(ctx, p.URL, p.Code, opts)
+	c.stats.Observe(time.Since(started))
+
+	blocked := errors.Is(fetchErr, ErrBlocked)
+	c.breaker.Record(blocked)
+	switch {
+	case blocked:
+		c.proxies.Report(px, proxy.Blocked)
+	case ErrorStage(fetchErr) == "navigate":
+		c.proxies.Report(px, proxy.Failure)
+	default:
+		c.proxies.Report(px, proxy.Success)
+	}
+
+	if fetchErr == nil {
+		detail.ContentHash = fingerprint.Product(detail)
+		observeFields(detail)
+		c.stats.Add("fetched", 1)
+		c.stats.Add("parsed", 1)
+	} else {
+		stage := ErrorStage(fetchErr)
+		if stage != "navigate" {
+			c.stats.Add("fetched", 1)
+		}
+		c.stats.Add("failed", 1)
+		c.stats.Error(stage)
+		log.Warnw("failed to fetch detail", "stage", stage, "error", fetchErr)
+	}
+	if schedule == nil {
+		return detail, fetchErr
+	}
+
+	ctx, span := tracing.Start(ctx, "service.ProductService.SaveSchedule")
+	defer span.End()
+
+	now := time.Now()
+	if fetchErr != nil {
+		c.policy.RecordFailure(schedule, now)
+	} else {
+		c.policy.RecordFetch(schedule, detail.ContentHash, now)
+	}
+	if err := c.products.SaveSchedule(ctx, schedule); err != nil {
+		log.Warnw("failed to save crawl schedule", "stage", "schedule", "error", err)
+		span.RecordError(err)
+	}
+
+	return detail, fetchErr
+}
+
+// observeFields records which product fields the parser managed to fill.
+func observeFields(p model.Product) {
+	metrics.ObserveField(site, "name", p.Name != "")
+	metrics.ObserveField(site, "category", p.Category != "")
+	metrics.ObserveField(site, "price", p.PriceYen > 0)
+	metrics.ObserveField(site, "title_description", p.TitleDescription != "")
+	metrics.ObserveField(site, "general_description", p.GeneralDescription != "")
+	metrics.ObserveField(site, "images", len(p.Images) > 0)
+	metrics.ObserveField(site, "sizes", len(p.Sizes) > 0)
+	metrics.ObserveField(site, "reviews", len(p.Reviews) > 0)
+	metrics.ObserveField(site, "aspect_ratings", len(p.AspectRatings) > 0)
+	metrics.ObserveField(site, "coordinated", len(p.Coordinated) > 0)
+}
+
+// recordCoordinations adds a fetched product's coordinated products to the
+// coordination graph and, if configured, queues the ones not stored yet.
+func (c *AdidasCrawler) recordCoordinations(ctx context.Context, p model.Product) {
+	if err := c.products.RecordCoordinations(ctx, p); err != nil {
+		c.log(ctx).Warnw("failed to record coordinated products", "stage", "coordinated", "error", err)
+		c.stats.Error("coordinated")
+		return
+	}
+	if !c.cfg.EnqueueCoordinated {
+		return
+	}
+	queued, err := c.products.EnqueueCoordinated(ctx, p.ProductCode)
+	if err != nil {
+		c.log(ctx).Warnw("failed to enqueue coordinated products", "stage", "coordinated", "error", err)
+		c.stats.Error("coordinated")
+		return
+	}
+	if len(queued) > 0 {
+		c.log(ctx).Debugw("queued coordinated products for crawling", "stage", "coordinated", "count", len(queued))
+	}
+}
+
+// updateStatus advances the lifecycle status of a freshly fetched product.
+func (c *AdidasCrawler) updateStatus(ctx context.Context, p model.Product) {
+	inStock := false
+	for _, s := range p.Sizes {
+		if s.Availability > 0 {
+			inStock = true
+			break
+		}
+	}
+
+	next := lifecycle.Next(p.Status, lifecycle.Observation{Fetched: true, InStock: inStock})
+	if err := c.products.SetStatus(ctx, p, next, "crawl"); err != nil {
+		c.log(ctx).Warnw("failed to update product status", "stage", "store", "status", next, "error", err)
+	}
+}
+
+// log returns the request-scoped logger from ctx, falling back to the
+// crawler's own.
+func (c *AdidasCrawler) log(ctx context.Context) *zap.SugaredLogger {
+	return logger.FromContext(ctx, c.logger)
+}
+
+// Seeds returns the listing pages or sitemaps discovery starts from.
+func (c *AdidasCrawler) Seeds() []string {
+	if c.cfg.Discovery == "sitemap" {
+		if len(c.cfg.Sitemap.URLs) > 0 {
+			return c.cfg.Sitemap.URLs
+		}
+		return []string{c.cfg.Sitemap.RobotsURL}
+	}
+	return c.cfg.SeedURLs
+}
+
+// discover returns candidate product URLs using the configured discovery mode.
+func (c *AdidasCrawler) discover(ctx context.Context, limit int) ([]model.ProductURL, error) {
+	ctx, span := tracing.Start(ctx, "adidas.discover", trace.WithAttributes(attribute.String("discovery", c.cfg.Discovery)))
+	var (
+		products []model.ProductURL
+		complete []string
+		err      error
+	)
+	if c.cfg.Discovery == "sitemap" {
+		products, complete, err = collectSitemapURLs(ctx, c.cfg.Sitemap, limit, c.profile, c.proxies, c.log(ctx))
+	} else {
+		opts := FetchOptions{Profile: c.profile, Resources: c.resources, Blocks: c.blocks}
+		px := c.proxies.Pick("listing")
+		if px != nil {
+			opts.Proxy = px.URL
+		}
+		// a blocked listing page pauses like the detail workers do and is
+		// retried once more after the breaker's cooldown
+		opts.Pause = func(ctx context.Context, attempt int, blocked error) error {
+			c.proxies.Report(px, proxy.Blocked)
+			c.breaker.Record(true)
+			if attempt > c.cfg.Block.Threshold {
+				return blocked
+			}
+			return c.breaker.Wait(ctx)
+		}
+		products, complete, err = collectProductURLs(ctx, c.cfg.SeedURLs, limit, c.log(ctx), opts)
+		if err == nil {
+			c.breaker.Record(false)
+		}
+	}
+	if err == nil {
+		c.listedSeeds = complete
+	}
+	span.SetAttributes(attribute.Int("discovered", len(products)))
+	tracing.End(span, err)
+	return products, err
+}
@@ -0,0 +1,153 @@
+package adidas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/config"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/lifecycle"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/logger"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/proxy"
+)
+
+// DetectDelisted checks up to limit products that were listed under the
+// seeds the last discovery read to the end before since but were not
+// discovered again, and marks the ones whose detail URL is gone as
+// delisted. It needs a crawl without a limit cutting the listings short to
+// have run first. Requests are DelistingDelay apart; blocked ones count
+// towards the crawl's breaker, and the check stops once it opens.
+func (c *AdidasCrawler) DetectDelisted(ctx context.Context, since time.Time, limit int) (int, error) {
+	seeds := c.listedSeeds
+	if len(seeds) == 0 {
+		return 0, fmt.Errorf("no seed was listed to its end; run a full crawl without -limit first")
+	}
+
+	missing, err := c.products.MissingFromListings(ctx, seeds, since, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, _ = logger.With(ctx, c.logger, "site", site, "stage", "delisting")
+
+	// one client per proxy, so connections are reused across products
+	clients := map[*proxy.Proxy]*http.Client{}
+	defer func() {
+		for _, client := range clients {
+			client.CloseIdleConnections()
+		}
+	}()
+
+	delisted := 0
+	for i, p := range missing {
+		if c.breaker.Open() {
+			c.log(ctx).Warnw("delisting check stopped while the crawl is paused after blocks", "delisted", delisted, "checked", i)
+			return delisted, fmt.Errorf("delisting check stopped after %d of %d products: %w", i, len(missing), ErrBlocked)
+		}
+		if i > 0 && c.cfg.DelistingDelay > 0 {
+			select {
+			case <-ctx.Done():
+				return delisted, ctx.Err()
+			case <-time.After(c.cfg.DelistingDelay):
+			}
+		}
+
+		log := c.log(ctx).With("product_code", p.Code, "url", p.URL)
+		px := c.proxies.Pick(p.Code)
+		if px == nil && c.proxies.Len() > 0 {
+			log.Warnw("delisting check skipped", "error", proxy.ErrNoProxy)
+			continue
+		}
+		client := clients[px]
+		if client == nil {
+			client = proxy.Client(px, 30*time.Second)
+			// a redirect is an answer in itself, don't follow it
+			client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+			clients[px] = client
+		}
+
+		gone, reason, err := checkDetailGone(ctx, client, c.profile, c.blocks, p)
+		blocked := errors.Is(err, ErrBlocked)
+		c.breaker.Record(blocked)
+		switch {
+		case blocked:
+			c.proxies.Report(px, proxy.Blocked)
+			log.Warnw("delisting check blocked", "reason", blockReason(err), "error", err)
+			continue
+		case err != nil:
+			c.proxies.Report(px, proxy.Failure)
+			log.Warnw("delisting check failed", "error", err)
+			continue
+		}
+		c.proxies.Report(px, proxy.Success)
+		if !gone {
+			continue
+		}
+
+		product, err := c.products.Product(ctx, p.Code)
+		if err != nil {
+			log.Warnw("failed to load product", "error", err)
+			continue
+		}
+		next := lifecycle.Next(product.Status, lifecycle.Observation{Gone: true})
+		if err := c.products.SetStatus(ctx, *product, next, reason); err != nil {
+			log.Warnw("failed to mark product delisted", "error", err)
+			continue
+		}
+		delisted++
+	}
+
+	c.log(ctx).Infow("delisting check finished", "delisted", delisted, "checked", len(missing))
+	return delisted, nil
+}
+
+// checkDetailGone confirms a missing product by requesting its detail page:
+// 404/410 or a redirect to a page that is not the same product means gone.
+// Any other answer must be the product page itself; a block or challenge
+// page is returned as an error matching ErrBlocked.
+func checkDetailGone(ctx context.Context, client *http.Client, profile config.BrowserProfile, blocks BlockDetector, p model.ProductURL) (bool, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return false, "", err
+	}
+	req.Header.Set("User-Agent", profile.UserAgent)
+	if profile.AcceptLanguage != "" {
+		req.Header.Set("Accept-Language", profile.AcceptLanguage)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, "", err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return true, fmt.Sprintf("detail page returned %d", resp.StatusCode), nil
+	case resp.StatusCode >= 300 && resp.StatusCode < 400:
+		loc, err := url.Parse(resp.Header.Get("Location"))
+		if err != nil || productCodeFromHref(loc.Path) != p.Code {
+			return true, fmt.Sprintf("detail page redirected to %q", resp.Header.Get("Location")), nil
+		}
+		return false, "", nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDetailBytes))
+	if err != nil {
+		return false, "", err
+	}
+	err = blocks.Check(resp.StatusCode, string(body), true)
+	if resp.StatusCode != http.StatusOK && blockReason(err) != "status" {
+		// a server error is not a block, and says nothing about the product
+		return false, "", fmt.Errorf("detail page returned %d", resp.StatusCode)
+	}
+	return false, "", err
+}
+
+// maxDetailBytes bounds how much of a detail page checkDetailGone reads.
+const maxDetailBytes = 8 << 20
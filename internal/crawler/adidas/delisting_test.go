@@ -0,0 +1,80 @@
+package adidas
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/config"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+)
+
+func TestCheckDetailGone(t *testing.T) {
+	productPage := `<html><head><title>サンバ OG / Samba OG | アディダス公式通販</title>
+<script type="application/ld+json">{"@type":"Product","sku":"JI2734"}</script></head><body>` +
+		strings.Repeat("<div>content</div>", 400) + `</body></html>`
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		gone    bool
+		blocked bool
+		failed  bool
+	}{
+		{"not found", func(w http.ResponseWriter, r *http.Request) {
+			http.NotFound(w, r)
+		}, true, false, false},
+		{"gone", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusGone)
+		}, true, false, false},
+		{"redirect to a category", func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "/メンズ", http.StatusMovedPermanently)
+		}, true, false, false},
+		{"redirect to another product", func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "/samba-og/IG1025.html", http.StatusFound)
+		}, true, false, false},
+		{"redirect to the same product", func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "/samba-og-shoes/JI2734.html?forceSelSize=1", http.StatusMovedPermanently)
+		}, false, false, false},
+		{"product page", func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, productPage)
+		}, false, false, false},
+		{"forbidden", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, productPage)
+		}, false, true, false},
+		{"too many requests", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}, false, true, false},
+		{"challenge page", func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, `<html><head><title>Access Denied</title></head><body></body></html>`)
+		}, false, true, false},
+		{"server error", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}, false, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.handler)
+			defer srv.Close()
+			client := srv.Client()
+			client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+
+			p := model.ProductURL{Code: "JI2734", URL: srv.URL + "/samba-og/JI2734.html"}
+			gone, reason, err := checkDetailGone(context.Background(), client, config.BrowserProfile{}, BlockDetector{MinHTMLBytes: 5000}, p)
+			if blocked := errors.Is(err, ErrBlocked); blocked != tt.blocked {
+				t.Fatalf("err = %v, want blocked %v", err, tt.blocked)
+			}
+			if failed := err != nil && !tt.blocked; failed != tt.failed {
+				t.Fatalf("err = %v, want failed %v", err, tt.failed)
+			}
+			if gone != tt.gone || gone != (reason != "") {
+				t.Errorf("gone = %v (%q), want %v", gone, reason, tt.gone)
+			}
+		})
+	}
+}
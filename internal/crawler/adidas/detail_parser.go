@@ -3,6 +3,8 @@ package adidas
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -10,43 +12,109 @@ import (
 	"context"
 	"github.com/PuerkitoBio/goquery"
 	"github.com/chromedp/chromedp"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/browser"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/config"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/logger"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/metrics"
 	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 )
 
-func FetchAndParseDetailPage(url string, code string) (model.Product, error) {
-	opts := append(chromedp.DefaultExecAllocatorOptions[:],
-		chromedp.Flag("headless", true),
-		chromedp.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/114.0.0.0 Safari/537.36"),
-	)
+// FetchOptions tune how a page is fetched.
+type FetchOptions struct {
+	// Profile is the browser fingerprint the session presents.
+	Profile config.BrowserProfile
+	// Resources filters the subresources the page may load.
+	Resources browser.Rules
+	// Proxy, if set, is the proxy the session goes through; its
+	// credentials are sent when the proxy asks for them.
+	Proxy *url.URL
+	// Allocator options are applied on top of the profile.
+	Allocator []chromedp.ExecAllocatorOption
+	Blocks    BlockDetector
+	// Pause, if set, is called when a listing page is blocked, with the
+	// number of consecutive blocks. It returns once the page may be
+	// retried, or an error to give up on it.
+	Pause func(ctx context.Context, attempt int, blocked error) error
+	// Capture picks the JSON API responses read in place of the DOM; see
+	// NewCapturePatterns.
+	Capture CapturePatterns
+}
 
-	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), opts...)
-	defer cancelAlloc()
+// FetchAndParseDetailPage loads a product detail page in headless Chrome and
+// parses it into a model.Product. A block or challenge page yields an error
+// matching ErrBlocked instead of a mostly empty product.
+func FetchAndParseDetailPage(ctx context.Context, url string, code string, opts FetchOptions) (model.Product, error) {
+	ctx, span := tracing.Start(ctx, "adidas.FetchAndParseDetailPage", trace.WithAttributes(
+		attribute.String("product.code", code),
+		attribute.String("product.url", url),
+	))
+	product, err := fetchAndParseDetailPage(ctx, url, code, opts)
+	tracing.End(span, err)
+	return product, err
+}
 
-	ctx, cancel := chromedp.NewContext(allocCtx)
+func fetchAndParseDetailPage(ctx context.Context, url string, code string, fetchOpts FetchOptions) (model.Product, error) {
+	ctx, cancel, err := browser.Start(ctx, fetchOpts.Profile, fetchOpts.Resources, fetchOpts.Proxy, fetchOpts.Allocator...)
+	if err != nil {
+		return model.Product{}, stageErr("navigate", err)
+	}
 	defer cancel()
 
 	ctx, cancel = context.WithTimeout(ctx, 60*time.Second)
 	defer cancel()
 
+	capture := browser.NewCapture(fetchOpts.Capture.all()...)
+	if err := chromedp.Run(ctx, capture.Listen()); err != nil {
+		return model.Product{}, stageErr("navigate", err)
+	}
+
 	var html string
-	if err := chromedp.Run(ctx,
-		chromedp.Navigate(url),
-		chromedp.Sleep(3*time.Second),
-		chromedp.OuterHTML("html", &html),
-	); err != nil {
-		return model.Product{}, err
+	started := time.Now()
+	resp, err := chromedp.RunResponse(ctx, chromedp.Navigate(url))
+	if err == nil {
+		err = chromedp.Run(ctx,
+			chromedp.Sleep(3*time.Second),
+			chromedp.OuterHTML("html", &html),
+		)
+	}
+	capture.Wait(ctx)
+	metrics.ObservePageFetch(site, metrics.PageDetail, started, err)
+	if err != nil {
+		return model.Product{}, stageErr("navigate", err)
+	}
+
+	status := 0
+	if resp != nil {
+		status = int(resp.Status)
+	}
+	if err := fetchOpts.Blocks.Check(status, html, true); err != nil {
+		metrics.ObserveBlock(site, metrics.PageDetail, blockReason(err))
+		return model.Product{}, stageErr("blocked", err)
 	}
 
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
 	if err != nil {
-		return model.Product{}, err
+		return model.Product{}, stageErr("parse", err)
 	}
 
 	// Extract fields
 	name := strings.TrimSpace(doc.Find(`h1[data-auto-id="product-title"]`).Text())
 	priceStr := doc.Find(`[data-testid="main-price"] span`).Last().Text()
 
+	log := logger.FromContext(ctx, zap.NewNop().Sugar()).With("stage", "parse")
+	if name == "" {
+		log.Debugw("selector matched nothing", "selector", `h1[data-auto-id="product-title"]`)
+	}
+	if priceStr == "" {
+		log.Debugw("selector matched nothing", "selector", `[data-testid="main-price"] span`)
+	}
+
 	var category, titleDescription, generalDescription string
+	var productGroupID, color string
 	reviewCount := 0
 
 	doc.Find(`script[type="application/ld+json"]`).Each(func(i int, s *goquery.Selection) {
@@ -65,6 +133,12 @@ func FetchAndParseDetailPage(url string, code string) (model.Product, error) {
 			if val, ok := data["description"].(string); ok {
 				generalDescription = val
 			}
+			if val, ok := data["productGroupID"].(string); ok {
+				productGroupID = val
+			}
+			if val, ok := data["color"].(string); ok {
+				color = val
+			}
 			// optional fallback for titleDescription
 			//if titleDescription == "" {
 			//	titleDescription = val
@@ -90,26 +164,43 @@ func FetchAndParseDetailPage(url string, code string) (model.Product, error) {
 		priceYen, _ = strconv.ParseFloat(cleaned, 64)
 	}
 
-	sizes, err := ExtractProductSizes(ctx)
-	if err != nil {
-		return model.Product{}, fmt.Errorf("extract sizes failed: %w", err)
+	// Prefer what the page's own JSON APIs returned; the DOM is the fallback.
+	api := decodeCaptured(capture, fetchOpts.Capture, log)
+	if api.ReviewCount > 0 {
+		reviewCount = api.ReviewCount
+	}
+
+	sizes := api.Sizes
+	if sizes == nil {
+		sizes, err = ExtractProductSizes(ctx)
+		if err != nil {
+			return model.Product{}, stageErr("sizes", fmt.Errorf("extract sizes failed: %w", err))
+		}
 	}
 
 	// Extract reviews
-	reviews, err := ExtractReviews(ctx)
-	if err != nil {
-		return model.Product{}, fmt.Errorf("extract reviews failed: %w", err)
+	reviews := api.Reviews
+	if reviews == nil {
+		reviews, err = ExtractReviews(ctx)
+		if err != nil {
+			return model.Product{}, stageErr("reviews", fmt.Errorf("extract reviews failed: %w", err))
+		}
 	}
 
 	// Extract reviews
-	aspectRatings, err := ExtractAspectRatings(ctx)
-	if err != nil {
-		return model.Product{}, fmt.Errorf("extract aspectRatings failed: %w", err)
+	aspectRatings := api.AspectRatings
+	if aspectRatings == nil {
+		aspectRatings, err = ExtractAspectRatings(ctx)
+		if err != nil {
+			return model.Product{}, stageErr("aspects", fmt.Errorf("extract aspectRatings failed: %w", err))
+		}
 	}
 
-	coordinatedItems, err := ExtractCoordinatedItems(doc)
+	_, coordSpan := tracing.Start(ctx, "adidas.ExtractCoordinatedItems")
+	coordinatedItems, err := ExtractCoordinatedItems(doc, url)
+	tracing.End(coordSpan, err)
 	if err != nil {
-		return model.Product{}, fmt.Errorf("extract coordinatedItems failed: %w", err)
+		return model.Product{}, stageErr("coordinated", fmt.Errorf("extract coordinatedItems failed: %w", err))
 	}
 
 	// Extract product images
@@ -125,6 +216,11 @@ func FetchAndParseDetailPage(url string, code string) (model.Product, error) {
 		})
 	})
 
+	var group *model.ProductGroup
+	if key := groupKeyFor(productGroupID); key != "" {
+		group = &model.ProductGroup{GroupKey: key, Name: name}
+	}
+
 	var data = model.Product{
 		ProductCode:                code,
 		Name:                       name,
@@ -132,6 +228,7 @@ func FetchAndParseDetailPage(url string, code string) (model.Product, error) {
 		PriceYen:                   priceYen,
 		SenseOfSize:                "",
 		TotalReviews:               reviewCount,
+		OverallRating:              api.OverallRating,
 		DetailsURL:                 url,
 		TitleDescription:           titleDescription,
 		GeneralDescription:         generalDescription,
@@ -142,11 +239,17 @@ func FetchAndParseDetailPage(url string, code string) (model.Product, error) {
 		Images:                     images,
 		AspectRatings:              aspectRatings,
 		Coordinated:                coordinatedItems,
+		Color:                      color,
+		ProductGroup:               group,
+		Variants:                   extractColorVariants(doc, url, code),
 	}
 	return data, nil
 }
 
-func ExtractProductSizes(ctx context.Context) ([]model.ProductSize, error) {
+func ExtractProductSizes(ctx context.Context) (sizes []model.ProductSize, err error) {
+	ctx, span := tracing.Start(ctx, "adidas.ExtractProductSizes")
+	defer func() { tracing.End(span, err) }()
+
 	// Wait for the size-selector section to become visible
 	if err := chromedp.Run(ctx,
 		chromedp.WaitVisible(`div.size-selector___2kfnl`, chromedp.ByQuery),
@@ -155,36 +258,54 @@ func ExtractProductSizes(ctx context.Context) ([]model.ProductSize, error) {
 		return nil, fmt.Errorf("size container not visible: %w", err)
 	}
 
-	// Pull all span texts under the sizes container, filtering out placeholder 'AAA'
-	var labels []string
-	js := `
-        Array.from(
-            document.querySelectorAll('div.sizes___2jQjF .gl-label span')
-        ).map(el => el.textContent.trim())
-         .filter(lbl => lbl && lbl !== 'AAA')
-    `
+	var html string
 	if err := chromedp.Run(ctx,
-		chromedp.Evaluate(js, &labels),
+		chromedp.OuterHTML(`div.sizes___2jQjF`, &html, chromedp.ByQuery),
 	); err != nil {
-		return nil, fmt.Errorf("evaluate size labels error: %w", err)
+		return nil, fmt.Errorf("read sizes container error: %w", err)
 	}
+	return parseSizes(html)
+}
 
-	if len(labels) == 0 {
-		return nil, fmt.Errorf("no sizes found in DOM via JS")
-	}
+// soldOutClass matches the class names of a size button that is shown but
+// cannot be added to the bag.
+var soldOutClass = regexp.MustCompile(`(?i)unavailable|disabled`)
 
-	sizes := make([]model.ProductSize, len(labels))
-	for i, lbl := range labels {
-		sizes[i] = model.ProductSize{
-			ProductID:    0,
-			SizeLabel:    lbl,
-			Availability: 1,
+// parseSizes reads the size labels of a rendered sizes container, skipping
+// the placeholder 'AAA'. Sold-out sizes are rendered as disabled buttons;
+// the DOM tells sold out from available but gives no quantity.
+func parseSizes(html string) ([]model.ProductSize, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return nil, err
+	}
+	var sizes []model.ProductSize
+	doc.Find(`.gl-label span`).Each(func(_ int, s *goquery.Selection) {
+		label := strings.TrimSpace(s.Text())
+		if label == "" || label == "AAA" {
+			return
 		}
+		size := model.ProductSize{SizeLabel: label, Availability: 1}
+		if btn := s.Closest("button"); btn.Length() > 0 {
+			_, disabled := btn.Attr("disabled")
+			aria, _ := btn.Attr("aria-disabled")
+			class, _ := btn.Attr("class")
+			if disabled || aria == "true" || soldOutClass.MatchString(class) {
+				size.Availability = 0
+			}
+		}
+		sizes = append(sizes, size)
+	})
+	if len(sizes) == 0 {
+		return nil, fmt.Errorf("no sizes found in DOM")
 	}
 	return sizes, nil
 }
 
-func ExtractReviews(ctx context.Context) ([]model.Review, error) {
+func ExtractReviews(ctx context.Context) (reviews []model.Review, err error) {
+	ctx, span := tracing.Start(ctx, "adidas.ExtractReviews")
+	defer func() { tracing.End(span, err) }()
+
 	// Expand the reviews accordion
 	if err := chromedp.Run(ctx,
 		chromedp.Click(`div[data-testid="accordion"] button.accordion__header___3Pii5`, chromedp.ByQuery),
@@ -229,7 +350,6 @@ func ExtractReviews(ctx context.Context) ([]model.Review, error) {
 		return nil, fmt.Errorf("evaluate reviews JS error: %w", err)
 	}
 
-	var reviews []model.Review
 	for _, r := range raw {
 		t, _ := time.Parse("2006-1-2", r.DateText)
 		reviews = append(reviews, model.Review{
@@ -243,7 +363,10 @@ func ExtractReviews(ctx context.Context) ([]model.Review, error) {
 }
 
 // ExtractAspectRatings scrapes aspect ratings from the expanded review section via Chromedp.
-func ExtractAspectRatings(ctx context.Context) ([]model.ReviewAspectRating, error) {
+func ExtractAspectRatings(ctx context.Context) (aspects []model.ReviewAspectRating, err error) {
+	ctx, span := tracing.Start(ctx, "adidas.ExtractAspectRatings")
+	defer func() { tracing.End(span, err) }()
+
 	// 1) Expand the reviews accordion if it's collapsed
 	if err := chromedp.Run(ctx,
 		chromedp.Click(`div[data-testid="accordion"] button.accordion__header___3Pii5`, chromedp.ByQuery),
@@ -273,7 +396,7 @@ func ExtractAspectRatings(ctx context.Context) ([]model.ReviewAspectRating, erro
 	}
 
 	// 3) Map into your model.ReviewAspectRating
-	aspects := make([]model.ReviewAspectRating, len(raw))
+	aspects = make([]model.ReviewAspectRating, len(raw))
 	for i, a := range raw {
 		aspects[i] = model.ReviewAspectRating{Aspect: a.Aspect, Rating: a.Rating}
 	}
@@ -281,8 +404,28 @@ func ExtractAspectRatings(ctx context.Context) ([]model.ReviewAspectRating, erro
 }
 
 // ExtractCoordinatedItems pulls both style‐lookbook cards and the "complete the look" product carousel
-func ExtractCoordinatedItems(doc *goquery.Document) ([]model.CoordinatedItem, error) {
+// from a detail page. Links and images are resolved against pageURL, and
+// ProductNumber is the product code of the linked detail page, or empty
+// for lookbook cards that link elsewhere. Every product is listed once.
+func ExtractCoordinatedItems(doc *goquery.Document, pageURL string) ([]model.CoordinatedItem, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page URL %q: %w", pageURL, err)
+	}
 	var items []model.CoordinatedItem
+	seen := map[string]bool{}
+	add := func(item model.CoordinatedItem) {
+		if item.ProductNumber != "" {
+			if seen[item.ProductNumber] {
+				return
+			}
+			seen[item.ProductNumber] = true
+		}
+		if item.ImageURL != "" {
+			item.ImageURL = resolveURL(base, item.ImageURL)
+		}
+		items = append(items, item)
+	}
 
 	// 1) Lookbook / style cards
 	doc.Find(`div[data-testid="styles-carousel"] a[data-testid="style-card"]`).Each(func(_ int, s *goquery.Selection) {
@@ -291,20 +434,23 @@ func ExtractCoordinatedItems(doc *goquery.Document) ([]model.CoordinatedItem, er
 		img, _ := s.Find("span._imageWrap_1hxoi_9 img").First().Attr("src")
 		headline := strings.TrimSpace(s.Find(`[data-testid="style-card-headline"]`).Text())
 		desc := strings.TrimSpace(s.Find(`[data-testid="style-card-description"]`).Text())
-		items = append(items, model.CoordinatedItem{
-			ProductNumber:  "", // none for lookbook
+		add(model.CoordinatedItem{
+			ProductNumber:  productCodeFromHref(href), // "" unless it links a product
 			Name:           fmt.Sprintf("%s %s", headline, desc),
 			PriceYen:       0, // no price
 			ImageURL:       img,
-			ProductPageURL: href,
+			ProductPageURL: resolveURL(base, href),
 		})
 	})
 
 	// 2) "Complete the look" product recommendations
 	doc.Find(`#gl-carousel-system-product-carousel-complete-the-look-recs-content li`).Each(func(_ int, s *goquery.Selection) {
-		id, _ := s.Attr("id")
 		card := s.Find(`a._product-card__link_o6rgp_73`)
 		href, _ := card.Attr("href")
+		code := productCodeFromHref(href)
+		if code == "" {
+			return
+		}
 		img, _ := card.Find(`img`).First().Attr("src")
 		name := strings.TrimSpace(card.Find("h4").Text())
 		priceStr := strings.TrimSpace(card.Find(`[data-testid="main-price"]`).Text())
@@ -312,12 +458,12 @@ func ExtractCoordinatedItems(doc *goquery.Document) ([]model.CoordinatedItem, er
 		clean := strings.ReplaceAll(strings.ReplaceAll(priceStr, "¥", ""), ",", "")
 		priceVal, _ := strconv.ParseFloat(clean, 64)
 
-		items = append(items, model.CoordinatedItem{
-			ProductNumber:  id,
+		add(model.CoordinatedItem{
+			ProductNumber:  code,
 			Name:           name,
 			PriceYen:       priceVal,
 			ImageURL:       img,
-			ProductPageURL: href,
+			ProductPageURL: resolveURL(base, strings.SplitN(href, "?", 2)[0]),
 		})
 	})
 
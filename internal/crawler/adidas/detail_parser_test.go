@@ -0,0 +1,35 @@
+package adidas
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSizes(t *testing.T) {
+	html, err := os.ReadFile(filepath.Join("testdata", "sizes_JI2734.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sizes, err := parseSizes(string(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		label string
+		avail float64
+	}{{"24.5cm", 1}, {"25.5cm", 1}, {"26.0cm", 0}, {"26.5cm", 0}, {"27.0cm", 0}, {"27.5cm", 1}}
+	if len(sizes) != len(want) {
+		t.Fatalf("sizes = %+v", sizes)
+	}
+	for i, w := range want {
+		if s := sizes[i]; s.SizeLabel != w.label || s.Availability != w.avail {
+			t.Errorf("size %d = %s/%v, want %s/%v", i, s.SizeLabel, s.Availability, w.label, w.avail)
+		}
+	}
+
+	if _, err := parseSizes(`<div class="sizes___2jQjF"></div>`); err == nil {
+		t.Error("parseSizes of an empty container succeeded")
+	}
+}
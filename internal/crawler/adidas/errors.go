@@ -0,0 +1,27 @@
+package adidas
+
+import "errors"
+
+// StageError tells which step of fetching a detail page failed, e.g.
+// "navigate", "parse" or "sizes".
+type StageError struct {
+	Stage string
+	Err   error
+}
+
+func (e *StageError) Error() string { return e.Err.Error() }
+
+func (e *StageError) Unwrap() error { return e.Err }
+
+func stageErr(stage string, err error) error {
+	return &StageError{Stage: stage, Err: err}
+}
+
+// ErrorStage returns the stage of a StageError in err's chain, or "unknown".
+func ErrorStage(err error) string {
+	var se *StageError
+	if errors.As(err, &se) {
+		return se.Stage
+	}
+	return "unknown"
+}
@@ -3,101 +3,228 @@ package adidas
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/chromedp/chromedp"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/browser"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/metrics"
 	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
 const step = 48
 
-func collectProductURLs(limit int, logger *zap.SugaredLogger) ([]model.ProductURL, error) {
-	opts := append(chromedp.DefaultExecAllocatorOptions[:],
-		chromedp.Flag("headless", true),
-		chromedp.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/114.0.0.0 Safari/537.36"),
-	)
-
-	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), opts...)
-	defer cancelAlloc()
+// seedTimeout bounds the pagination of one seed listing.
+const seedTimeout = 60 * time.Second
+
+// collectProductURLs paginates every seed listing page and returns up to
+// limit product URLs in total. Each seed gets an even share of the limit so
+// that one large category cannot starve the others. A product listed under
+// several seeds is returned once, carrying every seed it was found on.
+// complete lists the seeds whose listing was paginated to its last page,
+// the only ones whose products not returned can be told apart from the
+// ones cut off by the limit.
+func collectProductURLs(ctx context.Context, seeds []string, limit int, logger *zap.SugaredLogger, fetchOpts FetchOptions) (productList []model.ProductURL, complete []string, err error) {
+	if len(seeds) == 0 {
+		return nil, nil, fmt.Errorf("no seed URLs configured")
+	}
 
-	ctx, cancel := chromedp.NewContext(allocCtx)
+	// start the browser outside of the per-seed timeouts below
+	ctx, cancel, err := browser.Start(ctx, fetchOpts.Profile, fetchOpts.Resources, fetchOpts.Proxy, fetchOpts.Allocator...)
+	if err != nil {
+		return nil, nil, err
+	}
 	defer cancel()
 
-	ctx, cancel = context.WithTimeout(ctx, 60*time.Second)
-	defer cancel()
+	productIndex := map[string]int{}
+	perSeed := (limit + len(seeds) - 1) / len(seeds)
+
+	for _, seed := range seeds {
+		if len(productList) >= limit {
+			break
+		}
+		quota := perSeed
+		if remaining := limit - len(productList); remaining < quota {
+			quota = remaining
+		}
+
+		var ended bool
+		productList, ended, err = collectFromSeed(ctx, seed, quota, productIndex, productList, fetchOpts, logger)
+		if err != nil {
+			return productList, complete, err
+		}
+		if ended {
+			complete = append(complete, seed)
+		}
+	}
+
+	return productList, complete, nil
+}
+
+// collectFromSeed paginates a single listing page until quota new products
+// were found or the listing runs out; ended reports the latter, a page
+// without any product links. URLs already in productIndex are not counted
+// again; the seed is only added to their Seeds. A blocked page is retried
+// once fetchOpts.Pause allows it; otherwise the block stops discovery
+// altogether with an error matching ErrBlocked. A seed gets seedTimeout to
+// paginate, restarted after a pause.
+func collectFromSeed(ctx context.Context, seed string, quota int, productIndex map[string]int, productList []model.ProductURL, fetchOpts FetchOptions, logger *zap.SugaredLogger) (_ []model.ProductURL, ended bool, _ error) {
+	base, err := url.Parse(seed)
+	if err != nil {
+		logger.Errorw("invalid seed URL", "stage", "discover", "seed", seed, "error", err)
+		return productList, false, nil
+	}
+	logger = logger.With("stage", "discover", "seed", seed)
+	categoryPath := categoryPathFromURL(base)
 
-	productMap := map[string]bool{}
-	var productList []model.ProductURL
+	var (
+		seedCtx    context.Context
+		cancelSeed context.CancelFunc
+	)
+	restart := func() { seedCtx, cancelSeed = context.WithTimeout(ctx, seedTimeout) }
+	restart()
+	defer func() { cancelSeed() }()
+
+	added := 0
 	start := 0
+	blocked := 0
 
-	for len(productList) < limit {
-		pageURL := "https://www.adidas.jp/メンズ"
+	for added < quota {
+		pageURL := seed
 		if start > 0 {
-			pageURL = fmt.Sprintf("%s?start=%d", pageURL, start)
+			sep := "?"
+			if strings.Contains(seed, "?") {
+				sep = "&"
+			}
+			pageURL = fmt.Sprintf("%s%sstart=%d", pageURL, sep, start)
 		}
 
+		pageCtx, span := tracing.Start(seedCtx, "adidas.listingPage", trace.WithAttributes(
+			attribute.String("seed", seed),
+			attribute.String("page.url", pageURL),
+			attribute.Int("start", start),
+		))
+
 		var html string
-		err := chromedp.Run(ctx,
-			chromedp.Navigate(pageURL),
-			chromedp.Sleep(6*time.Second),
-			chromedp.OuterHTML("html", &html),
-		)
+		started := time.Now()
+		resp, err := chromedp.RunResponse(pageCtx, chromedp.Navigate(pageURL))
+		if err == nil {
+			err = chromedp.Run(pageCtx,
+				chromedp.Sleep(6*time.Second),
+				chromedp.OuterHTML("html", &html),
+			)
+		}
+		metrics.ObservePageFetch(site, metrics.PageListing, started, err)
+		if err == nil {
+			status := 0
+			if resp != nil {
+				status = int(resp.Status)
+			}
+			if blockErr := fetchOpts.Blocks.Check(status, html, false); blockErr != nil {
+				metrics.ObserveBlock(site, metrics.PageListing, blockReason(blockErr))
+				tracing.End(span, blockErr)
+				blocked++
+				if fetchOpts.Pause == nil {
+					return productList, false, blockErr
+				}
+				logger.Warnw("listing page blocked", "url", pageURL, "attempt", blocked, "reason", blockReason(blockErr))
+				cancelSeed()
+				if err := fetchOpts.Pause(ctx, blocked, blockErr); err != nil {
+					return productList, false, err
+				}
+				restart()
+				continue
+			}
+		}
+		blocked = 0
+		tracing.End(span, err)
 		if err != nil {
-			logger.Errorf("Failed to load %s: %v", pageURL, err)
+			logger.Errorw("failed to load listing page", "url", pageURL, "error", err)
 			break
 		}
 
 		doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
 		if err != nil {
-			logger.Errorf("Failed to parse HTML: %v", err)
+			logger.Errorw("failed to parse listing HTML", "url", pageURL, "error", err)
 			break
 		}
 
-		found := 0
-		doc.Find("a[href$='.html']").Each(func(_ int, s *goquery.Selection) {
+		// listed counts every product link, found only the new ones; a
+		// page of products already seen under another seed is not the end
+		listed, found := 0, 0
+		doc.Find("a[href$='.html']").EachWithBreak(func(_ int, s *goquery.Selection) bool {
 			href, exists := s.Attr("href")
 			if !exists || strings.Count(href, "/") != 2 || !strings.HasSuffix(href, ".html") {
-				return
+				return true
 			}
-
-			fullURL := "https://www.adidas.jp" + href
-			if productMap[fullURL] {
-				return
+			listed++
+
+			fullURL := resolveURL(base, href)
+			seedRef := model.ProductURLSeed{Seed: seed, CategoryPath: categoryPath}
+			if i, ok := productIndex[fullURL]; ok {
+				if !hasSeed(productList[i].Seeds, seed) {
+					productList[i].Seeds = append(productList[i].Seeds, seedRef)
+				}
+				return true
 			}
 
 			// ✅ Extract code from last segment of path
-			parts := strings.Split(href, "/")
-			code := strings.TrimSuffix(parts[len(parts)-1], ".html")
+			code := productCodeFromHref(href)
 
 			imgURL := ""
 			if img := s.Find("img"); img.Length() > 0 {
 				imgURL, _ = img.Attr("src")
 			}
 
+			now := time.Now()
+			productIndex[fullURL] = len(productList)
 			productList = append(productList, model.ProductURL{
-				Code:      code,
-				URL:       fullURL,
-				ImageURL:  imgURL,
-				ScrapedAt: time.Now(),
+				Code:         code,
+				URL:          fullURL,
+				ImageURL:     imgURL,
+				Seed:         seed,
+				CategoryPath: categoryPath,
+				ScrapedAt:    now,
+				LastListedAt: &now,
+				Seeds:        []model.ProductURLSeed{seedRef},
 			})
-			productMap[fullURL] = true
 			found++
+			added++
 
-			if len(productList) >= limit {
-				return
-			}
+			return added < quota
 		})
 
-		if found == 0 {
-			logger.Info("No more products found. Ending pagination.")
-			break
+		if listed == 0 {
+			logger.Infow("no more products found, ending pagination", "url", pageURL)
+			return productList, true, nil
 		}
+		logger.Debugw("listing page read", "url", pageURL, "listed", listed, "new", found)
 
 		start += step
 	}
 
-	return productList, nil
+	return productList, false, nil
+}
+
+// categoryPathFromURL turns a listing path such as "/メンズ-シューズ" into
+// "メンズ/シューズ".
+func categoryPathFromURL(u *url.URL) string {
+	path := strings.Trim(u.Path, "/")
+	path = strings.TrimSuffix(path, ".html")
+	return strings.ReplaceAll(path, "-", "/")
+}
+
+func hasSeed(seeds []model.ProductURLSeed, seed string) bool {
+	for _, s := range seeds {
+		if s.Seed == seed {
+			return true
+		}
+	}
+	return false
 }
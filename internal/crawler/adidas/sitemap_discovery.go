@@ -0,0 +1,98 @@
+package adidas
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/config"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/crawler/sitemap"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/proxy"
+	"go.uber.org/zap"
+)
+
+// collectSitemapURLs discovers product detail URLs from the sitemaps listed
+// in robots.txt (or configured explicitly) and returns up to limit of them,
+// in the same shape collectProductURLs produces. complete lists the roots
+// that were walked in full, without an unreadable child sitemap and without
+// stopping at the limit.
+func collectSitemapURLs(ctx context.Context, cfg config.SitemapConfig, limit int, profile config.BrowserProfile, proxies *proxy.Pool, logger *zap.SugaredLogger) (productList []model.ProductURL, complete []string, err error) {
+	pattern, err := regexp.Compile(cfg.PathPattern)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid sitemap path pattern: %w", err)
+	}
+
+	skipped := false
+	client := sitemap.NewClient(profile.UserAgent)
+	client.OnSkip = func(sitemapURL string, err error) {
+		skipped = true
+		logger.Warnw("skipping unreadable sitemap", "stage", "discover", "sitemap", sitemapURL, "error", err)
+	}
+	client.HTTP, _, err = proxies.HTTPClient("sitemap", client.HTTP.Timeout)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	roots := cfg.URLs
+	if len(roots) == 0 {
+		roots, err = client.SitemapsFromRobots(ctx, cfg.RobotsURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read robots.txt: %w", err)
+		}
+	}
+	if len(roots) == 0 {
+		return nil, nil, fmt.Errorf("no sitemaps found")
+	}
+
+	// a product URL passes the locale filter on its own or by being listed
+	// in a sitemap that does, which covers flat urlsets mixing locales as
+	// well as locale-specific sitemaps of locale-less URLs
+	follow := func(loc string) bool {
+		return cfg.Locale == "" || sitemap.MatchesLocale(loc, cfg.Locale)
+	}
+
+	seen := map[string]bool{}
+
+	for _, root := range roots {
+		skipped = false
+		err := client.Walk(ctx, root, follow, func(e sitemap.Entry) bool {
+			u, err := url.Parse(e.Loc)
+			if err != nil || !pattern.MatchString(u.Path) || seen[e.Loc] || !(follow(e.Sitemap) || follow(e.Loc)) {
+				return true
+			}
+			seen[e.Loc] = true
+
+			now := time.Now()
+			p := model.ProductURL{
+				Code:         productCodeFromHref(u.Path),
+				URL:          e.Loc,
+				Seed:         root,
+				ScrapedAt:    now,
+				LastListedAt: &now,
+				Seeds:        []model.ProductURLSeed{{Seed: root}},
+			}
+			if !e.LastMod.IsZero() {
+				lastMod := e.LastMod
+				p.LastModified = &lastMod
+			}
+			productList = append(productList, p)
+			return len(productList) < limit
+		})
+		if err != nil {
+			logger.Errorw("failed to read sitemap", "stage", "discover", "seed", root, "error", err)
+			continue
+		}
+		if len(productList) >= limit {
+			break
+		}
+		if !skipped {
+			complete = append(complete, root)
+		}
+	}
+
+	logger.Infow("discovered product URLs from sitemaps", "stage", "discover", "discovered", len(productList), "sitemaps", len(roots))
+	return productList, complete, nil
+}
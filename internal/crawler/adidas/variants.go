@@ -0,0 +1,78 @@
+package adidas
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+)
+
+// productCodeFromHref returns the product code from a detail link such as
+// "/サンバ-og-samba-og/JI2734.html?pr=..." ("JI2734"), or "" if the link
+// does not look like a product detail page.
+func productCodeFromHref(href string) string {
+	if i := strings.IndexAny(href, "?#"); i >= 0 {
+		href = href[:i]
+	}
+	if !strings.HasSuffix(href, ".html") {
+		return ""
+	}
+	parts := strings.Split(href, "/")
+	return strings.TrimSuffix(parts[len(parts)-1], ".html")
+}
+
+// resolveURL resolves href against the page it was found on, so links keep
+// the host (and locale) of the seed they came from.
+func resolveURL(base *url.URL, href string) string {
+	ref, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// groupKeyFor returns the key of the parent model of a colorway, taken
+// from its JSON-LD productGroupID. Every colorway of a model carries the
+// same productGroupID, including the one whose sku it is; products without
+// one are left ungrouped rather than guessed from their names.
+func groupKeyFor(productGroupID string) string {
+	if productGroupID == "" {
+		return ""
+	}
+	return "pg:" + strings.ToUpper(productGroupID)
+}
+
+// extractColorVariants collects the sibling colorways linked from the color
+// selector on a detail page, skipping the page's own product code.
+func extractColorVariants(doc *goquery.Document, pageURL, code string) []model.ProductURL {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+	seen := map[string]bool{code: true}
+	var variants []model.ProductURL
+
+	doc.Find(`[data-auto-id="color-chooser"] a, [data-testid="color-chooser"] a, [data-auto-id="color-variation"] a`).Each(func(_ int, s *goquery.Selection) {
+		href, ok := s.Attr("href")
+		if !ok {
+			return
+		}
+		variantCode := productCodeFromHref(href)
+		if variantCode == "" || seen[variantCode] {
+			return
+		}
+		seen[variantCode] = true
+
+		imgURL, _ := s.Find("img").First().Attr("src")
+		variants = append(variants, model.ProductURL{
+			Code:      variantCode,
+			URL:       resolveURL(base, strings.SplitN(href, "?", 2)[0]),
+			ImageURL:  imgURL,
+			ScrapedAt: time.Now(),
+		})
+	})
+
+	return variants
+}
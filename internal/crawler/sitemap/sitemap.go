@@ -0,0 +1,221 @@
+package sitemap
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxDepth bounds how many sitemap indexes deep Walk will follow.
+const maxDepth = 4
+
+// Entry is a single <url> of a sitemap.
+type Entry struct {
+	Loc     string
+	LastMod time.Time // zero when the sitemap omits <lastmod>
+	Sitemap string    // the sitemap listing it
+}
+
+// Client fetches robots.txt and sitemap files over plain HTTP.
+type Client struct {
+	HTTP      *http.Client
+	UserAgent string
+	// OnSkip, if set, is called for every child sitemap of an index that
+	// Walk failed to read and skipped.
+	OnSkip func(sitemapURL string, err error)
+}
+
+// NewClient returns a Client with a bounded request timeout.
+func NewClient(userAgent string) *Client {
+	return &Client{
+		HTTP:      &http.Client{Timeout: 30 * time.Second},
+		UserAgent: userAgent,
+	}
+}
+
+// SitemapsFromRobots returns the "Sitemap:" entries listed in robots.txt.
+func (c *Client) SitemapsFromRobots(ctx context.Context, robotsURL string) ([]string, error) {
+	body, err := c.get(ctx, robotsURL)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var sitemaps []string
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || !strings.EqualFold(strings.TrimSpace(key), "sitemap") {
+			continue
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			sitemaps = append(sitemaps, value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", robotsURL, err)
+	}
+	return sitemaps, nil
+}
+
+// Walk reads the sitemap at sitemapURL, descending into sitemap indexes.
+// follow decides whether a child sitemap of an index is read at all; visit is
+// called for every <url> entry and stops the walk by returning false. A
+// child sitemap that fails to load is reported to OnSkip and skipped; only
+// a failure to read sitemapURL itself is returned.
+func (c *Client) Walk(ctx context.Context, sitemapURL string, follow func(loc string) bool, visit func(Entry) bool) error {
+	_, err := c.walk(ctx, sitemapURL, 0, map[string]bool{}, follow, visit)
+	return err
+}
+
+func (c *Client) walk(ctx context.Context, sitemapURL string, depth int, seen map[string]bool, follow func(string) bool, visit func(Entry) bool) (bool, error) {
+	if depth > maxDepth || seen[sitemapURL] {
+		return true, nil
+	}
+	seen[sitemapURL] = true
+
+	doc, err := c.fetch(ctx, sitemapURL)
+	if err != nil {
+		return false, err
+	}
+
+	for _, s := range doc.Sitemaps {
+		loc := strings.TrimSpace(s.Loc)
+		if loc == "" || (follow != nil && !follow(loc)) {
+			continue
+		}
+		more, err := c.walk(ctx, loc, depth+1, seen, follow, visit)
+		if err != nil {
+			if ctx.Err() != nil {
+				return false, ctx.Err()
+			}
+			if c.OnSkip != nil {
+				c.OnSkip(loc, err)
+			}
+			continue
+		}
+		if !more {
+			return false, nil
+		}
+	}
+
+	for _, u := range doc.URLs {
+		loc := strings.TrimSpace(u.Loc)
+		if loc == "" {
+			continue
+		}
+		if !visit(Entry{Loc: loc, LastMod: parseLastMod(u.LastMod), Sitemap: sitemapURL}) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+type document struct {
+	XMLName  xml.Name
+	URLs     []location `xml:"url"`
+	Sitemaps []location `xml:"sitemap"`
+}
+
+type location struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// fetch downloads and decodes a <urlset> or <sitemapindex>, transparently
+// un-gzipping "*.xml.gz" files.
+func (c *Client) fetch(ctx context.Context, sitemapURL string) (*document, error) {
+	body, err := c.get(ctx, sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	r := bufio.NewReader(body)
+	var src io.Reader = r
+	if magic, _ := r.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("gunzip %s: %w", sitemapURL, err)
+		}
+		defer gz.Close()
+		src = gz
+	}
+
+	var doc document
+	if err := xml.NewDecoder(src).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", sitemapURL, err)
+	}
+	return &doc, nil
+}
+
+func (c *Client) get(ctx context.Context, rawURL string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: unexpected status %s", rawURL, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+var lastModLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+func parseLastMod(raw string) time.Time {
+	raw = strings.TrimSpace(raw)
+	for _, layout := range lastModLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// MatchesLocale reports whether the sitemap or page URL loc is for locale,
+// e.g. "ja": one of its path segments is the locale, optionally with a
+// region ("/ja/", "/ja-JP/"), or its file name ends in "-ja" or "_ja"
+// before the extension ("sitemap-products-ja.xml.gz"). Any other
+// occurrence, such as "ninja" or "pajama", does not count. Matching ignores
+// case.
+func MatchesLocale(loc, locale string) bool {
+	locale = strings.ToLower(locale)
+	u, err := url.Parse(loc)
+	if err != nil || locale == "" {
+		return false
+	}
+	segments := strings.Split(strings.ToLower(u.Path), "/")
+	for _, seg := range segments {
+		if seg == locale {
+			return true
+		}
+		if rest, ok := strings.CutPrefix(seg, locale); ok && len(rest) == 3 && (rest[0] == '-' || rest[0] == '_') {
+			return true
+		}
+	}
+	name, _, _ := strings.Cut(segments[len(segments)-1], ".")
+	return strings.HasSuffix(name, "-"+locale) || strings.HasSuffix(name, "_"+locale)
+}
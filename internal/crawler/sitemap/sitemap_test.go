@@ -0,0 +1,204 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWalkSkipsFailedChildSitemap(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.xml":
+			fmt.Fprintf(w, `<sitemapindex>
+<sitemap><loc>%[1]s/broken.xml</loc></sitemap>
+<sitemap><loc>%[1]s/products.xml</loc></sitemap>
+</sitemapindex>`, srv.URL)
+		case "/products.xml":
+			fmt.Fprint(w, `<urlset>
+<url><loc>https://www.adidas.jp/samba/JI2734.html</loc><lastmod>2024-05-01</lastmod></url>
+</urlset>`)
+		default:
+			http.Error(w, "gone", http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient("test")
+	var skipped []string
+	c.OnSkip = func(sitemapURL string, err error) { skipped = append(skipped, sitemapURL) }
+
+	var locs []string
+	err := c.Walk(context.Background(), srv.URL+"/index.xml", nil, func(e Entry) bool {
+		locs = append(locs, e.Loc)
+		if e.Sitemap != srv.URL+"/products.xml" {
+			t.Errorf("entry %s listed in %s, want products.xml", e.Loc, e.Sitemap)
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	if want := []string{"https://www.adidas.jp/samba/JI2734.html"}; !reflect.DeepEqual(locs, want) {
+		t.Errorf("visited %v, want %v", locs, want)
+	}
+	if want := []string{srv.URL + "/broken.xml"}; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("skipped %v, want %v", skipped, want)
+	}
+}
+
+func TestWalkReturnsRootError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	err := NewClient("test").Walk(context.Background(), srv.URL+"/sitemap.xml", nil, func(Entry) bool { return true })
+	if err == nil {
+		t.Fatal("Walk of a missing root sitemap succeeded")
+	}
+}
+
+func TestSitemapsFromRobots(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `User-agent: *
+Disallow: /checkout
+# Sitemap: https://www.adidas.jp/commented.xml
+Sitemap: https://www.adidas.jp/sitemap-index.xml
+sitemap:   https://www.adidas.jp/sitemap-products-ja.xml.gz
+Sitemap:
+`)
+	}))
+	defer srv.Close()
+
+	got, err := NewClient("test").SitemapsFromRobots(context.Background(), srv.URL+"/robots.txt")
+	if err != nil {
+		t.Fatalf("SitemapsFromRobots: %v", err)
+	}
+	want := []string{"https://www.adidas.jp/sitemap-index.xml", "https://www.adidas.jp/sitemap-products-ja.xml.gz"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sitemaps = %v, want %v", got, want)
+	}
+}
+
+func TestWalkReadsGzipChildren(t *testing.T) {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	fmt.Fprint(w, `<urlset><url><loc>https://www.adidas.jp/samba/JI2734.html</loc></url></urlset>`)
+	w.Close()
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.xml":
+			fmt.Fprintf(w, `<sitemapindex><sitemap><loc>%s/products.xml.gz</loc></sitemap></sitemapindex>`, srv.URL)
+		case "/products.xml.gz":
+			// served as a plain file, not with Content-Encoding: gzip
+			w.Header().Set("Content-Type", "application/x-gzip")
+			w.Write(gz.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	var locs []string
+	err := NewClient("test").Walk(context.Background(), srv.URL+"/index.xml", nil, func(e Entry) bool {
+		locs = append(locs, e.Loc)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	if want := []string{"https://www.adidas.jp/samba/JI2734.html"}; !reflect.DeepEqual(locs, want) {
+		t.Errorf("visited %v, want %v", locs, want)
+	}
+}
+
+func TestParseLastMod(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	tests := []struct {
+		raw  string
+		want time.Time
+	}{
+		{"2024-05-01T10:30:00+09:00", time.Date(2024, 5, 1, 10, 30, 0, 0, jst)},
+		{"2024-05-01T01:30:00Z", time.Date(2024, 5, 1, 1, 30, 0, 0, time.UTC)},
+		{"2024-05-01T10:30+09:00", time.Date(2024, 5, 1, 10, 30, 0, 0, jst)},
+		{"2024-05-01T10:30:00", time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)},
+		{" 2024-05-01\n", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"", time.Time{}},
+		{"May 1, 2024", time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := parseLastMod(tt.raw); !got.Equal(tt.want) {
+			t.Errorf("parseLastMod(%q) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestMatchesLocale(t *testing.T) {
+	tests := []struct {
+		loc  string
+		want bool
+	}{
+		{"https://www.adidas.com/ja/samba/JI2734.html", true},
+		{"https://www.adidas.com/JA-JP/sitemap.xml", true},
+		{"https://www.adidas.com/ja_jp/sitemap.xml", true},
+		{"https://www.adidas.jp/sitemap-products-ja.xml.gz", true},
+		{"https://www.adidas.jp/sitemap_JA.xml", true},
+		{"https://www.adidas.jp/sitemap-products-en.xml.gz", false},
+		{"https://www.adidas.jp/ninja-shoes/HQ1234.html", false},
+		{"https://www.adidas.jp/pajama-pants/HQ1234.html", false},
+		{"https://www.adidas.jp/ja-morant-1/IE1234.html", false},
+		{"https://www.adidas.jp/sitemap-japan.xml", false},
+		{"https://ja.adidas.com/sitemap.xml", false},
+	}
+	for _, tt := range tests {
+		if got := MatchesLocale(tt.loc, "ja"); got != tt.want {
+			t.Errorf("MatchesLocale(%q, ja) = %v, want %v", tt.loc, got, tt.want)
+		}
+	}
+}
+
+func TestWalkFollowsLocaleSitemaps(t *testing.T) {
+	var srv *httptest.Server
+	var fetched []string
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched = append(fetched, r.URL.Path)
+		switch r.URL.Path {
+		case "/index.xml":
+			fmt.Fprintf(w, `<sitemapindex>
+<sitemap><loc>%[1]s/sitemap-products-ja.xml</loc></sitemap>
+<sitemap><loc>%[1]s/sitemap-products-en.xml</loc></sitemap>
+<sitemap><loc>%[1]s/sitemap-ninja.xml</loc></sitemap>
+</sitemapindex>`, srv.URL)
+		case "/sitemap-products-ja.xml":
+			fmt.Fprint(w, `<urlset><url><loc>https://www.adidas.jp/samba/JI2734.html</loc></url></urlset>`)
+		default:
+			t.Errorf("fetched %s, which is not a ja sitemap", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	follow := func(loc string) bool { return MatchesLocale(loc, "ja") }
+	var locs []string
+	err := NewClient("test").Walk(context.Background(), srv.URL+"/index.xml", follow, func(e Entry) bool {
+		locs = append(locs, e.Loc)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	if want := []string{"https://www.adidas.jp/samba/JI2734.html"}; !reflect.DeepEqual(locs, want) {
+		t.Errorf("visited %v, want %v", locs, want)
+	}
+	if want := []string{"/index.xml", "/sitemap-products-ja.xml"}; !reflect.DeepEqual(fetched, want) {
+		t.Errorf("fetched %v, want %v", fetched, want)
+	}
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/config"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// DSN builds the Postgres connection string from the configuration.
+func DSN(cfg *config.Config) string {
+	return fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s port=%d sslmode=%s",
+		cfg.DBHost,
+		cfg.DBUser,
+		cfg.DBPassword,
+		cfg.DBName,
+		cfg.DBPort,
+		cfg.DBSSLMode,
+	)
+}
+
+// Open connects to the configured database with GORM. An SQLite database is
+// created if needed and migrated to the latest schema.
+func Open(cfg *config.Config) (*gorm.DB, error) {
+	if cfg.DBDriver == "sqlite" {
+		return openSQLite(cfg.DBPath)
+	}
+	return gorm.Open(postgres.Open(DSN(cfg)), &gorm.Config{})
+}
+
+// openSQLite opens the database file at path with foreign keys enforced, as
+// Postgres does, and a single connection: SQLite allows one writer at a
+// time, and crawler workers otherwise fail with "database is locked".
+func openSQLite(path string) (*gorm.DB, error) {
+	dsn := path + "?_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := migrateSQLite(db); err != nil {
+		return nil, fmt.Errorf("migrate %s: %w", path, err)
+	}
+	return db, nil
+}
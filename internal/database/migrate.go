@@ -0,0 +1,64 @@
+package database
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/migrations"
+	"gorm.io/gorm"
+)
+
+// schemaMigration records an applied migration file.
+type schemaMigration struct {
+	Version   string `gorm:"primaryKey"`
+	AppliedAt time.Time
+}
+
+// migrateSQLite applies the embedded SQLite migrations that have not been
+// applied yet, in file name order, each in its own transaction.
+func migrateSQLite(db *gorm.DB) error {
+	if err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations
+		(version TEXT PRIMARY KEY, applied_at TIMESTAMP NOT NULL)`).Error; err != nil {
+		return err
+	}
+
+	var applied []string
+	if err := db.Model(&schemaMigration{}).Pluck("version", &applied).Error; err != nil {
+		return err
+	}
+	done := make(map[string]bool, len(applied))
+	for _, v := range applied {
+		done[v] = true
+	}
+
+	files, err := fs.Glob(migrations.SQLite, "sqlite/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		version := strings.TrimSuffix(path.Base(file), ".sql")
+		if done[version] {
+			continue
+		}
+		script, err := migrations.SQLite.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(string(script)).Error; err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{Version: version, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", version, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"github.com/jakib01/web-crawiling-golang-colly/internal/config"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/repository/postgres"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/repository/sqlite"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/service"
+	"gorm.io/gorm"
+)
+
+// Repositories are the storage implementations of the service
+// repositories for the configured driver.
+type Repositories struct {
+	Products    service.ProductRepository
+	ProductURLs service.ProductURLRepository
+	CrawlRuns   service.CrawlRunRepository
+	Reviews     service.ReviewRepository
+}
+
+// NewRepositories returns the repositories for cfg.DBDriver on db, which
+// Open returned.
+func NewRepositories(cfg *config.Config, db *gorm.DB) Repositories {
+	if cfg.DBDriver == "sqlite" {
+		return Repositories{
+			Products:    sqlite.NewProducts(db),
+			ProductURLs: sqlite.NewProductURLs(db),
+			CrawlRuns:   sqlite.NewCrawlRuns(db),
+			Reviews:     sqlite.NewReviews(db),
+		}
+	}
+	return Repositories{
+		Products:    postgres.NewProducts(db),
+		ProductURLs: postgres.NewProductURLs(db),
+		CrawlRuns:   postgres.NewCrawlRuns(db),
+		Reviews:     postgres.NewReviews(db),
+	}
+}
@@ -0,0 +1,144 @@
+package fingerprint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+)
+
+// Product returns a stable fingerprint of the parsed content of p. Database
+// IDs, timestamps, discovered variants and image CDN transformations are
+// ignored, and collections are sorted, so that re-parsing an unchanged page
+// yields the same value.
+func Product(p model.Product) string {
+	n := normalized{
+		ProductCode:                p.ProductCode,
+		Name:                       clean(p.Name),
+		Category:                   clean(p.Category),
+		PriceYen:                   p.PriceYen,
+		SenseOfSize:                clean(p.SenseOfSize),
+		DetailsURL:                 stripQuery(p.DetailsURL),
+		TotalReviews:               p.TotalReviews,
+		OverallRating:              p.OverallRating,
+		TitleDescription:           clean(p.TitleDescription),
+		GeneralDescription:         clean(p.GeneralDescription),
+		ItemGeneralDescription:     clean(p.ItemGeneralDescription),
+		SpecialFunctionDescription: clean(p.SpecialFunctionDescription),
+		Color:                      clean(p.Color),
+	}
+
+	images := map[string]bool{}
+	for _, img := range p.Images {
+		images[ImageKey(img.URL)] = true
+	}
+	for u := range images {
+		n.Images = append(n.Images, u)
+	}
+	sort.Strings(n.Images)
+
+	for _, s := range p.Sizes {
+		n.Sizes = append(n.Sizes, strings.Join([]string{
+			clean(s.SizeLabel), formatFloat(s.Availability), formatFloat(s.ChestCM), formatFloat(s.BackLengthCM),
+		}, "|"))
+	}
+	sort.Strings(n.Sizes)
+
+	for _, r := range p.Reviews {
+		n.Reviews = append(n.Reviews, strings.Join([]string{
+			r.ReviewDate.Format("2006-01-02"), formatFloat(r.Rating), clean(r.Title), clean(r.Body),
+		}, "|"))
+	}
+	sort.Strings(n.Reviews)
+
+	for _, a := range p.AspectRatings {
+		n.AspectRatings = append(n.AspectRatings, clean(a.Aspect)+"|"+formatFloat(a.Rating))
+	}
+	sort.Strings(n.AspectRatings)
+
+	for _, c := range p.Coordinated {
+		n.Coordinated = append(n.Coordinated, strings.Join([]string{
+			c.ProductNumber, clean(c.Name), formatFloat(c.PriceYen), stripQuery(c.ProductPageURL),
+		}, "|"))
+	}
+	sort.Strings(n.Coordinated)
+
+	raw, _ := json.Marshal(n)
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// ImageKey reduces an adidas CDN image URL to the asset it points at, e.g.
+// ".../images/w_600,f_auto,q_auto/54c6.../Samba_OG.jpg?sh=1" becomes
+// ".../images/54c6.../Samba_OG.jpg".
+func ImageKey(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return raw
+	}
+	u.RawQuery, u.Fragment = "", ""
+
+	segments := strings.Split(u.Path, "/")
+	kept := segments[:0]
+	for i, seg := range segments {
+		if i > 0 && segments[i-1] == "images" && isTransformation(seg) {
+			continue
+		}
+		kept = append(kept, seg)
+	}
+	u.Path = strings.Join(kept, "/")
+	u.RawPath = ""
+	return u.String()
+}
+
+// isTransformation reports whether a path segment is a Cloudinary-style
+// transformation list such as "w_600,f_auto,q_auto".
+func isTransformation(seg string) bool {
+	for _, part := range strings.Split(seg, ",") {
+		if len(part) < 3 || part[1] != '_' {
+			return false
+		}
+	}
+	return seg != ""
+}
+
+type normalized struct {
+	ProductCode                string
+	Name                       string
+	Category                   string
+	PriceYen                   float64
+	SenseOfSize                string
+	DetailsURL                 string
+	TotalReviews               int
+	OverallRating              float64
+	TitleDescription           string
+	GeneralDescription         string
+	ItemGeneralDescription     string
+	SpecialFunctionDescription string
+	Color                      string
+	Images                     []string
+	Sizes                      []string
+	Reviews                    []string
+	AspectRatings              []string
+	Coordinated                []string
+}
+
+func clean(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func stripQuery(raw string) string {
+	if i := strings.IndexAny(raw, "?#"); i >= 0 {
+		return raw[:i]
+	}
+	return raw
+}
+
+func formatFloat(f float64) string {
+	b, _ := json.Marshal(f)
+	return string(b)
+}
@@ -0,0 +1,144 @@
+package fingerprint
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+)
+
+const cdn = "https://assets.adidas.com/images/"
+
+func product() model.Product {
+	return model.Product{
+		ProductCode: "JI2734",
+		Name:        "サンバ OG / Samba OG",
+		Category:    "オリジナルス シューズ",
+		PriceYen:    14300,
+		DetailsURL:  "https://www.adidas.jp/samba-og/JI2734.html",
+		Color:       "コアブラック",
+		Images: []model.ProductImage{
+			{URL: cdn + "w_600,f_auto,q_auto/54c6/Samba_OG_Black_JI2734_01.jpg", IsMain: true},
+			{URL: cdn + "w_600,f_auto,q_auto/8d1e/Samba_OG_Black_JI2734_02.jpg"},
+		},
+		Sizes: []model.ProductSize{
+			{SizeLabel: "26.0cm", Availability: 3},
+			{SizeLabel: "27.0cm", Availability: 0},
+		},
+		Reviews: []model.Review{
+			{ReviewDate: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Rating: 5, Title: "最高", Body: "履きやすい"},
+		},
+		AspectRatings: []model.ReviewAspectRating{{Aspect: "快適さ", Rating: 90}},
+		Coordinated: []model.CoordinatedItem{
+			{ProductNumber: "IA4845", Name: "トレフォイル Tシャツ", PriceYen: 5489, ProductPageURL: "https://www.adidas.jp/IA4845.html"},
+		},
+	}
+}
+
+func TestProductIgnoresVolatileFields(t *testing.T) {
+	want := Product(product())
+	now := time.Now()
+	groupID := uint(7)
+
+	tests := []struct {
+		name   string
+		modify func(p *model.Product)
+	}{
+		{"ids and timestamps", func(p *model.Product) {
+			p.ID, p.ProductGroupID, p.CreatedAt, p.UpdatedAt, p.LastSeenAt = 42, &groupID, now, now, &now
+			p.Sizes[0].ID, p.Images[0].ProductID = 3, 42
+		}},
+		{"stored state", func(p *model.Product) {
+			p.ContentHash, p.Status, p.StatusChangedAt = "old", "active", &now
+		}},
+		{"variants", func(p *model.Product) {
+			p.Variants = []model.ProductURL{{Code: "B75806"}}
+		}},
+		{"image query string", func(p *model.Product) {
+			p.Images[0].URL += "?sh=1&v=2"
+		}},
+		{"image transformations", func(p *model.Product) {
+			p.Images[1].URL = cdn + "h_840,f_webp/8d1e/Samba_OG_Black_JI2734_02.jpg"
+		}},
+		{"main image flag", func(p *model.Product) {
+			p.Images[0].IsMain, p.Images[1].IsMain = false, true
+		}},
+		{"duplicate image", func(p *model.Product) {
+			p.Images = append(p.Images, model.ProductImage{URL: cdn + "w_1200/54c6/Samba_OG_Black_JI2734_01.jpg"})
+		}},
+		{"collection order", func(p *model.Product) {
+			p.Images[0], p.Images[1] = p.Images[1], p.Images[0]
+			p.Sizes[0], p.Sizes[1] = p.Sizes[1], p.Sizes[0]
+		}},
+		{"whitespace", func(p *model.Product) {
+			p.Name = "  サンバ OG /\n  Samba OG "
+		}},
+		{"details URL query string", func(p *model.Product) {
+			p.DetailsURL += "?forceSelSize=1#reviews"
+		}},
+		{"coordinated image", func(p *model.Product) {
+			p.Coordinated[0].ImageURL = cdn + "IA4845.jpg"
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := product()
+			tt.modify(&p)
+			if got := Product(p); got != want {
+				t.Errorf("Product changed to %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestProductTracksContent(t *testing.T) {
+	base := Product(product())
+
+	tests := []struct {
+		name   string
+		modify func(p *model.Product)
+	}{
+		{"name", func(p *model.Product) { p.Name = "サンバ OG / Samba OG W" }},
+		{"price", func(p *model.Product) { p.PriceYen = 12870 }},
+		{"color", func(p *model.Product) { p.Color = "クラウドホワイト" }},
+		{"description", func(p *model.Product) { p.GeneralDescription = "1950年代のサッカーシューズ" }},
+		{"rating", func(p *model.Product) { p.OverallRating = 4.8 }},
+		{"review count", func(p *model.Product) { p.TotalReviews = 12 }},
+		{"size stock", func(p *model.Product) { p.Sizes[1].Availability = 1 }},
+		{"size added", func(p *model.Product) { p.Sizes = append(p.Sizes, model.ProductSize{SizeLabel: "28.0cm"}) }},
+		{"image asset", func(p *model.Product) {
+			p.Images[1].URL = cdn + "w_600,f_auto,q_auto/9f3a/Samba_OG_Black_JI2734_03.jpg"
+		}},
+		{"review", func(p *model.Product) { p.Reviews[0].Body = "少し小さめ" }},
+		{"aspect rating", func(p *model.Product) { p.AspectRatings[0].Rating = 85 }},
+		{"coordinated price", func(p *model.Product) { p.Coordinated[0].PriceYen = 4389 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := product()
+			tt.modify(&p)
+			if Product(p) == base {
+				t.Error("Product did not change")
+			}
+		})
+	}
+}
+
+func TestImageKey(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{cdn + "w_600,f_auto,q_auto/54c6/Samba_OG.jpg?sh=1", cdn + "54c6/Samba_OG.jpg"},
+		{cdn + "h_840,f_webp/54c6/Samba_OG.jpg", cdn + "54c6/Samba_OG.jpg"},
+		{" " + cdn + "54c6/Samba_OG.jpg#zoom ", cdn + "54c6/Samba_OG.jpg"},
+		// only the segment after /images/ can be a transformation
+		{"https://www.adidas.jp/static/w_600/Samba_OG.jpg", "https://www.adidas.jp/static/w_600/Samba_OG.jpg"},
+		{cdn + "54c6/Samba_OG_Black.jpg", cdn + "54c6/Samba_OG_Black.jpg"},
+	}
+	for _, tt := range tests {
+		if got := ImageKey(tt.raw); got != tt.want {
+			t.Errorf("ImageKey(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
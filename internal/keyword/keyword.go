@@ -0,0 +1,150 @@
+package keyword
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxLen matches keywords.kw VARCHAR(100).
+const maxLen = 100
+
+// script is the character class a token is made of.
+type script int
+
+const (
+	scriptNone script = iota
+	scriptLatin
+	scriptKatakana
+	scriptKanji
+	scriptHiragana
+)
+
+// stopwords are dropped after normalization. Hiragana runs are dropped
+// wholesale (particles and inflections), so only other scripts appear here.
+var stopwords = map[string]bool{
+	// English
+	"a": true, "an": true, "and": true, "the": true, "of": true, "for": true,
+	"with": true, "in": true, "on": true, "to": true, "by": true, "or": true,
+	"is": true, "it": true, "at": true, "as": true, "from": true, "this": true,
+	"your": true, "you": true, "our": true, "be": true, "are": true,
+	// site and unit noise
+	"adidas": true, "アディダス": true, "cm": true, "mm": true, "kg": true,
+	"html": true, "商品": true, "サイズ": true, "着用": true, "使用": true,
+	"場合": true, "素材": true, "当社": true, "予定": true, "方": true,
+	"的": true, "性": true, "用": true, "等": true, "他": true,
+}
+
+// singleKanji are the one-character kanji worth keeping as keywords; other
+// single kanji are usually verb or adjective stems cut off by hiragana.
+var singleKanji = map[string]bool{
+	"白": true, "黒": true, "赤": true, "青": true, "緑": true, "茶": true, "紺": true,
+	"黄": true, "灰": true, "金": true, "銀": true, "靴": true, "夏": true, "冬": true,
+}
+
+// Normalize folds full-width ASCII, half-width katakana and case, so that
+// "ＳＡＭＢＡ", "ｻﾝﾊﾞ" and "Samba" compare as "samba" and "サンバ".
+func Normalize(s string) string {
+	return strings.ToLower(norm.NFKC.String(s))
+}
+
+// Tokenize splits text into keywords. Without a dictionary, Japanese is
+// segmented on script boundaries: katakana runs ("スニーカー") and kanji
+// compounds ("通気性") become keywords, hiragana runs are treated as
+// particles and dropped. Latin words and model numbers are split on
+// anything that is not a letter or digit, so "サンバ OG / Samba OG" yields
+// "サンバ", "og" and "samba".
+func Tokenize(text string) []string {
+	var tokens []string
+	var cur []rune
+	curScript := scriptNone
+
+	flush := func() {
+		if tok, ok := accept(string(cur), curScript); ok {
+			tokens = append(tokens, tok)
+		}
+		cur, curScript = cur[:0], scriptNone
+	}
+	for _, r := range Normalize(text) {
+		s := classify(r)
+		// the prolonged sound mark belongs to the katakana run it follows
+		if r == 'ー' && curScript == scriptKatakana {
+			s = scriptKatakana
+		}
+		if s != curScript {
+			flush()
+		}
+		if s != scriptNone {
+			cur = append(cur, r)
+			curScript = s
+		}
+	}
+	flush()
+	return tokens
+}
+
+// Extract returns the distinct keywords of a product's name, descriptions,
+// category and the category paths it was listed under, in first-seen order.
+func Extract(p model.Product, categoryPaths []string) []string {
+	sources := []string{p.Name, p.Category, p.TitleDescription, p.GeneralDescription,
+		p.ItemGeneralDescription, p.SpecialFunctionDescription, p.Color}
+	sources = append(sources, categoryPaths...)
+
+	seen := map[string]bool{}
+	var keywords []string
+	for _, src := range sources {
+		for _, tok := range Tokenize(src) {
+			if !seen[tok] {
+				seen[tok] = true
+				keywords = append(keywords, tok)
+			}
+		}
+	}
+	return keywords
+}
+
+func classify(r rune) script {
+	switch {
+	case r >= 'a' && r <= 'z', unicode.IsDigit(r):
+		return scriptLatin
+	case unicode.Is(unicode.Katakana, r):
+		return scriptKatakana
+	case unicode.Is(unicode.Han, r), r == '々':
+		return scriptKanji
+	case unicode.Is(unicode.Hiragana, r):
+		return scriptHiragana
+	}
+	return scriptNone
+}
+
+// accept decides whether a run becomes a keyword.
+func accept(tok string, s script) (string, bool) {
+	n := utf8.RuneCountInString(tok)
+	switch {
+	case n == 0 || n > maxLen:
+		return "", false
+	case s == scriptHiragana:
+		return "", false
+	case s == scriptLatin && (n < 2 || isNumber(tok)):
+		return "", false
+	case s == scriptKatakana && n < 2:
+		return "", false
+	case s == scriptKanji && n < 2 && !singleKanji[tok]:
+		return "", false
+	case stopwords[tok]:
+		return "", false
+	}
+	return tok, true
+}
+
+func isNumber(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package keyword
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"katakana and ascii name", "サンバ OG / Samba OG", []string{"サンバ", "og", "samba", "og"}},
+		{"numbers dropped", "ウルトラブースト 5 / ULTRABOOST 5 ランニングシューズ", []string{"ウルトラブースト", "ultraboost", "ランニングシューズ"}},
+		{"model numbers", "品番: JI2734, IG1025", []string{"品番", "ji2734", "ig1025"}},
+		{"kanji, hiragana and katakana", "通気性に優れた白のスニーカー", []string{"通気性", "白", "スニーカー"}},
+		{"iteration mark", "色々な場面で", []string{"色々", "場面"}},
+		{"full and half width", "ＳＡＭＢＡ ｻﾝﾊﾞ ｽﾆｰｶｰ", []string{"samba", "サンバ", "スニーカー"}},
+		{"single letter", "Tシャツ", []string{"シャツ"}},
+		{"hyphenated", "GORE-TEX", []string{"gore", "tex"}},
+		{"english stop words", "The Originals for you by adidas", []string{"originals"}},
+		{"japanese stop words", "アディダス 商品 サイズ 素材 着用サイズ", nil},
+		{"units and kanji stems", "着丈 70 cm, 重さ 300g", []string{"着丈", "300g"}},
+		{"empty", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractDeduplicates(t *testing.T) {
+	p := model.Product{
+		Name:     "サンバ OG / Samba OG",
+		Category: "オリジナルス シューズ",
+		Color:    "コアブラック / クラウドホワイト",
+	}
+	got := Extract(p, []string{"メンズ シューズ"})
+	want := []string{"サンバ", "og", "samba", "オリジナルス", "シューズ", "コアブラック", "クラウドホワイト", "メンズ"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,40 @@
+package lifecycle
+
+// Product lifecycle statuses stored in products.status.
+const (
+	StatusNew        = "new"
+	StatusActive     = "active"
+	StatusOutOfStock = "out_of_stock"
+	StatusDelisted   = "delisted"
+	StatusRelisted   = "relisted"
+)
+
+// Observation is what a crawl learned about a product.
+type Observation struct {
+	// Fetched is true when the detail page was fetched and parsed.
+	Fetched bool
+	// InStock is true when at least one size was available.
+	InStock bool
+	// Gone is true when the product vanished from its listings and the
+	// detail URL answered 404/410 or redirected away from the product.
+	Gone bool
+}
+
+// Next returns the status a product moves to from current given obs. An
+// empty current status means the product has never been stored before.
+func Next(current string, obs Observation) string {
+	switch {
+	case obs.Gone:
+		return StatusDelisted
+	case !obs.Fetched:
+		return current
+	case current == "":
+		return StatusNew
+	case current == StatusDelisted:
+		return StatusRelisted
+	case !obs.InStock:
+		return StatusOutOfStock
+	default:
+		return StatusActive
+	}
+}
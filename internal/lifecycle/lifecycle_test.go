@@ -0,0 +1,43 @@
+package lifecycle
+
+import "testing"
+
+func TestNext(t *testing.T) {
+	inStock := Observation{Fetched: true, InStock: true}
+	soldOut := Observation{Fetched: true}
+	gone := Observation{Gone: true}
+
+	tests := []struct {
+		name    string
+		current string
+		obs     Observation
+		want    string
+	}{
+		{"first seen", "", inStock, StatusNew},
+		{"first seen sold out", "", soldOut, StatusNew},
+		{"new in stock", StatusNew, inStock, StatusActive},
+		{"new sold out", StatusNew, soldOut, StatusOutOfStock},
+		{"active in stock", StatusActive, inStock, StatusActive},
+		{"active sold out", StatusActive, soldOut, StatusOutOfStock},
+		{"restocked", StatusOutOfStock, inStock, StatusActive},
+		{"still sold out", StatusOutOfStock, soldOut, StatusOutOfStock},
+		{"active gone", StatusActive, gone, StatusDelisted},
+		{"sold out gone", StatusOutOfStock, gone, StatusDelisted},
+		{"relisted", StatusDelisted, inStock, StatusRelisted},
+		{"relisted sold out", StatusDelisted, soldOut, StatusRelisted},
+		{"still gone", StatusDelisted, gone, StatusDelisted},
+		{"relisted in stock", StatusRelisted, inStock, StatusActive},
+		{"relisted then sold out", StatusRelisted, soldOut, StatusOutOfStock},
+		{"relisted gone", StatusRelisted, gone, StatusDelisted},
+		{"not fetched", StatusActive, Observation{}, StatusActive},
+		{"delisted not fetched", StatusDelisted, Observation{}, StatusDelisted},
+		{"never stored not fetched", "", Observation{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Next(tt.current, tt.obs); got != tt.want {
+				t.Errorf("Next(%q, %+v) = %q, want %q", tt.current, tt.obs, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package logger
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type ctxKey struct{}
+
+// WithContext returns a copy of ctx carrying l. Child loggers with extra
+// fields (run_id, product_code, url, stage, ...) are attached this way so
+// that everything logged further down the call chain carries them.
+func WithContext(ctx context.Context, l *zap.SugaredLogger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the logger stored in ctx, or fallback if there is none.
+func FromContext(ctx context.Context, fallback *zap.SugaredLogger) *zap.SugaredLogger {
+	if l, ok := ctx.Value(ctxKey{}).(*zap.SugaredLogger); ok {
+		return l
+	}
+	return fallback
+}
+
+// With attaches the given key/value fields to the logger in ctx (or
+// fallback) and returns the new context together with the child logger.
+func With(ctx context.Context, fallback *zap.SugaredLogger, keysAndValues ...interface{}) (context.Context, *zap.SugaredLogger) {
+	l := FromContext(ctx, fallback).With(keysAndValues...)
+	return WithContext(ctx, l), l
+}
@@ -1,26 +1,79 @@
 package logger
 
 import (
+	"os"
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/config"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 // New returns a configured *zap.Logger.
-// level should be one of "debug", "info", "warn", "error", etc.
-func New(level string) (*zap.Logger, error) {
-	// choose dev vs prod config
-	cfg := zap.NewProductionConfig()
-	if level == "debug" {
-		cfg = zap.NewDevelopmentConfig()
+// cfg.Level should be one of "debug", "info", "warn", "error", etc. Output
+// always goes to stderr; with cfg.File set it is also written as JSON to a
+// size-rotated file. Repeated debug and info messages are sampled per
+// second, so noisy per-selector logs should use a constant message and put
+// the varying parts into fields; warnings and errors are always kept.
+func New(cfg config.LogConfig) (*zap.Logger, error) {
+	// choose dev vs prod encoding
+	encCfg := zap.NewProductionEncoderConfig()
+	encCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+	consoleEnc := zapcore.NewJSONEncoder(encCfg)
+	if cfg.Level == "debug" || cfg.Format == "console" {
+		devCfg := zap.NewDevelopmentEncoderConfig()
+		consoleEnc = zapcore.NewConsoleEncoder(devCfg)
 	}
 
 	// parse the level
 	var zapLevel zapcore.Level
-	if err := zapLevel.UnmarshalText([]byte(level)); err != nil {
+	if err := zapLevel.UnmarshalText([]byte(cfg.Level)); err != nil {
 		// fallback to info
 		zapLevel = zapcore.InfoLevel
 	}
-	cfg.Level = zap.NewAtomicLevelAt(zapLevel)
+	level := zap.NewAtomicLevelAt(zapLevel)
+
+	var rotator zapcore.WriteSyncer
+	if cfg.File != "" {
+		rotator = zapcore.AddSync(&lumberjack.Logger{
+			Filename:   cfg.File,
+			MaxSize:    cfg.MaxSizeMB,
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     cfg.MaxAgeDays,
+			Compress:   true,
+		})
+	}
+	newCore := func(enab zapcore.LevelEnabler) zapcore.Core {
+		cores := []zapcore.Core{
+			zapcore.NewCore(consoleEnc, zapcore.Lock(os.Stderr), enab),
+		}
+		if rotator != nil {
+			cores = append(cores, zapcore.NewCore(zapcore.NewJSONEncoder(encCfg), rotator, enab))
+		}
+		return zapcore.NewTee(cores...)
+	}
 
-	return cfg.Build()
+	core := sample(newCore, level, cfg.SampleInitial, cfg.SampleThereafter)
+
+	return zap.New(core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel)), nil
+}
+
+// sample builds the logging core with newCore so that debug and info
+// entries are sampled per second, initial and then every thereafter-th of
+// the same message, while warnings and errors always pass.
+func sample(newCore func(zapcore.LevelEnabler) zapcore.Core, level zapcore.LevelEnabler, initial, thereafter int) zapcore.Core {
+	if initial <= 0 {
+		return newCore(level)
+	}
+	noisy := zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+		return l < zapcore.WarnLevel && level.Enabled(l)
+	})
+	important := zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+		return l >= zapcore.WarnLevel && level.Enabled(l)
+	})
+	return zapcore.NewTee(
+		zapcore.NewSamplerWithOptions(newCore(noisy), time.Second, initial, thereafter),
+		newCore(important),
+	)
 }
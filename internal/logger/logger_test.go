@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSampleKeepsWarningsAndErrors(t *testing.T) {
+	var observed []*observer.ObservedLogs
+	newCore := func(enab zapcore.LevelEnabler) zapcore.Core {
+		core, logs := observer.New(enab)
+		observed = append(observed, logs)
+		return core
+	}
+	log := zap.New(sample(newCore, zapcore.DebugLevel, 2, 0))
+
+	for i := 0; i < 10; i++ {
+		log.Debug("selector matched nothing")
+		log.Info("page fetched")
+		log.Warn("failed to store detail")
+		log.Error("delisting check failed")
+	}
+
+	counts := map[zapcore.Level]int{}
+	for _, logs := range observed {
+		for _, e := range logs.All() {
+			counts[e.Level]++
+		}
+	}
+	want := map[zapcore.Level]int{
+		zapcore.DebugLevel: 2,
+		zapcore.InfoLevel:  2,
+		zapcore.WarnLevel:  10,
+		zapcore.ErrorLevel: 10,
+	}
+	for level, n := range want {
+		if counts[level] != n {
+			t.Errorf("%s entries = %d, want %d", level, counts[level], n)
+		}
+	}
+}
+
+func TestSampleDisabled(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	log := zap.New(sample(func(zapcore.LevelEnabler) zapcore.Core { return core }, zapcore.InfoLevel, 0, 0))
+	for i := 0; i < 5; i++ {
+		log.Info("page fetched")
+	}
+	if n := logs.Len(); n != 5 {
+		t.Errorf("logged %d entries, want 5", n)
+	}
+}
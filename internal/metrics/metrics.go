@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Page types used for the page_type label.
+const (
+	PageListing = "listing"
+	PageDetail  = "detail"
+)
+
+var (
+	PageFetchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "crawler_page_fetch_duration_seconds",
+		Help:    "Time to load a page in the headless browser.",
+		Buckets: []float64{0.5, 1, 2, 4, 8, 15, 30, 60},
+	}, []string{"site", "page_type"})
+
+	NavigationErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "crawler_navigation_errors_total",
+		Help: "Failed page loads by error type.",
+	}, []string{"site", "page_type", "error_type"})
+
+	ParseFields = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "crawler_parse_fields_total",
+		Help: "Parsed product fields by whether they were filled; fill rate is filled=\"true\" over the total.",
+	}, []string{"site", "field", "filled"})
+
+	Blocks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "crawler_blocks_total",
+		Help: "Pages detected as block or bot-challenge pages, by detection reason.",
+	}, []string{"site", "page_type", "reason"})
+
+	CircuitOpen = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "crawler_circuit_open",
+		Help: "1 while the crawl is paused after repeated blocks.",
+	}, []string{"site"})
+
+	InterceptedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "crawler_intercepted_requests_total",
+		Help: "Subresource requests seen by headless sessions, by whether they were blocked.",
+	}, []string{"resource_type", "decision"})
+
+	DBUpsertDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "storage_upsert_duration_seconds",
+		Help:    "Time to write a batch or record to the database.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	StoredProducts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "storage_products_stored_total",
+		Help: "Products written by batch flushes, by result: created, updated, unchanged or failed.",
+	}, []string{"result"})
+
+	APIRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "api_request_duration_seconds",
+		Help:    "API request latency.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// Handler serves the registered metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Serve exposes /metrics on addr in the background. It is a no-op when addr
+// is empty.
+func Serve(addr string, onError func(error)) {
+	if addr == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			onError(err)
+		}
+	}()
+}
+
+// ObservePageFetch records a page load and, if it failed, its error type.
+func ObservePageFetch(site, pageType string, started time.Time, err error) {
+	PageFetchDuration.WithLabelValues(site, pageType).Observe(time.Since(started).Seconds())
+	if err != nil {
+		NavigationErrors.WithLabelValues(site, pageType, NavigationErrorType(err)).Inc()
+	}
+}
+
+// ObserveBlock records a detected block page.
+func ObserveBlock(site, pageType, reason string) {
+	Blocks.WithLabelValues(site, pageType, reason).Inc()
+}
+
+// ObserveIntercept records a request the headless browser blocked or let
+// through.
+func ObserveIntercept(resourceType string, blocked bool) {
+	decision := "allowed"
+	if blocked {
+		decision = "blocked"
+	}
+	InterceptedRequests.WithLabelValues(resourceType, decision).Inc()
+}
+
+// ObserveField records whether a parsed field was filled.
+func ObserveField(site, field string, filled bool) {
+	ParseFields.WithLabelValues(site, field, strconv.FormatBool(filled)).Inc()
+}
+
+// ObserveUpsert records how long a database write took.
+func ObserveUpsert(operation string, started time.Time) {
+	DBUpsertDuration.WithLabelValues(operation).Observe(time.Since(started).Seconds())
+}
+
+var netErrPattern = regexp.MustCompile(`net::(ERR_[A-Z_]+)`)
+
+// NavigationErrorType buckets a navigation error into a small set of label
+// values: "timeout", "canceled", a Chrome net error such as
+// "ERR_NAME_NOT_RESOLVED", or "other".
+func NavigationErrorType(err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+	if m := netErrPattern.FindStringSubmatch(err.Error()); m != nil {
+		return m[1]
+	}
+	return "other"
+}
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Middleware records the latency of every request by its route pattern.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		APIRequestDuration.WithLabelValues(r.Method, route, strconv.Itoa(rec.status)).
+			Observe(time.Since(started).Seconds())
+	})
+}
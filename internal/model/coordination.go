@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// CoordinationEdge links a product to one of the products its detail page
+// styles it with, in the "complete the look" carousel or a lookbook card.
+// The target need not have been crawled. SeenCount counts the fetches of
+// the source that listed the target.
+type CoordinationEdge struct {
+	ID              uint      `gorm:"primaryKey"`
+	SourceProductID uint      `gorm:"index;not null"`
+	SourceCode      string    `gorm:"size:50;uniqueIndex:idx_coordination_pair;not null"`
+	TargetCode      string    `gorm:"size:50;uniqueIndex:idx_coordination_pair;index;not null"`
+	TargetURL       string    `gorm:"type:text;not null"`
+	SeenCount       int       `gorm:"not null;default:1"`
+	FirstSeenAt     time.Time `gorm:"not null"`
+	LastSeenAt      time.Time `gorm:"not null"`
+}
+
+// CoordinatedProduct is a product ranked by how often it is coordinated
+// with another one. Name and PriceYen are set when it has been crawled.
+type CoordinatedProduct struct {
+	ProductCode string
+	URL         string
+	Score       int
+	// Links counts the fetches on which either product listed the other.
+	Links int
+	// SharedLooks counts the products listing both.
+	SharedLooks int
+	Crawled     bool
+	Name        string
+	PriceYen    float64
+}
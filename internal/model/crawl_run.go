@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Crawl run statuses.
+const (
+	CrawlRunRunning   = "running"
+	CrawlRunSucceeded = "succeeded"
+	CrawlRunFailed    = "failed"
+)
+
+// CrawlRun is one invocation of cmd/crawl and how it went.
+type CrawlRun struct {
+	ID             uint      `gorm:"primaryKey"`
+	StartedAt      time.Time `gorm:"not null"`
+	FinishedAt     *time.Time
+	Mode           string         `gorm:"size:20;not null"`
+	Status         string         `gorm:"size:20;not null"`
+	ConfigSnapshot datatypes.JSON `gorm:"type:jsonb"` // crawler config, without credentials
+	Seeds          datatypes.JSON `gorm:"type:jsonb"`
+	Discovered     int
+	Fetched        int
+	Parsed         int
+	Failed         int
+	Stored         int
+	Errors         datatypes.JSON `gorm:"type:jsonb"` // error kind -> count
+	DurationP50Ms  int64
+	DurationP90Ms  int64
+	DurationP99Ms  int64
+	ErrorMessage   string `gorm:"type:text"`
+}
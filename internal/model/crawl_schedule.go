@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// CrawlSchedule tracks when a product URL was last fetched and changed, and
+// when it is next due for a recrawl.
+type CrawlSchedule struct {
+	ID            uint `gorm:"primaryKey"`
+	ProductURLID  uint `gorm:"uniqueIndex;not null"`
+	LastFetchedAt *time.Time
+	LastChangedAt *time.Time
+	ContentHash   string        `gorm:"size:64"`
+	Interval      time.Duration `gorm:"not null"` // stored as nanoseconds
+	NextDueAt     time.Time     `gorm:"index;not null"`
+	FetchCount    int           `gorm:"default:0"`
+	ChangeCount   int           `gorm:"default:0"`
+	FailCount     int           `gorm:"default:0"`
+}
@@ -16,15 +16,30 @@ type Product struct {
 	GeneralDescription         string  `gorm:"type:text;not null"`
 	ItemGeneralDescription     string  `gorm:"type:text;not null"`
 	SpecialFunctionDescription string  `gorm:"type:text;not null"`
+	Color                      string  `gorm:"size:100"`
+	ProductGroupID             *uint   `gorm:"index"`
+	ContentHash                string  `gorm:"size:64"` // see fingerprint.Product
+	LastSeenAt                 *time.Time
+	Status                     string `gorm:"size:20"` // see lifecycle package
+	StatusChangedAt            *time.Time
+	// SearchName and SearchText are the name, and the name and
+	// descriptions, folded by search.Fold; they are filled in when the
+	// product is stored.
+	SearchName string `gorm:"type:text;not null;default:''" json:"-"`
+	SearchText string `gorm:"type:text;not null;default:''" json:"-"`
 
+	ProductGroup  *ProductGroup        `gorm:"foreignKey:ProductGroupID"`
 	Images        []ProductImage       `gorm:"foreignKey:ProductID"`
 	Sizes         []ProductSize        `gorm:"foreignKey:ProductID"`
 	Keywords      []Keyword            `gorm:"many2many:product_keywords"`
 	Reviews       []Review             `gorm:"foreignKey:ProductID"`
-	AspectRatings []ReviewAspectRating `gorm:"foreignKey:ReviewID"`
+	AspectRatings []ReviewAspectRating `gorm:"foreignKey:ProductID"`
 	Coordinated   []CoordinatedItem    `gorm:"foreignKey:SourceProductID"`
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
+	// Variants are sibling colorways linked from the detail page's color
+	// selector. They are discovered during parsing and not persisted here.
+	Variants  []ProductURL `gorm:"-"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 type ProductImage struct {
@@ -73,13 +88,19 @@ type Review struct {
 	OverallRating float64   `gorm:"type:numeric(3,2);not null"`
 	Title         string    `gorm:"size:255"`
 	Body          string    `gorm:"type:text"`
+	// SearchText is the title and body folded by search.Fold.
+	SearchText string `gorm:"type:text;not null;default:''" json:"-"`
 }
 
+// ReviewAspectRating is the rating of one aspect, such as comfort or fit,
+// as a percentage. The ratings scraped from a product page summarize all
+// of its reviews and have only ProductID set.
 type ReviewAspectRating struct {
-	ID       uint    `gorm:"primaryKey"`
-	ReviewID uint    `gorm:"index"`
-	Aspect   string  `gorm:"size:100;not null"`
-	Rating   float64 `gorm:"type:numeric(3,2);not null"`
+	ID        uint    `gorm:"primaryKey"`
+	ProductID uint    `gorm:"index"`
+	ReviewID  *uint   `gorm:"index"`
+	Aspect    string  `gorm:"size:100;not null"`
+	Rating    float64 `gorm:"type:numeric(5,2);not null"`
 }
 type ProductDetail struct {
 	ID          uint   `gorm:"primaryKey"`
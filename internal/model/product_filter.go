@@ -0,0 +1,15 @@
+package model
+
+// ProductFilter narrows a product listing. Zero fields do not filter.
+type ProductFilter struct {
+	Category string // substring of the category
+	Status   string
+	GroupKey string
+	MinPrice float64
+	MaxPrice float64
+	Limit    int
+	Offset   int
+	// WithDetails loads images, sizes, reviews, aspect ratings and
+	// coordinated items too.
+	WithDetails bool
+}
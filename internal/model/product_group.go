@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// ProductGroup is the parent model (e.g. "Samba OG") that several colorway
+// product codes belong to. Groups are keyed on the productGroupID of the
+// detail page's JSON-LD only; a product without one stays ungrouped.
+type ProductGroup struct {
+	ID        uint      `gorm:"primaryKey"`
+	GroupKey  string    `gorm:"size:255;uniqueIndex;not null"`
+	Name      string    `gorm:"size:500;not null"`
+	Products  []Product `gorm:"foreignKey:ProductGroupID"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// ProductSnapshot is an append-only record of a product's attributes, see
+// the snapshot package. A new one is stored only when they changed.
+type ProductSnapshot struct {
+	ID         uint           `gorm:"primaryKey"`
+	ProductID  uint           `gorm:"index;not null"`
+	CrawlRunID *uint          `gorm:"index"` // nil when stored outside a crawl run
+	Hash       string         `gorm:"size:64;not null"`
+	Data       datatypes.JSON `gorm:"type:jsonb;not null"` // snapshot.Snapshot
+	CapturedAt time.Time      `gorm:"not null"`
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// ProductStatusChange is one entry of a product's lifecycle history.
+type ProductStatusChange struct {
+	ID         uint      `gorm:"primaryKey"`
+	ProductID  uint      `gorm:"index;not null"`
+	FromStatus string    `gorm:"size:20"`
+	ToStatus   string    `gorm:"size:20;not null"`
+	Reason     string    `gorm:"type:text"`
+	ChangedAt  time.Time `gorm:"not null"`
+}
+
+func (ProductStatusChange) TableName() string {
+	return "product_status_history"
+}
@@ -3,9 +3,23 @@ package model
 import "time"
 
 type ProductURL struct {
-	ID        uint   `gorm:"primaryKey"`
-	Code      string `gorm:"not null"`
-	URL       string `gorm:"uniqueIndex;not null"`
-	ImageURL  string
-	ScrapedAt time.Time
+	ID           uint   `gorm:"primaryKey"`
+	Code         string `gorm:"not null"`
+	URL          string `gorm:"uniqueIndex;not null"`
+	ImageURL     string
+	Seed         string // listing page the URL was first discovered from
+	CategoryPath string
+	ScrapedAt    time.Time
+	LastModified *time.Time // sitemap <lastmod>, when discovered via sitemap
+	LastListedAt *time.Time // last time a listing or sitemap still contained the URL
+
+	// Seeds lists every listing page the product appeared on.
+	Seeds []ProductURLSeed `gorm:"foreignKey:ProductURLID"`
+}
+
+type ProductURLSeed struct {
+	ID           uint   `gorm:"primaryKey"`
+	ProductURLID uint   `gorm:"uniqueIndex:idx_url_seed;not null"`
+	Seed         string `gorm:"uniqueIndex:idx_url_seed;not null"`
+	CategoryPath string
 }
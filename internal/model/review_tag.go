@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// Review tag kinds.
+const (
+	ReviewTagSentiment = "sentiment"
+	ReviewTagTopic     = "topic"
+)
+
+// ReviewTag is one label a classifier put on a review: its sentiment
+// ("positive", "neutral", "negative") or a topic it talks about.
+type ReviewTag struct {
+	ID         uint    `gorm:"primaryKey"`
+	ReviewID   uint    `gorm:"index;not null"`
+	Kind       string  `gorm:"size:20;not null"`
+	Value      string  `gorm:"size:50;not null"`
+	Score      float64 // sentiment polarity in [-1, 1]; hit count for topics
+	Classifier string  `gorm:"size:50;not null"`
+	CreatedAt  time.Time
+}
+
+// ReviewTagCount is how many of a product's reviews carry a tag.
+type ReviewTagCount struct {
+	Kind     string
+	Value    string
+	Reviews  int
+	AvgScore float64
+}
+
+// TopicSentimentCount is how many reviews mentioning a topic had a given
+// sentiment.
+type TopicSentimentCount struct {
+	Topic     string
+	Sentiment string
+	Reviews   int
+}
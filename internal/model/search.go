@@ -0,0 +1,17 @@
+package model
+
+// ProductSearchHit is a product matching a search, with the best matching
+// review body when the match came from reviews. It is a query result, not
+// a table.
+type ProductSearchHit struct {
+	ProductID          uint
+	ProductCode        string
+	Name               string
+	TitleDescription   string
+	GeneralDescription string
+	PriceYen           float64
+	DetailsURL         string
+	ReviewMatches      int
+	ReviewBody         string
+	Score              float64
+}
@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// Size stock statuses.
+const (
+	StockInStock    = "in_stock"
+	StockOutOfStock = "out_of_stock"
+)
+
+// SizeStockObservation is the stock of one size of a product as seen by
+// one fetch of its detail page.
+type SizeStockObservation struct {
+	ID         uint      `gorm:"primaryKey"`
+	ProductID  uint      `gorm:"index;not null"`
+	SizeLabel  string    `gorm:"size:20;not null"`
+	Status     string    `gorm:"size:20;not null"`
+	Quantity   float64   `gorm:"type:numeric(5,2)"` // ProductSize.Availability
+	ObservedAt time.Time `gorm:"not null"`
+}
+
+// SizeStockPeriod is a stretch of time one size kept the same stock
+// status. Until is nil for the current period.
+type SizeStockPeriod struct {
+	SizeLabel string
+	Status    string
+	Since     time.Time
+	Until     *time.Time
+}
+
+// SizeSellThrough is how one size label sold across products. Durations
+// are averages in hours over the observed sell-outs and restocks.
+type SizeSellThrough struct {
+	SizeLabel string
+	Products  int // products the size was observed for
+	SellOuts  int
+	// FirstSellOuts counts the products where this size sold out before
+	// any other size.
+	FirstSellOuts     int
+	AvgHoursToSellOut float64
+	Restocks          int
+	AvgHoursToRestock float64
+	OutOfStockNow     int
+}
@@ -0,0 +1,126 @@
+package proxy
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// standInProxy is a forward proxy for plain HTTP that requires the given
+// basic credentials and answers every proxied request itself, so no
+// upstream is needed.
+func standInProxy(t *testing.T, user, password string) (*httptest.Server, *int) {
+	t.Helper()
+	served := new(int)
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Authorization") != want {
+			w.Header().Set("Proxy-Authenticate", `Basic realm="stand-in"`)
+			http.Error(w, "proxy auth required", http.StatusProxyAuthRequired)
+			return
+		}
+		*served++
+		io.WriteString(w, "via proxy "+r.URL.String())
+	}))
+	t.Cleanup(srv.Close)
+	return srv, served
+}
+
+func TestHTTPClientGoesThroughProxy(t *testing.T) {
+	srv, served := standInProxy(t, "crawler", "s3cret")
+	proxyURL, _ := url.Parse(srv.URL)
+	proxyURL.User = url.UserPassword("crawler", "s3cret")
+
+	pool, err := NewPool([]string{proxyURL.String()}, time.Minute, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, px, err := pool.HTTPClient("JI2734", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if px == nil || px.String() == proxyURL.String() {
+		t.Fatalf("picked %v, want the proxy with its password redacted", px)
+	}
+
+	resp, err := client.Get("http://shop.test/JI2734.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || *served != 1 {
+		t.Fatalf("status %d, %d requests served, body %q", resp.StatusCode, *served, body)
+	}
+}
+
+func TestHTTPClientWithoutCredentialsIsRejected(t *testing.T) {
+	srv, served := standInProxy(t, "crawler", "s3cret")
+	pool, err := NewPool([]string{srv.URL}, time.Minute, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, _, err := pool.HTTPClient("JI2734", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get("http://shop.test/JI2734.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusProxyAuthRequired || *served != 0 {
+		t.Fatalf("status %d, %d requests served", resp.StatusCode, *served)
+	}
+}
+
+func TestPickStickyAndQuarantine(t *testing.T) {
+	pool, err := NewPool([]string{"http://a.test:8080", "http://b.test:8080"}, time.Minute, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	pool.now = func() time.Time { return now }
+
+	first := pool.Pick("JI2734")
+	if again := pool.Pick("JI2734"); again != first {
+		t.Fatalf("session moved from %v to %v", first, again)
+	}
+
+	pool.Report(first, Blocked)
+	moved := pool.Pick("JI2734")
+	if moved == nil || moved == first {
+		t.Fatalf("after a block the session kept %v", moved)
+	}
+
+	pool.Report(moved, Blocked)
+	if px := pool.Pick("JI2734"); px != nil {
+		t.Fatalf("picked %v with every proxy quarantined", px)
+	}
+	if _, _, err := pool.HTTPClient("JI2734", time.Second); err != ErrNoProxy {
+		t.Fatalf("HTTPClient error = %v, want ErrNoProxy", err)
+	}
+}
+
+func TestPickPrunesExpiredSessions(t *testing.T) {
+	pool, err := NewPool([]string{"http://a.test:8080"}, time.Minute, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	pool.now = func() time.Time { return now }
+
+	for _, code := range []string{"JI2734", "IE3437", "B75806"} {
+		pool.Pick(code)
+	}
+	now = now.Add(2 * time.Minute)
+	pool.Pick("GY7036")
+
+	if n := len(pool.sessions); n != 1 {
+		t.Fatalf("%d sessions kept, want only the fresh one", n)
+	}
+}
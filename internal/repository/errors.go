@@ -0,0 +1,12 @@
+// Package repository holds what the storage implementations share. The
+// repository interfaces, one per aggregate (products, product URLs, reviews
+// and crawl runs), are declared in package service next to their users;
+// package postgres implements them on the database and package memory in
+// memory.
+package repository
+
+import "errors"
+
+// ErrNotFound is returned by repositories when a looked-up record does not
+// exist, whatever the storage behind them.
+var ErrNotFound = errors.New("record not found")
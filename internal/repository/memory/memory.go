@@ -0,0 +1,750 @@
+// Package memory holds in-memory implementations of the service
+// repositories, for unit tests and for running services without a
+// database. They keep no indexes and are meant for small data sets.
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/lifecycle"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/repository"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/search"
+)
+
+// ProductRepository implements service.ProductRepository.
+type ProductRepository struct {
+	mu            sync.RWMutex
+	nextID        uint
+	nextChildID   uint
+	byCode        map[string]*model.Product
+	history       []model.ProductStatusChange
+	snapshots     []model.ProductSnapshot
+	stock         []model.SizeStockObservation
+	edges         []model.CoordinationEdge
+	keywords      map[uint][]string
+	categoryPaths map[string][]string
+}
+
+func NewProductRepository() *ProductRepository {
+	return &ProductRepository{
+		byCode:        map[string]*model.Product{},
+		keywords:      map[uint][]string{},
+		categoryPaths: map[string][]string{},
+	}
+}
+
+// SetCategoryPaths sets the listing category paths CategoryPaths returns
+// for a product code; in Postgres they come from the discovered URLs.
+func (r *ProductRepository) SetCategoryPaths(code string, paths []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.categoryPaths[code] = append([]string(nil), paths...)
+}
+
+func (r *ProductRepository) Store(_ context.Context, p *model.Product) (created, changed bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	existing, ok := r.byCode[p.ProductCode]
+	if ok {
+		p.ID = existing.ID
+		p.Status = existing.Status
+		if p.ContentHash != "" && existing.ContentHash == p.ContentHash {
+			existing.LastSeenAt = &now
+			return false, false, nil
+		}
+		p.CreatedAt = existing.CreatedAt
+		p.StatusChangedAt = existing.StatusChangedAt
+	} else {
+		r.nextID++
+		p.ID = r.nextID
+		p.CreatedAt = now
+	}
+	p.UpdatedAt = now
+	p.LastSeenAt = &now
+
+	for i := range p.Images {
+		r.nextChildID++
+		p.Images[i].ID, p.Images[i].ProductID = r.nextChildID, p.ID
+	}
+	for i := range p.Sizes {
+		r.nextChildID++
+		p.Sizes[i].ID, p.Sizes[i].ProductID = r.nextChildID, p.ID
+	}
+	for i := range p.Reviews {
+		r.nextChildID++
+		p.Reviews[i].ID, p.Reviews[i].ProductID = r.nextChildID, p.ID
+	}
+	for i := range p.AspectRatings {
+		r.nextChildID++
+		p.AspectRatings[i].ID, p.AspectRatings[i].ProductID = r.nextChildID, p.ID
+	}
+	for i := range p.Coordinated {
+		r.nextChildID++
+		p.Coordinated[i].ID, p.Coordinated[i].SourceProductID = r.nextChildID, p.ID
+	}
+
+	stored := copyProduct(*p)
+	r.byCode[p.ProductCode] = &stored
+	return !ok, true, nil
+}
+
+// StoreBatch stores the products one by one.
+func (r *ProductRepository) StoreBatch(ctx context.Context, products []*model.Product) ([]repository.StoreOutcome, error) {
+	outcomes := make([]repository.StoreOutcome, len(products))
+	for i, p := range products {
+		created, changed, err := r.Store(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		outcomes[i] = repository.OutcomeOf(created, changed)
+	}
+	return outcomes, nil
+}
+
+func (r *ProductRepository) FindByCode(_ context.Context, code string) (*model.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.byCode[code]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	out := copyProduct(*p)
+	return &out, nil
+}
+
+func (r *ProductRepository) FindByCodes(_ context.Context, codes []string) ([]model.Product, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []model.Product
+	for _, code := range codes {
+		if p, ok := r.byCode[code]; ok {
+			out = append(out, copyProduct(*p))
+		}
+	}
+	return out, nil
+}
+
+func (r *ProductRepository) List(_ context.Context, f model.ProductFilter) ([]model.Product, error) {
+	var out []model.Product
+	for _, p := range r.sorted() {
+		switch {
+		case f.Category != "" && !strings.Contains(p.Category, f.Category),
+			f.Status != "" && p.Status != f.Status,
+			f.GroupKey != "" && (p.ProductGroup == nil || p.ProductGroup.GroupKey != f.GroupKey),
+			f.MinPrice > 0 && p.PriceYen < f.MinPrice,
+			f.MaxPrice > 0 && p.PriceYen > f.MaxPrice:
+			continue
+		}
+		if !f.WithDetails {
+			p.Images, p.Sizes, p.Reviews, p.AspectRatings, p.Coordinated = nil, nil, nil, nil, nil
+		}
+		out = append(out, p)
+	}
+	return page(out, f.Offset, f.Limit), nil
+}
+
+func (r *ProductRepository) InBatches(_ context.Context, size int, fn func([]model.Product) error) error {
+	all := r.sorted()
+	for start := 0; start < len(all); start += size {
+		end := min(start+size, len(all))
+		if err := fn(all[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *ProductRepository) Variants(_ context.Context, code string) ([]model.Product, error) {
+	r.mu.RLock()
+	p, ok := r.byCode[code]
+	r.mu.RUnlock()
+	if !ok || p.ProductGroup == nil {
+		return nil, nil
+	}
+	var out []model.Product
+	for _, v := range r.sorted() {
+		if v.ProductGroup != nil && v.ProductGroup.GroupKey == p.ProductGroup.GroupKey {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+func (r *ProductRepository) SetStatus(_ context.Context, productID uint, from, to, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p := r.byID(productID)
+	if p == nil {
+		return repository.ErrNotFound
+	}
+	now := time.Now()
+	p.Status, p.StatusChangedAt = to, &now
+	r.history = append(r.history, model.ProductStatusChange{
+		ID:         uint(len(r.history) + 1),
+		ProductID:  productID,
+		FromStatus: from,
+		ToStatus:   to,
+		Reason:     reason,
+		ChangedAt:  now,
+	})
+	return nil
+}
+
+func (r *ProductRepository) StatusHistory(_ context.Context, productID uint) ([]model.ProductStatusChange, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []model.ProductStatusChange
+	for _, h := range r.history {
+		if h.ProductID == productID {
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
+
+func (r *ProductRepository) AddSnapshot(_ context.Context, s *model.ProductSnapshot) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s.ID = uint(len(r.snapshots) + 1)
+	r.snapshots = append(r.snapshots, *s)
+	return nil
+}
+
+func (r *ProductRepository) Snapshots(_ context.Context, productID uint) ([]model.ProductSnapshot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []model.ProductSnapshot
+	for _, s := range r.snapshots {
+		if s.ProductID == productID {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (r *ProductRepository) LatestSnapshot(_ context.Context, productID uint) (*model.ProductSnapshot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := len(r.snapshots) - 1; i >= 0; i-- {
+		if s := r.snapshots[i]; s.ProductID == productID {
+			return &s, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *ProductRepository) AddStockObservations(_ context.Context, obs []model.SizeStockObservation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, o := range obs {
+		o.ID = uint(len(r.stock) + 1)
+		r.stock = append(r.stock, o)
+	}
+	return nil
+}
+
+func (r *ProductRepository) StockChanges(_ context.Context, productID uint, since time.Time) ([]model.SizeStockObservation, error) {
+	r.mu.RLock()
+	var obs []model.SizeStockObservation
+	for _, o := range r.stock {
+		if (productID == 0 || o.ProductID == productID) && !o.ObservedAt.Before(since) {
+			obs = append(obs, o)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.SliceStable(obs, func(i, j int) bool {
+		a, b := obs[i], obs[j]
+		if a.ProductID != b.ProductID {
+			return a.ProductID < b.ProductID
+		}
+		if a.SizeLabel != b.SizeLabel {
+			return a.SizeLabel < b.SizeLabel
+		}
+		return a.ObservedAt.Before(b.ObservedAt)
+	})
+	var out []model.SizeStockObservation
+	for i, o := range obs {
+		if i > 0 {
+			prev := obs[i-1]
+			if prev.ProductID == o.ProductID && prev.SizeLabel == o.SizeLabel && prev.Status == o.Status {
+				continue
+			}
+		}
+		out = append(out, o)
+	}
+	return out, nil
+}
+
+func (r *ProductRepository) StockSizeLabels(_ context.Context, productID uint) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	seen := map[string]bool{}
+	var labels []string
+	for _, o := range r.stock {
+		if o.ProductID == productID && !seen[o.SizeLabel] {
+			seen[o.SizeLabel] = true
+			labels = append(labels, o.SizeLabel)
+		}
+	}
+	sort.Strings(labels)
+	return labels, nil
+}
+
+func (r *ProductRepository) StoreCoordinations(_ context.Context, edges []model.CoordinationEdge) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+next:
+	for _, e := range edges {
+		for i := range r.edges {
+			known := &r.edges[i]
+			if known.SourceCode == e.SourceCode && known.TargetCode == e.TargetCode {
+				known.SeenCount++
+				known.TargetURL, known.LastSeenAt = e.TargetURL, e.LastSeenAt
+				continue next
+			}
+		}
+		e.ID = uint(len(r.edges) + 1)
+		r.edges = append(r.edges, e)
+	}
+	return nil
+}
+
+func (r *ProductRepository) Coordinations(_ context.Context, code string) ([]model.CoordinationEdge, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	listing := map[string]bool{}
+	for _, e := range r.edges {
+		if e.TargetCode == code {
+			listing[e.SourceCode] = true
+		}
+	}
+	var out []model.CoordinationEdge
+	for _, e := range r.edges {
+		if e.SourceCode == code || e.TargetCode == code || listing[e.SourceCode] {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (r *ProductRepository) SetKeywords(_ context.Context, productID uint, kws []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keywords[productID] = append([]string(nil), kws...)
+	return nil
+}
+
+func (r *ProductRepository) ByKeyword(_ context.Context, kw string, limit int) ([]model.Product, error) {
+	r.mu.RLock()
+	linked := map[uint]bool{}
+	for id, kws := range r.keywords {
+		for _, k := range kws {
+			if k == kw {
+				linked[id] = true
+			}
+		}
+	}
+	r.mu.RUnlock()
+
+	var out []model.Product
+	for _, p := range r.sorted() {
+		if linked[p.ID] {
+			out = append(out, p)
+		}
+	}
+	return page(out, 0, limit), nil
+}
+
+func (r *ProductRepository) CategoryPaths(_ context.Context, code string) ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]string(nil), r.categoryPaths[code]...), nil
+}
+
+// Search matches terms by substring of the text folded by search.Fold, like
+// the Postgres LIKE search, and scores a product by how many terms its
+// name, text and reviews contain.
+func (r *ProductRepository) Search(_ context.Context, terms []string, limit int) ([]model.ProductSearchHit, error) {
+	var hits []model.ProductSearchHit
+	for _, p := range r.sorted() {
+		text := search.Fold(p.Name + " " + p.TitleDescription + " " + p.GeneralDescription)
+		hit := model.ProductSearchHit{
+			ProductID: p.ID, ProductCode: p.ProductCode, Name: p.Name,
+			TitleDescription: p.TitleDescription, GeneralDescription: p.GeneralDescription,
+			PriceYen: p.PriceYen, DetailsURL: p.DetailsURL,
+		}
+		matchedAll := true
+		for _, t := range terms {
+			inText := strings.Contains(text, t)
+			inReviews := 0
+			for _, rv := range p.Reviews {
+				if strings.Contains(search.Fold(rv.Title+" "+rv.Body), t) {
+					inReviews++
+					if hit.ReviewBody == "" {
+						hit.ReviewBody = rv.Body
+					}
+				}
+			}
+			if !inText && inReviews == 0 {
+				matchedAll = false
+				break
+			}
+			if strings.Contains(search.Fold(p.Name), t) {
+				hit.Score += 3
+			}
+			if inText {
+				hit.Score++
+			}
+			hit.Score += 0.2 * float64(min(inReviews, 5))
+			hit.ReviewMatches = max(hit.ReviewMatches, inReviews)
+		}
+		if matchedAll {
+			hits = append(hits, hit)
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	return page(hits, 0, limit), nil
+}
+
+// sorted returns copies of all products ordered by product code.
+func (r *ProductRepository) sorted() []model.Product {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]model.Product, 0, len(r.byCode))
+	for _, p := range r.byCode {
+		out = append(out, copyProduct(*p))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ProductCode < out[j].ProductCode })
+	return out
+}
+
+func (r *ProductRepository) byID(id uint) *model.Product {
+	for _, p := range r.byCode {
+		if p.ID == id {
+			return p
+		}
+	}
+	return nil
+}
+
+// ProductURLRepository implements service.ProductURLRepository.
+type ProductURLRepository struct {
+	products *ProductRepository // for product status in MissingFromListings
+
+	mu        sync.RWMutex
+	nextID    uint
+	byURL     map[string]*model.ProductURL
+	schedules map[uint]*model.CrawlSchedule
+}
+
+func NewProductURLRepository(products *ProductRepository) *ProductURLRepository {
+	return &ProductURLRepository{
+		products:  products,
+		byURL:     map[string]*model.ProductURL{},
+		schedules: map[uint]*model.CrawlSchedule{},
+	}
+}
+
+func (r *ProductURLRepository) Store(_ context.Context, urls []model.ProductURL) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range urls {
+		u := &urls[i]
+		existing, ok := r.byURL[u.URL]
+		if !ok {
+			r.nextID++
+			u.ID = r.nextID
+			stored := *u
+			stored.Seeds = append([]model.ProductURLSeed(nil), u.Seeds...)
+			r.byURL[u.URL] = &stored
+			continue
+		}
+		u.ID = existing.ID
+		for _, s := range u.Seeds {
+			if !hasSeed(existing.Seeds, s.Seed) {
+				s.ProductURLID = existing.ID
+				existing.Seeds = append(existing.Seeds, s)
+			}
+		}
+		if u.LastModified != nil {
+			existing.LastModified = u.LastModified
+		}
+		if u.LastListedAt != nil {
+			existing.LastListedAt = u.LastListedAt
+		}
+	}
+	return nil
+}
+
+func (r *ProductURLRepository) Due(_ context.Context, now time.Time, budget int) ([]model.ProductURL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var due []model.ProductURL
+	var dueAt []time.Time
+	for _, u := range r.byURL {
+		s, ok := r.schedules[u.ID]
+		switch {
+		case !ok:
+			due, dueAt = append(due, *u), append(dueAt, time.Time{})
+		case !s.NextDueAt.After(now),
+			u.LastModified != nil && s.LastFetchedAt != nil && u.LastModified.After(*s.LastFetchedAt):
+			due, dueAt = append(due, *u), append(dueAt, s.NextDueAt)
+		}
+	}
+	idx := make([]int, len(due))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(a, b int) bool { return dueAt[idx[a]].Before(dueAt[idx[b]]) })
+	out := make([]model.ProductURL, 0, len(due))
+	for _, i := range idx {
+		out = append(out, due[i])
+	}
+	return page(out, 0, budget), nil
+}
+
+func (r *ProductURLRepository) MissingFromListings(ctx context.Context, seeds []string, since time.Time, limit int) ([]model.ProductURL, error) {
+	r.mu.RLock()
+	var candidates []model.ProductURL
+	for _, u := range r.byURL {
+		listed := false
+		for _, s := range seeds {
+			listed = listed || hasSeed(u.Seeds, s)
+		}
+		if listed && (u.LastListedAt == nil || u.LastListedAt.Before(since)) {
+			candidates = append(candidates, *u)
+		}
+	}
+	r.mu.RUnlock()
+
+	var out []model.ProductURL
+	for _, u := range candidates {
+		p, err := r.products.FindByCode(ctx, u.Code)
+		if err != nil || p.Status == lifecycle.StatusDelisted {
+			continue
+		}
+		out = append(out, u)
+	}
+	return page(out, 0, limit), nil
+}
+
+func (r *ProductURLRepository) Schedule(_ context.Context, productURLID uint) (*model.CrawlSchedule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if s, ok := r.schedules[productURLID]; ok {
+		out := *s
+		return &out, nil
+	}
+	return &model.CrawlSchedule{ProductURLID: productURLID}, nil
+}
+
+func (r *ProductURLRepository) SaveSchedule(_ context.Context, s *model.CrawlSchedule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s.ID == 0 {
+		s.ID = s.ProductURLID
+	}
+	stored := *s
+	r.schedules[s.ProductURLID] = &stored
+	return nil
+}
+
+// CrawlRunRepository implements service.CrawlRunRepository.
+type CrawlRunRepository struct {
+	mu   sync.RWMutex
+	runs []model.CrawlRun
+}
+
+func NewCrawlRunRepository() *CrawlRunRepository {
+	return &CrawlRunRepository{}
+}
+
+func (r *CrawlRunRepository) Create(_ context.Context, run *model.CrawlRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run.ID = uint(len(r.runs) + 1)
+	r.runs = append(r.runs, *run)
+	return nil
+}
+
+func (r *CrawlRunRepository) Save(_ context.Context, run *model.CrawlRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if run.ID == 0 || int(run.ID) > len(r.runs) {
+		return repository.ErrNotFound
+	}
+	r.runs[run.ID-1] = *run
+	return nil
+}
+
+func (r *CrawlRunRepository) List(_ context.Context, limit int) ([]model.CrawlRun, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]model.CrawlRun, 0, len(r.runs))
+	for i := len(r.runs) - 1; i >= 0; i-- {
+		out = append(out, r.runs[i])
+	}
+	return page(out, 0, limit), nil
+}
+
+func (r *CrawlRunRepository) Find(_ context.Context, id uint) (*model.CrawlRun, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if id == 0 || int(id) > len(r.runs) {
+		return nil, repository.ErrNotFound
+	}
+	run := r.runs[id-1]
+	return &run, nil
+}
+
+// ReviewRepository implements service.ReviewRepository over the reviews
+// stored with products.
+type ReviewRepository struct {
+	products *ProductRepository
+
+	mu   sync.RWMutex
+	tags map[uint][]model.ReviewTag // by review ID
+}
+
+func NewReviewRepository(products *ProductRepository) *ReviewRepository {
+	return &ReviewRepository{products: products, tags: map[uint][]model.ReviewTag{}}
+}
+
+func (r *ReviewRepository) ForProduct(_ context.Context, productID uint) ([]model.Review, error) {
+	r.products.mu.RLock()
+	defer r.products.mu.RUnlock()
+	p := r.products.byID(productID)
+	if p == nil {
+		return nil, nil
+	}
+	return append([]model.Review(nil), p.Reviews...), nil
+}
+
+func (r *ReviewRepository) ReplaceTags(_ context.Context, reviewIDs []uint, tags []model.ReviewTag) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range reviewIDs {
+		delete(r.tags, id)
+	}
+	for _, t := range tags {
+		r.tags[t.ReviewID] = append(r.tags[t.ReviewID], t)
+	}
+	return nil
+}
+
+func (r *ReviewRepository) TagCounts(ctx context.Context, productID uint) ([]model.ReviewTagCount, error) {
+	reviews, _ := r.ForProduct(ctx, productID)
+
+	r.mu.RLock()
+	type key struct{ kind, value string }
+	counts := map[key]*model.ReviewTagCount{}
+	for _, rv := range reviews {
+		for _, t := range r.tags[rv.ID] {
+			k := key{t.Kind, t.Value}
+			c, ok := counts[k]
+			if !ok {
+				c = &model.ReviewTagCount{Kind: t.Kind, Value: t.Value}
+				counts[k] = c
+			}
+			// AvgScore holds the running sum until the end
+			c.Reviews++
+			c.AvgScore += t.Score
+		}
+	}
+	r.mu.RUnlock()
+
+	out := make([]model.ReviewTagCount, 0, len(counts))
+	for _, c := range counts {
+		c.AvgScore /= float64(c.Reviews)
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Reviews != out[j].Reviews {
+			return out[i].Reviews > out[j].Reviews
+		}
+		return out[i].Value < out[j].Value
+	})
+	return out, nil
+}
+
+func (r *ReviewRepository) TopicSentimentCounts(ctx context.Context, productID uint) ([]model.TopicSentimentCount, error) {
+	reviews, _ := r.ForProduct(ctx, productID)
+
+	r.mu.RLock()
+	type key struct{ topic, sentiment string }
+	counts := map[key]int{}
+	for _, rv := range reviews {
+		sentiment := ""
+		for _, t := range r.tags[rv.ID] {
+			if t.Kind == model.ReviewTagSentiment {
+				sentiment = t.Value
+			}
+		}
+		if sentiment == "" {
+			continue
+		}
+		for _, t := range r.tags[rv.ID] {
+			if t.Kind == model.ReviewTagTopic {
+				counts[key{t.Value, sentiment}]++
+			}
+		}
+	}
+	r.mu.RUnlock()
+
+	out := make([]model.TopicSentimentCount, 0, len(counts))
+	for k, n := range counts {
+		out = append(out, model.TopicSentimentCount{Topic: k.topic, Sentiment: k.sentiment, Reviews: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Topic != out[j].Topic {
+			return out[i].Topic < out[j].Topic
+		}
+		return out[i].Sentiment < out[j].Sentiment
+	})
+	return out, nil
+}
+
+// copyProduct copies p and its child slices, so that callers cannot modify
+// what is stored.
+func copyProduct(p model.Product) model.Product {
+	p.Images = append([]model.ProductImage(nil), p.Images...)
+	p.Sizes = append([]model.ProductSize(nil), p.Sizes...)
+	p.Reviews = append([]model.Review(nil), p.Reviews...)
+	p.AspectRatings = append([]model.ReviewAspectRating(nil), p.AspectRatings...)
+	p.Coordinated = append([]model.CoordinatedItem(nil), p.Coordinated...)
+	p.Variants = nil
+	if p.ProductGroup != nil {
+		g := *p.ProductGroup
+		p.ProductGroup = &g
+	}
+	return p
+}
+
+func hasSeed(seeds []model.ProductURLSeed, seed string) bool {
+	for _, s := range seeds {
+		if s.Seed == seed {
+			return true
+		}
+	}
+	return false
+}
+
+// page applies offset and limit to items; a non-positive limit means all.
+func page[T any](items []T, offset, limit int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
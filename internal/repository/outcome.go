@@ -0,0 +1,35 @@
+package repository
+
+// StoreOutcome is what storing one product did.
+type StoreOutcome int
+
+const (
+	// Unchanged means the stored content fingerprint matched; only
+	// last_seen_at was bumped.
+	Unchanged StoreOutcome = iota
+	Created
+	Updated
+)
+
+func (o StoreOutcome) String() string {
+	switch o {
+	case Created:
+		return "created"
+	case Updated:
+		return "updated"
+	default:
+		return "unchanged"
+	}
+}
+
+// OutcomeOf maps the created and changed results of storing one product.
+func OutcomeOf(created, changed bool) StoreOutcome {
+	switch {
+	case created:
+		return Created
+	case changed:
+		return Updated
+	default:
+		return Unchanged
+	}
+}
@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/metrics"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StoreCoordinationEdges records that the sources listed the targets once
+// more: new pairs are inserted, known ones get their count bumped and
+// their target URL and last-seen time refreshed.
+func StoreCoordinationEdges(db *gorm.DB, edges []model.CoordinationEdge) error {
+	defer metrics.ObserveUpsert("coordination_edges", time.Now())
+	if len(edges) == 0 {
+		return nil
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "source_code"}, {Name: "target_code"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"seen_count":   gorm.Expr("coordination_edges.seen_count + 1"),
+			"target_url":   gorm.Expr("EXCLUDED.target_url"),
+			"last_seen_at": gorm.Expr("EXCLUDED.last_seen_at"),
+		}),
+	}).CreateInBatches(&edges, upsertBatchSize).Error
+}
+
+// CoordinationNeighborhood returns the edges from and to a product code,
+// and every edge from the products that list it.
+func CoordinationNeighborhood(db *gorm.DB, code string) ([]model.CoordinationEdge, error) {
+	var edges []model.CoordinationEdge
+	err := db.Where("source_code = ? OR target_code = ? OR source_code IN (?)", code, code,
+		db.Model(&model.CoordinationEdge{}).Select("source_code").Where("target_code = ?", code)).
+		Order("source_code, target_code").
+		Find(&edges).Error
+	return edges, err
+}
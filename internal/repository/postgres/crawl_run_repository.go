@@ -0,0 +1,29 @@
+package postgres
+
+import (
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"gorm.io/gorm"
+)
+
+func CreateCrawlRun(db *gorm.DB, run *model.CrawlRun) error {
+	return db.Create(run).Error
+}
+
+func SaveCrawlRun(db *gorm.DB, run *model.CrawlRun) error {
+	return db.Save(run).Error
+}
+
+// ListCrawlRuns returns the most recent runs first.
+func ListCrawlRuns(db *gorm.DB, limit int) ([]model.CrawlRun, error) {
+	var runs []model.CrawlRun
+	err := db.Order("started_at DESC").Limit(limit).Find(&runs).Error
+	return runs, err
+}
+
+func FindCrawlRun(db *gorm.DB, id uint) (*model.CrawlRun, error) {
+	var run model.CrawlRun
+	if err := db.First(&run, id).Error; err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
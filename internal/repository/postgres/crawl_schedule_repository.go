@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DueProductURLs returns up to budget URLs that need fetching: never fetched,
+// past their next-due time, or with a sitemap lastmod newer than the last
+// fetch. Never-fetched and most overdue URLs come first.
+func DueProductURLs(db *gorm.DB, now time.Time, budget int) ([]model.ProductURL, error) {
+	var urls []model.ProductURL
+	err := db.Model(&model.ProductURL{}).
+		Joins("LEFT JOIN crawl_schedules ON crawl_schedules.product_url_id = product_urls.id").
+		Where("crawl_schedules.id IS NULL OR crawl_schedules.next_due_at <= ? OR product_urls.last_modified > crawl_schedules.last_fetched_at", now).
+		Order("crawl_schedules.next_due_at NULLS FIRST").
+		Limit(budget).
+		Find(&urls).Error
+	return urls, err
+}
+
+// FindCrawlSchedule returns the schedule for a URL, or a new unsaved one.
+func FindCrawlSchedule(db *gorm.DB, productURLID uint) (*model.CrawlSchedule, error) {
+	s := model.CrawlSchedule{ProductURLID: productURLID}
+	err := db.Where("product_url_id = ?", productURLID).First(&s).Error
+	if err == gorm.ErrRecordNotFound {
+		return &s, nil
+	}
+	return &s, err
+}
+
+// SaveCrawlSchedule upserts the schedule by its URL, so that two workers
+// fetching the same URL for the first time do not collide.
+func SaveCrawlSchedule(db *gorm.DB, s *model.CrawlSchedule) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "product_url_id"}},
+		UpdateAll: true,
+	}).Create(s).Error
+}
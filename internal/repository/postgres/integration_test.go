@@ -0,0 +1,458 @@
+//go:build integration
+
+package postgres
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/keyword"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/repository"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/search"
+	"github.com/jakib01/web-crawiling-golang-colly/migrations"
+	pgdriver "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// These tests run against a real Postgres, migrated from migrations/*.sql:
+//
+//	go test -tags integration ./internal/repository/postgres/
+//
+// They start an embedded Postgres, which downloads its binaries on first
+// use, unless TEST_POSTGRES_DSN names a database to use instead; its
+// encoding must be UTF8 for normalize(). Every test gets a schema of its
+// own that is dropped afterwards. pg_trgm is created in public first, so
+// that it outlives those schemas.
+
+var testDSN string
+
+func TestMain(m *testing.M) {
+	testDSN = os.Getenv("TEST_POSTGRES_DSN")
+	stop := func() error { return nil }
+	if testDSN == "" {
+		dir, err := os.MkdirTemp("", "crawler-postgres")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer os.RemoveAll(dir)
+		port, err := freePort()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		pg := embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+			Version(embeddedpostgres.V16).
+			Encoding("UTF8").
+			Port(port).
+			RuntimePath(dir).
+			Logger(io.Discard))
+		if err := pg.Start(); err != nil {
+			fmt.Fprintln(os.Stderr, "start embedded postgres (or set TEST_POSTGRES_DSN):", err)
+			os.Exit(1)
+		}
+		stop = pg.Stop
+		testDSN = fmt.Sprintf("host=localhost port=%d user=postgres password=postgres dbname=postgres sslmode=disable", port)
+	}
+
+	if err := createExtensions(); err != nil {
+		fmt.Fprintln(os.Stderr, "create extensions:", err)
+		stop()
+		os.Exit(1)
+	}
+	code := m.Run()
+	if err := stop(); err != nil {
+		fmt.Fprintln(os.Stderr, "stop embedded postgres:", err)
+	}
+	os.Exit(code)
+}
+
+func createExtensions() error {
+	db, err := gorm.Open(pgdriver.Open(testDSN), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		return err
+	}
+	defer closeDB(db)
+	return db.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm SCHEMA public").Error
+}
+
+func freePort() (uint32, error) {
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return uint32(l.Addr().(*net.TCPAddr).Port), nil
+}
+
+// openDB returns a connection whose search_path is a fresh schema with
+// every migration applied.
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	schema := fmt.Sprintf("it_%d", time.Now().UnixNano())
+
+	admin, err := gorm.Open(pgdriver.Open(testDSN), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatal(err)
+	}
+	closeDB(admin)
+	t.Cleanup(func() {
+		admin, err := gorm.Open(pgdriver.Open(testDSN), &gorm.Config{Logger: logger.Discard})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer closeDB(admin)
+		if err := admin.Exec("DROP SCHEMA " + schema + " CASCADE").Error; err != nil {
+			t.Error(err)
+		}
+	})
+
+	db, err := gorm.Open(pgdriver.Open(testDSN+" search_path="+schema+",public"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { closeDB(db) })
+
+	files, err := fs.Glob(migrations.Postgres, "*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		script, err := migrations.Postgres.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Exec(string(script)).Error; err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+	}
+	return db
+}
+
+func closeDB(db *gorm.DB) {
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
+
+func count(t *testing.T, db *gorm.DB, table string) int64 {
+	t.Helper()
+	var n int64
+	if err := db.Table(table).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestStoreProductURLsUpsert(t *testing.T) {
+	db := openDB(t)
+	day1 := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	samba := "https://www.adidas.jp/samba-og/JI2734.html"
+	gazelle := "https://www.adidas.jp/gazelle/IE3437.html"
+	mens := model.ProductURLSeed{Seed: "https://www.adidas.jp/men-shoes", CategoryPath: "men-shoes"}
+	womens := model.ProductURLSeed{Seed: "https://www.adidas.jp/women-shoes", CategoryPath: "women-shoes"}
+
+	input := func() []model.ProductURL {
+		return []model.ProductURL{
+			{Code: "JI2734", URL: samba, LastModified: &day1, Seeds: []model.ProductURLSeed{mens}},
+			{Code: "IE3437", URL: gazelle, Seeds: []model.ProductURLSeed{mens}},
+			// the same URL again in one batch, listed under another seed
+			{Code: "JI2734", URL: samba, LastListedAt: &day1, Seeds: []model.ProductURLSeed{womens, mens}},
+		}
+	}
+
+	first := input()
+	if err := StoreProductURLs(db, first); err != nil {
+		t.Fatal(err)
+	}
+	if first[0].ID == 0 || first[0].ID != first[2].ID || first[1].ID == first[0].ID {
+		t.Fatalf("IDs %d, %d, %d", first[0].ID, first[1].ID, first[2].ID)
+	}
+
+	// re-running the same input changes nothing and reports the same IDs
+	again := input()
+	if err := StoreProductURLs(db, again); err != nil {
+		t.Fatal(err)
+	}
+	for i := range again {
+		if again[i].ID != first[i].ID {
+			t.Errorf("entry %d: ID %d on re-run, was %d", i, again[i].ID, first[i].ID)
+		}
+	}
+	if n := count(t, db, "product_urls"); n != 2 {
+		t.Errorf("%d product_urls, want 2", n)
+	}
+	if n := count(t, db, "product_url_seeds"); n != 3 {
+		t.Errorf("%d product_url_seeds, want 3", n)
+	}
+
+	// a later listing without lastmod keeps the stored one
+	if err := StoreProductURLs(db, []model.ProductURL{{Code: "JI2734", URL: samba, LastListedAt: &day2}}); err != nil {
+		t.Fatal(err)
+	}
+	var stored model.ProductURL
+	if err := db.Where("url = ?", samba).First(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.LastModified == nil || !stored.LastModified.Equal(day1) || stored.LastListedAt == nil || !stored.LastListedAt.Equal(day2) {
+		t.Errorf("lastmod %v, last listed %v; want %v and %v", stored.LastModified, stored.LastListedAt, day1, day2)
+	}
+}
+
+func TestStoreProductURLsAcrossBatches(t *testing.T) {
+	db := openDB(t)
+	seed := model.ProductURLSeed{Seed: "https://www.adidas.jp/shoes", CategoryPath: "shoes"}
+	const total = 2*upsertBatchSize + 37
+
+	input := func() []model.ProductURL {
+		urls := make([]model.ProductURL, total)
+		for i := range urls {
+			code := fmt.Sprintf("AA%04d", i)
+			urls[i] = model.ProductURL{Code: code, URL: "https://www.adidas.jp/p/" + code + ".html", Seeds: []model.ProductURLSeed{seed}}
+		}
+		return urls
+	}
+
+	first := input()
+	if err := StoreProductURLs(db, first); err != nil {
+		t.Fatal(err)
+	}
+	again := input()
+	if err := StoreProductURLs(db, again); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[uint]bool{}
+	for i := range first {
+		if first[i].ID == 0 || seen[first[i].ID] {
+			t.Fatalf("entry %d has ID %d", i, first[i].ID)
+		}
+		seen[first[i].ID] = true
+		if again[i].ID != first[i].ID {
+			t.Fatalf("entry %d: ID %d on re-run, was %d", i, again[i].ID, first[i].ID)
+		}
+	}
+	// the IDs filled in from RETURNING belong to the right URLs
+	var stored []model.ProductURL
+	if err := db.Find(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	urlByID := make(map[uint]string, len(stored))
+	for _, u := range stored {
+		urlByID[u.ID] = u.URL
+	}
+	for i := range first {
+		if urlByID[first[i].ID] != first[i].URL {
+			t.Fatalf("entry %d got the ID of %s", i, urlByID[first[i].ID])
+		}
+	}
+	if len(stored) != total {
+		t.Errorf("%d product_urls, want %d", len(stored), total)
+	}
+	if n := count(t, db, "product_url_seeds"); n != total {
+		t.Errorf("%d product_url_seeds, want %d", n, total)
+	}
+}
+
+func TestStoreProductURLSeedsSkipsKnown(t *testing.T) {
+	db := openDB(t)
+	urls := []model.ProductURL{{Code: "JI2734", URL: "https://www.adidas.jp/samba-og/JI2734.html"}}
+	if err := StoreProductURLs(db, urls); err != nil {
+		t.Fatal(err)
+	}
+	id := urls[0].ID
+	seeds := func() []model.ProductURLSeed {
+		return []model.ProductURLSeed{
+			{ProductURLID: id, Seed: "https://www.adidas.jp/men-shoes"},
+			{ProductURLID: id, Seed: "https://www.adidas.jp/men-shoes"},
+			{ProductURLID: id, Seed: "https://www.adidas.jp/originals"},
+		}
+	}
+	for run := 0; run < 2; run++ {
+		if err := storeProductURLSeeds(db, seeds()); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+		if n := count(t, db, "product_url_seeds"); n != 2 {
+			t.Errorf("run %d: %d product_url_seeds, want 2", run, n)
+		}
+	}
+}
+
+func TestSaveCrawlScheduleUpsert(t *testing.T) {
+	db := openDB(t)
+	urls := []model.ProductURL{{Code: "JI2734", URL: "https://www.adidas.jp/samba-og/JI2734.html"}}
+	if err := StoreProductURLs(db, urls); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	first := &model.CrawlSchedule{ProductURLID: urls[0].ID, LastFetchedAt: &now, Interval: time.Hour, NextDueAt: now.Add(time.Hour), FetchCount: 1}
+	if err := SaveCrawlSchedule(db, first); err != nil {
+		t.Fatal(err)
+	}
+	// a second worker saving a schedule for the same URL overwrites it
+	later := now.Add(time.Hour)
+	second := &model.CrawlSchedule{ProductURLID: urls[0].ID, LastFetchedAt: &later, Interval: 2 * time.Hour, NextDueAt: later.Add(2 * time.Hour), FetchCount: 2}
+	for run := 0; run < 2; run++ {
+		if err := SaveCrawlSchedule(db, second); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+	}
+
+	if n := count(t, db, "crawl_schedules"); n != 1 {
+		t.Fatalf("%d crawl_schedules, want 1", n)
+	}
+	stored, err := FindCrawlSchedule(db, urls[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.ID != first.ID || stored.FetchCount != 2 || stored.Interval != 2*time.Hour || !stored.NextDueAt.Equal(later.Add(2*time.Hour)) {
+		t.Errorf("stored %+v, want the second schedule under ID %d", stored, first.ID)
+	}
+}
+
+func TestUpsertProductGroupKeepsFirst(t *testing.T) {
+	db := openDB(t)
+	first := &model.ProductGroup{GroupKey: "IKM07", Name: "Samba OG"}
+	if err := UpsertProductGroup(db, first); err != nil {
+		t.Fatal(err)
+	}
+	for run := 0; run < 2; run++ {
+		again := &model.ProductGroup{GroupKey: "IKM07", Name: "サンバ OG"}
+		if err := UpsertProductGroup(db, again); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+		if again.ID != first.ID {
+			t.Errorf("run %d: ID %d, want %d", run, again.ID, first.ID)
+		}
+	}
+
+	var stored model.ProductGroup
+	if err := db.First(&stored, first.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if n := count(t, db, "product_groups"); n != 1 || stored.Name != "Samba OG" {
+		t.Errorf("%d groups, name %q; want 1 named Samba OG", n, stored.Name)
+	}
+}
+
+func TestStoreProductsBatchAndSearch(t *testing.T) {
+	db := openDB(t)
+	batch := func() []*model.Product {
+		return []*model.Product{{
+			ProductCode: "JI2734", Name: "ＳＡＭＢＡ OG", Category: "スニーカー", PriceYen: 14300,
+			DetailsURL: "https://www.adidas.jp/samba-og/JI2734.html", ContentHash: "h1",
+			TitleDescription: "ﾎﾜｲﾄのレザーアッパー",
+			ProductGroup:     &model.ProductGroup{GroupKey: "IKM07", Name: "Samba OG"},
+			Reviews:          []model.Review{{ReviewDate: time.Now(), Rating: 5, Title: "最高", Body: "ＣＯＭＦＯＲＴＡＢＬＥ"}},
+			AspectRatings:    []model.ReviewAspectRating{{Aspect: "Comfort", Rating: 90}},
+		}}
+	}
+
+	want := []repository.StoreOutcome{repository.Created, repository.Unchanged}
+	for run, w := range want {
+		outcomes, err := StoreProductsBatch(db, batch())
+		if err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+		if outcomes[0] != w {
+			t.Errorf("run %d: outcome %v, want %v", run, outcomes[0], w)
+		}
+	}
+	if n := count(t, db, "review_aspect_ratings"); n != 1 {
+		t.Errorf("%d aspect ratings, want 1", n)
+	}
+
+	for _, q := range []string{"samba", "ホワイト", "comfortable"} {
+		hits, err := SearchProducts(db, search.Terms(q), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hits) != 1 || hits[0].ProductCode != "JI2734" {
+			t.Errorf("search %q = %+v, want JI2734", q, hits)
+		}
+	}
+}
+
+func TestSearchShortTerms(t *testing.T) {
+	db := openDB(t)
+	p := &model.Product{
+		ProductCode: "JI2734", Name: "サンバ OG", PriceYen: 14300,
+		DetailsURL: "https://www.adidas.jp/samba-og/JI2734.html", ContentHash: "h1",
+		TitleDescription: "白のレザーアッパー",
+	}
+	if _, err := StoreProductsBatch(db, []*model.Product{p}); err != nil {
+		t.Fatal(err)
+	}
+	if err := StoreProductKeywords(db, p.ID, keyword.Extract(*p, nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		q    string
+		want int
+	}{
+		{"白", 1},
+		{"OG", 1},
+		{"白 レザー", 1},
+		{"黒", 0},
+		// not a whole keyword
+		{"ンバ", 0},
+	}
+	for _, tt := range tests {
+		hits, err := SearchProducts(db, search.Terms(tt.q), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hits) != tt.want {
+			t.Errorf("search %q = %+v, want %d hits", tt.q, hits, tt.want)
+		}
+	}
+}
+
+func TestStockChanges(t *testing.T) {
+	db := openDB(t)
+	p := &model.Product{ProductCode: "JI2734", Name: "Samba OG", DetailsURL: "https://www.adidas.jp/samba-og/JI2734.html"}
+	if _, err := StoreProductsBatch(db, []*model.Product{p}); err != nil {
+		t.Fatal(err)
+	}
+
+	day := func(d int) time.Time { return time.Date(2024, 5, d, 0, 0, 0, 0, time.UTC) }
+	var obs []model.SizeStockObservation
+	for d, status := range []string{model.StockInStock, model.StockInStock, model.StockOutOfStock, model.StockOutOfStock, model.StockInStock} {
+		obs = append(obs, model.SizeStockObservation{ProductID: p.ID, SizeLabel: "27.0cm", Status: status, ObservedAt: day(d + 1)})
+	}
+	if err := AddStockObservations(db, obs); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := StockChanges(db, 0, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var days []int
+	for _, o := range changes {
+		days = append(days, o.ObservedAt.Day())
+	}
+	if fmt.Sprint(days) != "[1 3 5]" {
+		t.Errorf("changes on days %v, want [1 3 5]", days)
+	}
+	if recent, err := StockChanges(db, p.ID, day(4)); err != nil || len(recent) != 2 {
+		t.Errorf("StockChanges since day 4 = %+v, %v, want 2", recent, err)
+	}
+}
@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/metrics"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StoreProductKeywords replaces the keywords linked to a product, creating
+// keywords that do not exist yet.
+func StoreProductKeywords(db *gorm.DB, productID uint, kws []string) error {
+	defer metrics.ObserveUpsert("product_keywords", time.Now())
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("product_id = ?", productID).Delete(&model.ProductKeyword{}).Error; err != nil {
+			return err
+		}
+		if len(kws) == 0 {
+			return nil
+		}
+
+		rows := make([]model.Keyword, len(kws))
+		for i, kw := range kws {
+			rows[i] = model.Keyword{Kw: kw}
+		}
+		err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "kw"}}, DoNothing: true}).
+			Create(&rows).Error
+		if err != nil {
+			return err
+		}
+
+		// ON CONFLICT DO NOTHING leaves the IDs of existing keywords unset
+		var stored []model.Keyword
+		if err := tx.Where("kw IN ?", kws).Find(&stored).Error; err != nil {
+			return err
+		}
+		links := make([]model.ProductKeyword, len(stored))
+		for i, k := range stored {
+			links[i] = model.ProductKeyword{ProductID: productID, KeywordID: k.ID}
+		}
+		return tx.Create(&links).Error
+	})
+}
+
+// ProductsByKeyword returns up to limit products linked to kw, which must be
+// normalized the way keyword.Normalize does, most recently seen first.
+func ProductsByKeyword(db *gorm.DB, kw string, limit int) ([]model.Product, error) {
+	var products []model.Product
+	err := db.Joins("JOIN product_keywords ON product_keywords.product_id = products.id").
+		Joins("JOIN keywords ON keywords.id = product_keywords.keyword_id").
+		Where("keywords.kw = ?", kw).
+		Order("products.last_seen_at DESC NULLS LAST, products.id").
+		Limit(limit).
+		Find(&products).Error
+	return products, err
+}
+
+// CategoryPathsForProduct returns the listing category paths a product code
+// was discovered under.
+func CategoryPathsForProduct(db *gorm.DB, code string) ([]string, error) {
+	var paths []string
+	err := db.Model(&model.ProductURLSeed{}).
+		Distinct("product_url_seeds.category_path").
+		Joins("JOIN product_urls ON product_urls.id = product_url_seeds.product_url_id").
+		Where("product_urls.code = ? AND product_url_seeds.category_path <> ''", code).
+		Pluck("product_url_seeds.category_path", &paths).Error
+	return paths, err
+}
+
+// ProductsInBatches calls fn with every stored product, size at a time,
+// without their associations.
+func ProductsInBatches(db *gorm.DB, size int, fn func([]model.Product) error) error {
+	var batch []model.Product
+	return db.Order("id").FindInBatches(&batch, size, func(_ *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
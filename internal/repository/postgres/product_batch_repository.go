@@ -0,0 +1,329 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/metrics"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/repository"
+	"gorm.io/gorm"
+)
+
+// stagedProductColumns are the products columns StoreProductsBatch writes,
+// in COPY order. status and status_changed_at are owned by
+// SetProductStatus.
+var stagedProductColumns = []string{
+	"product_code", "name", "category", "price_yen", "sense_of_size", "details_url",
+	"total_reviews", "overall_rating", "title_description", "general_description",
+	"item_general_description", "special_function_description", "color",
+	"product_group_id", "content_hash", "search_name", "search_text", "last_seen_at", "created_at", "updated_at",
+}
+
+// StoreProductsBatch stores many parsed products in one transaction, with
+// the same outcome per product as StoreProductDetail: unchanged products
+// only get last_seen_at bumped, the others are inserted or overwritten
+// together with their images, sizes, reviews, aspect ratings and
+// coordinated items. The ID
+// and Status of every product are filled in; child IDs are not.
+//
+// Products are bulk-loaded with COPY into a temporary staging table and
+// merged with INSERT ... ON CONFLICT; children of written products are
+// replaced with COPY. When a product code occurs more than once, the last
+// one is stored.
+func StoreProductsBatch(db *gorm.DB, products []*model.Product) ([]repository.StoreOutcome, error) {
+	now := time.Now()
+	defer metrics.ObserveUpsert("product_batch", now)
+
+	outcomes := make([]repository.StoreOutcome, len(products))
+	if len(products) == 0 {
+		return outcomes, nil
+	}
+
+	// ON CONFLICT may touch a row only once per statement
+	last := make(map[string]int, len(products))
+	for i, p := range products {
+		last[p.ProductCode] = i
+	}
+	batch := make([]*model.Product, 0, len(last))
+	for i, p := range products {
+		if last[p.ProductCode] == i {
+			batch = append(batch, p)
+		}
+	}
+
+	ctx := db.Statement.Context
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var stored map[string]repository.StoreOutcome
+	err = conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("batch store needs the pgx driver, got %T", driverConn)
+		}
+		return pgx.BeginFunc(ctx, c.Conn(), func(tx pgx.Tx) error {
+			var err error
+			stored, err = storeProductsBatch(ctx, tx, batch, now)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, p := range products {
+		if kept := products[last[p.ProductCode]]; kept != p {
+			p.ID, p.Status = kept.ID, kept.Status
+		}
+		outcomes[i] = stored[p.ProductCode]
+	}
+	return outcomes, nil
+}
+
+func storeProductsBatch(ctx context.Context, tx pgx.Tx, batch []*model.Product, now time.Time) (map[string]repository.StoreOutcome, error) {
+	if err := upsertProductGroups(ctx, tx, batch, now); err != nil {
+		return nil, err
+	}
+
+	_, err := tx.Exec(ctx, `CREATE TEMP TABLE stage_products ON COMMIT DROP AS
+		SELECT `+strings.Join(stagedProductColumns, ", ")+` FROM products WITH NO DATA`)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"stage_products"}, stagedProductColumns,
+		pgx.CopyFromSlice(len(batch), func(i int) ([]any, error) {
+			p := batch[i]
+			fillSearchText(p)
+			var groupID any
+			if p.ProductGroupID != nil {
+				groupID = int64(*p.ProductGroupID)
+			}
+			return []any{
+				p.ProductCode, p.Name, p.Category, p.PriceYen, p.SenseOfSize, p.DetailsURL,
+				p.TotalReviews, p.OverallRating, p.TitleDescription, p.GeneralDescription,
+				p.ItemGeneralDescription, p.SpecialFunctionDescription, p.Color,
+				groupID, p.ContentHash, p.SearchName, p.SearchText, now, now, now,
+			}, nil
+		}))
+	if err != nil {
+		return nil, fmt.Errorf("copy products: %w", err)
+	}
+
+	var updates []string
+	for _, col := range stagedProductColumns {
+		if col != "product_code" && col != "created_at" {
+			updates = append(updates, col+" = EXCLUDED."+col)
+		}
+	}
+	cols := strings.Join(stagedProductColumns, ", ")
+
+	// an unchanged fingerprint skips the update and so the RETURNING row
+	rows, err := tx.Query(ctx, `INSERT INTO products AS p (`+cols+`)
+		SELECT `+cols+` FROM stage_products
+		ON CONFLICT (product_code) DO UPDATE SET `+strings.Join(updates, ", ")+`
+		WHERE EXCLUDED.content_hash = '' OR p.content_hash IS DISTINCT FROM EXCLUDED.content_hash
+		RETURNING p.id, p.product_code, coalesce(p.status, ''), xmax = 0`)
+	if err != nil {
+		return nil, err
+	}
+	byCode := make(map[string]*model.Product, len(batch))
+	for _, p := range batch {
+		byCode[p.ProductCode] = p
+	}
+	outcomes := make(map[string]repository.StoreOutcome, len(batch))
+	// an empty rather than nil slice: <> ALL(NULL) matches nothing
+	writtenIDs, writtenCodes := []int64{}, []string{}
+	for rows.Next() {
+		var (
+			id           int64
+			code, status string
+			inserted     bool
+		)
+		if err := rows.Scan(&id, &code, &status, &inserted); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		p := byCode[code]
+		p.ID, p.Status = uint(id), status
+		p.LastSeenAt, p.UpdatedAt = &now, now
+		outcomes[code] = repository.Updated
+		if inserted {
+			outcomes[code] = repository.Created
+			p.CreatedAt = now
+		}
+		writtenIDs = append(writtenIDs, id)
+		writtenCodes = append(writtenCodes, code)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.Query(ctx, `UPDATE products AS p SET last_seen_at = s.last_seen_at
+		FROM stage_products AS s
+		WHERE p.product_code = s.product_code AND p.product_code <> ALL($1)
+		RETURNING p.id, p.product_code, coalesce(p.status, '')`, writtenCodes)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var (
+			id           int64
+			code, status string
+		)
+		if err := rows.Scan(&id, &code, &status); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		p := byCode[code]
+		p.ID, p.Status = uint(id), status
+		outcomes[code] = repository.Unchanged
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(writtenIDs) == 0 {
+		return outcomes, nil
+	}
+	written := make([]*model.Product, 0, len(writtenCodes))
+	for _, code := range writtenCodes {
+		written = append(written, byCode[code])
+	}
+	return outcomes, replaceProductChildren(ctx, tx, writtenIDs, written)
+}
+
+// upsertProductGroups creates the groups of the batch that do not exist yet
+// and sets ProductGroupID from their IDs.
+func upsertProductGroups(ctx context.Context, tx pgx.Tx, batch []*model.Product, now time.Time) error {
+	var keys, names []string
+	seen := map[string]bool{}
+	for _, p := range batch {
+		if g := p.ProductGroup; g != nil && !seen[g.GroupKey] {
+			seen[g.GroupKey] = true
+			keys = append(keys, g.GroupKey)
+			names = append(names, g.Name)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(ctx, `INSERT INTO product_groups (group_key, name, created_at, updated_at)
+		SELECT k, n, $3::timestamp, $3::timestamp FROM unnest($1::text[], $2::text[]) AS g(k, n)
+		ON CONFLICT (group_key) DO NOTHING`, keys, names, now)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(ctx, `SELECT id, group_key FROM product_groups WHERE group_key = ANY($1)`, keys)
+	if err != nil {
+		return err
+	}
+	ids := make(map[string]uint, len(keys))
+	for rows.Next() {
+		var (
+			id  int64
+			key string
+		)
+		if err := rows.Scan(&id, &key); err != nil {
+			rows.Close()
+			return err
+		}
+		ids[key] = uint(id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, p := range batch {
+		if p.ProductGroup != nil {
+			id := ids[p.ProductGroup.GroupKey]
+			p.ProductGroup.ID = id
+			p.ProductGroupID = &id
+		}
+	}
+	return nil
+}
+
+// replaceProductChildren deletes the images, sizes, reviews, aspect ratings
+// and coordinated items of the written products and copies in their new
+// ones.
+func replaceProductChildren(ctx context.Context, tx pgx.Tx, ids []int64, written []*model.Product) error {
+	for _, stmt := range []string{
+		`DELETE FROM product_images WHERE product_id = ANY($1)`,
+		`DELETE FROM product_sizes WHERE product_id = ANY($1)`,
+		`DELETE FROM review_aspect_ratings WHERE product_id = ANY($1)`,
+		`DELETE FROM reviews WHERE product_id = ANY($1)`,
+		`DELETE FROM coordinated_items WHERE source_product_id = ANY($1)`,
+	} {
+		if _, err := tx.Exec(ctx, stmt, ids); err != nil {
+			return err
+		}
+	}
+
+	var images, sizes, reviews, aspects, coordinated [][]any
+	for _, p := range written {
+		id := int64(p.ID)
+		for i := range p.Images {
+			img := &p.Images[i]
+			img.ID, img.ProductID = 0, p.ID
+			images = append(images, []any{id, img.URL, img.IsMain})
+		}
+		for i := range p.Sizes {
+			s := &p.Sizes[i]
+			s.ID, s.ProductID = 0, p.ID
+			sizes = append(sizes, []any{id, s.SizeLabel, s.ChestCM, s.Availability, s.BackLengthCM, s.OtherMeasurements, s.SpecialFunctions})
+		}
+		for i := range p.Reviews {
+			r := &p.Reviews[i]
+			r.ID, r.ProductID = 0, p.ID
+			reviews = append(reviews, []any{id, r.ReviewDate, r.Rating, r.OverallRating, r.Title, r.Body, r.SearchText})
+		}
+		for i := range p.AspectRatings {
+			a := &p.AspectRatings[i]
+			a.ID, a.ProductID = 0, p.ID
+			aspects = append(aspects, []any{id, a.Aspect, a.Rating})
+		}
+		for i := range p.Coordinated {
+			c := &p.Coordinated[i]
+			c.ID, c.SourceProductID = 0, p.ID
+			coordinated = append(coordinated, []any{id, c.ProductNumber, c.Name, c.PriceYen, c.ImageURL, c.ProductPageURL})
+		}
+	}
+
+	copies := []struct {
+		table   string
+		columns []string
+		rows    [][]any
+	}{
+		{"product_images", []string{"product_id", "url", "is_main"}, images},
+		{"product_sizes", []string{"product_id", "size_label", "chest_cm", "availability", "back_length_cm", "other_measurements", "special_functions"}, sizes},
+		{"reviews", []string{"product_id", "review_date", "rating", "overall_rating", "title", "body", "search_text"}, reviews},
+		{"review_aspect_ratings", []string{"product_id", "aspect", "rating"}, aspects},
+		{"coordinated_items", []string{"source_product_id", "product_number", "name", "price_yen", "image_url", "product_page_url"}, coordinated},
+	}
+	for _, c := range copies {
+		if len(c.rows) == 0 {
+			continue
+		}
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{c.table}, c.columns, pgx.CopyFromRows(c.rows)); err != nil {
+			return fmt.Errorf("copy %s: %w", c.table, err)
+		}
+	}
+	return nil
+}
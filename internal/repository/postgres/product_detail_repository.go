@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/metrics"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/search"
+	"gorm.io/gorm"
+)
+
+// StoreProductDetail upserts a parsed product with its images, sizes,
+// reviews, aspect ratings and coordinated items. When the stored
+// ContentHash matches p.ContentHash nothing is rewritten, only last_seen_at
+// is bumped, and changed is false. created is set when the product was not
+// stored before.
+func StoreProductDetail(db *gorm.DB, p *model.Product) (created, changed bool, err error) {
+	now := time.Now()
+	defer metrics.ObserveUpsert("product_detail", now)
+
+	var existing model.Product
+	err = db.Select("id", "content_hash", "status").Where("product_code = ?", p.ProductCode).First(&existing).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return false, false, err
+	}
+	created = err == gorm.ErrRecordNotFound
+	p.Status = existing.Status
+	if !created && p.ContentHash != "" && existing.ContentHash == p.ContentHash {
+		p.ID = existing.ID
+		return false, false, db.Model(&existing).Update("last_seen_at", now).Error
+	}
+
+	p.ID = existing.ID
+	p.LastSeenAt = &now
+	fillSearchText(p)
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if p.ProductGroup != nil {
+			if err := UpsertProductGroup(tx, p.ProductGroup); err != nil {
+				return err
+			}
+			p.ProductGroupID = &p.ProductGroup.ID
+		}
+
+		if p.ID != 0 {
+			if err := deleteProductChildren(tx, p.ID); err != nil {
+				return err
+			}
+		}
+		resetChildIDs(p)
+
+		// status is owned by SetProductStatus
+		return tx.Omit("ProductGroup", "Keywords", "Status", "StatusChangedAt").Save(p).Error
+	})
+	return created, err == nil, err
+}
+
+// deleteProductChildren removes the rows that StoreProductDetail rewrites.
+func deleteProductChildren(tx *gorm.DB, productID uint) error {
+	for _, m := range []interface{}{&model.ProductImage{}, &model.ProductSize{}, &model.ReviewAspectRating{}, &model.Review{}} {
+		if err := tx.Where("product_id = ?", productID).Delete(m).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Where("source_product_id = ?", productID).Delete(&model.CoordinatedItem{}).Error
+}
+
+// fillSearchText sets the search_name and search_text of p and the
+// search_text of its reviews, the text SearchProducts matches folded terms
+// against.
+func fillSearchText(p *model.Product) {
+	p.SearchName = search.Fold(p.Name)
+	p.SearchText = search.Fold(p.Name + " " + p.TitleDescription + " " + p.GeneralDescription)
+	for i := range p.Reviews {
+		r := &p.Reviews[i]
+		r.SearchText = search.Fold(r.Title + " " + r.Body)
+	}
+}
+
+func resetChildIDs(p *model.Product) {
+	for i := range p.Images {
+		p.Images[i].ID, p.Images[i].ProductID = 0, 0
+	}
+	for i := range p.Sizes {
+		p.Sizes[i].ID, p.Sizes[i].ProductID = 0, 0
+	}
+	for i := range p.Reviews {
+		p.Reviews[i].ID, p.Reviews[i].ProductID = 0, 0
+	}
+	for i := range p.AspectRatings {
+		p.AspectRatings[i].ID, p.AspectRatings[i].ProductID = 0, 0
+	}
+	for i := range p.Coordinated {
+		p.Coordinated[i].ID, p.Coordinated[i].SourceProductID = 0, 0
+	}
+}
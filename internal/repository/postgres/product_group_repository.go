@@ -0,0 +1,29 @@
+package postgres
+
+import (
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UpsertProductGroup creates the group unless one with its GroupKey exists
+// and fills in g.ID. The name of an existing group is kept.
+func UpsertProductGroup(db *gorm.DB, g *model.ProductGroup) error {
+	g.ID = 0
+	// the no-op update makes RETURNING report the existing row's ID
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "group_key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"group_key": gorm.Expr("EXCLUDED.group_key")}),
+	}).Create(g).Error
+}
+
+// FindProductVariants returns all colorways of the model the given product
+// code belongs to, including the product itself.
+func FindProductVariants(db *gorm.DB, productCode string) ([]model.Product, error) {
+	var products []model.Product
+	groupID := db.Model(&model.Product{}).Select("product_group_id").Where("product_code = ?", productCode)
+	err := db.Where("product_group_id = (?)", groupID).
+		Order("product_code").
+		Find(&products).Error
+	return products, err
+}
@@ -0,0 +1,29 @@
+package postgres
+
+import (
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"gorm.io/gorm"
+)
+
+// AddProductSnapshot appends a snapshot of a product's attributes.
+func AddProductSnapshot(db *gorm.DB, s *model.ProductSnapshot) error {
+	return db.Create(s).Error
+}
+
+// ProductSnapshots returns a product's snapshots, oldest first.
+func ProductSnapshots(db *gorm.DB, productID uint) ([]model.ProductSnapshot, error) {
+	var snapshots []model.ProductSnapshot
+	err := db.Where("product_id = ?", productID).Order("captured_at, id").Find(&snapshots).Error
+	return snapshots, err
+}
+
+// LatestProductSnapshot returns the most recently stored snapshot of a
+// product, or gorm.ErrRecordNotFound if it has none.
+func LatestProductSnapshot(db *gorm.DB, productID uint) (*model.ProductSnapshot, error) {
+	var s model.ProductSnapshot
+	err := db.Where("product_id = ?", productID).Order("id DESC").Take(&s).Error
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/lifecycle"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"gorm.io/gorm"
+)
+
+// SetProductStatus moves a product from one lifecycle status to another and
+// appends the transition to product_status_history.
+func SetProductStatus(db *gorm.DB, productID uint, from, to, reason string) error {
+	now := time.Now()
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.Product{}).Where("id = ?", productID).
+			Updates(map[string]interface{}{"status": to, "status_changed_at": now}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&model.ProductStatusChange{
+			ProductID:  productID,
+			FromStatus: from,
+			ToStatus:   to,
+			Reason:     reason,
+			ChangedAt:  now,
+		}).Error
+	})
+}
+
+// ProductStatusHistory returns a product's lifecycle transitions, oldest first.
+func ProductStatusHistory(db *gorm.DB, productID uint) ([]model.ProductStatusChange, error) {
+	var history []model.ProductStatusChange
+	err := db.Where("product_id = ?", productID).Order("changed_at").Find(&history).Error
+	return history, err
+}
+
+// MissingFromListings returns URLs of stored, not yet delisted products that
+// were found under one of seeds before but not listed since the given time.
+func MissingFromListings(db *gorm.DB, seeds []string, since time.Time, limit int) ([]model.ProductURL, error) {
+	var urls []model.ProductURL
+	err := db.Model(&model.ProductURL{}).
+		Distinct("product_urls.*").
+		Joins("JOIN product_url_seeds ON product_url_seeds.product_url_id = product_urls.id").
+		Joins("JOIN products ON products.product_code = product_urls.code").
+		Where("product_url_seeds.seed IN ?", seeds).
+		Where("product_urls.last_listed_at IS NULL OR product_urls.last_listed_at < ?", since).
+		Where("products.status IS DISTINCT FROM ?", lifecycle.StatusDelisted).
+		Limit(limit).
+		Find(&urls).Error
+	return urls, err
+}
+
+// FindProductByCode returns the stored product with the given code.
+func FindProductByCode(db *gorm.DB, code string) (*model.Product, error) {
+	var p model.Product
+	if err := db.Where("product_code = ?", code).First(&p).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// FindProductsByCodes returns the stored products among the given codes,
+// without details and in no particular order.
+func FindProductsByCodes(db *gorm.DB, codes []string) ([]model.Product, error) {
+	if len(codes) == 0 {
+		return nil, nil
+	}
+	var products []model.Product
+	err := db.Where("product_code IN ?", codes).Find(&products).Error
+	return products, err
+}
+
+// ListProducts returns the products matching f, ordered by product code.
+func ListProducts(db *gorm.DB, f model.ProductFilter) ([]model.Product, error) {
+	q := db.Model(&model.Product{})
+	if f.Category != "" {
+		q = q.Where(`products.category LIKE ? ESCAPE '\'`, "%"+escapeLike(f.Category)+"%")
+	}
+	if f.Status != "" {
+		q = q.Where("products.status = ?", f.Status)
+	}
+	if f.GroupKey != "" {
+		q = q.Joins("JOIN product_groups ON product_groups.id = products.product_group_id").
+			Where("product_groups.group_key = ?", f.GroupKey)
+	}
+	if f.MinPrice > 0 {
+		q = q.Where("products.price_yen >= ?", f.MinPrice)
+	}
+	if f.MaxPrice > 0 {
+		q = q.Where("products.price_yen <= ?", f.MaxPrice)
+	}
+	if f.Limit > 0 {
+		q = q.Limit(f.Limit)
+	}
+	if f.Offset > 0 {
+		q = q.Offset(f.Offset)
+	}
+	if f.WithDetails {
+		q = q.Preload("Images").Preload("Sizes").Preload("Reviews").Preload("AspectRatings").Preload("Coordinated")
+	}
+
+	var products []model.Product
+	err := q.Order("products.product_code").Find(&products).Error
+	return products, err
+}
@@ -1,21 +1,85 @@
 package postgres
 
 import (
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/metrics"
 	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// upsertBatchSize bounds the rows of one multi-row INSERT, well below
+// Postgres' limit of 65535 bind parameters.
+const upsertBatchSize = 500
+
+// StoreProductURLs inserts newly discovered URLs. URLs that already exist
+// only get the seeds they were not yet recorded under and fresh lastmod and
+// last-listed timestamps, if set. The ID of every entry is filled in.
+//
+// URLs and seeds are each written with batched INSERT ... ON CONFLICT
+// statements, so storing a listing costs a few round trips however many
+// URLs it has.
 func StoreProductURLs(db *gorm.DB, entries []model.ProductURL) error {
+	defer metrics.ObserveUpsert("product_urls", time.Now())
+	if len(entries) == 0 {
+		return nil
+	}
+
+	// a row may only be upserted once per statement, so merge duplicates
+	index := make(map[string]int, len(entries))
+	var rows []model.ProductURL
 	for _, p := range entries {
-		var existing model.ProductURL
-		err := db.Where("url = ?", p.URL).First(&existing).Error
-		if err == gorm.ErrRecordNotFound {
-			if err := db.Create(&p).Error; err != nil {
-				return err
+		i, ok := index[p.URL]
+		if !ok {
+			index[p.URL] = len(rows)
+			p.ID = 0
+			p.Seeds = nil
+			rows = append(rows, p)
+			continue
+		}
+		if p.LastModified != nil {
+			rows[i].LastModified = p.LastModified
+		}
+		if p.LastListedAt != nil {
+			rows[i].LastListedAt = p.LastListedAt
+		}
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "url"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"last_modified":  gorm.Expr("COALESCE(EXCLUDED.last_modified, product_urls.last_modified)"),
+				"last_listed_at": gorm.Expr("COALESCE(EXCLUDED.last_listed_at, product_urls.last_listed_at)"),
+			}),
+		}).Omit(clause.Associations).CreateInBatches(&rows, upsertBatchSize).Error
+		if err != nil {
+			return err
+		}
+
+		// RETURNING yields the ID of inserted and updated rows alike
+		var seeds []model.ProductURLSeed
+		for i := range entries {
+			p := &entries[i]
+			p.ID = rows[index[p.URL]].ID
+			for _, s := range p.Seeds {
+				s.ID = 0
+				s.ProductURLID = p.ID
+				seeds = append(seeds, s)
 			}
-		} else if err != nil {
-			return err // other errors
 		}
+		return storeProductURLSeeds(tx, seeds)
+	})
+}
+
+// storeProductURLSeeds records the seeds a URL was not yet listed under.
+func storeProductURLSeeds(db *gorm.DB, seeds []model.ProductURLSeed) error {
+	if len(seeds) == 0 {
+		return nil
 	}
-	return nil
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "product_url_id"}, {Name: "seed"}},
+		DoNothing: true,
+	}).CreateInBatches(&seeds, upsertBatchSize).Error
 }
@@ -0,0 +1,195 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/repository"
+	"gorm.io/gorm"
+)
+
+// notFound maps gorm's not-found error to repository.ErrNotFound.
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return repository.ErrNotFound
+	}
+	return err
+}
+
+// Products implements service.ProductRepository on top of the
+// functions in this package.
+type Products struct {
+	db *gorm.DB
+}
+
+func NewProducts(db *gorm.DB) *Products {
+	return &Products{db: db}
+}
+
+func (r *Products) Store(ctx context.Context, p *model.Product) (created, changed bool, err error) {
+	return StoreProductDetail(r.db.WithContext(ctx), p)
+}
+
+func (r *Products) StoreBatch(ctx context.Context, products []*model.Product) ([]repository.StoreOutcome, error) {
+	return StoreProductsBatch(r.db.WithContext(ctx), products)
+}
+
+func (r *Products) FindByCode(ctx context.Context, code string) (*model.Product, error) {
+	p, err := FindProductByCode(r.db.WithContext(ctx), code)
+	return p, notFound(err)
+}
+
+func (r *Products) FindByCodes(ctx context.Context, codes []string) ([]model.Product, error) {
+	return FindProductsByCodes(r.db.WithContext(ctx), codes)
+}
+
+func (r *Products) List(ctx context.Context, f model.ProductFilter) ([]model.Product, error) {
+	return ListProducts(r.db.WithContext(ctx), f)
+}
+
+func (r *Products) InBatches(ctx context.Context, size int, fn func([]model.Product) error) error {
+	return ProductsInBatches(r.db.WithContext(ctx), size, fn)
+}
+
+func (r *Products) Variants(ctx context.Context, code string) ([]model.Product, error) {
+	return FindProductVariants(r.db.WithContext(ctx), code)
+}
+
+func (r *Products) SetStatus(ctx context.Context, productID uint, from, to, reason string) error {
+	return SetProductStatus(r.db.WithContext(ctx), productID, from, to, reason)
+}
+
+func (r *Products) StatusHistory(ctx context.Context, productID uint) ([]model.ProductStatusChange, error) {
+	return ProductStatusHistory(r.db.WithContext(ctx), productID)
+}
+
+func (r *Products) AddSnapshot(ctx context.Context, s *model.ProductSnapshot) error {
+	return AddProductSnapshot(r.db.WithContext(ctx), s)
+}
+
+func (r *Products) Snapshots(ctx context.Context, productID uint) ([]model.ProductSnapshot, error) {
+	return ProductSnapshots(r.db.WithContext(ctx), productID)
+}
+
+func (r *Products) LatestSnapshot(ctx context.Context, productID uint) (*model.ProductSnapshot, error) {
+	s, err := LatestProductSnapshot(r.db.WithContext(ctx), productID)
+	return s, notFound(err)
+}
+
+func (r *Products) AddStockObservations(ctx context.Context, obs []model.SizeStockObservation) error {
+	return AddStockObservations(r.db.WithContext(ctx), obs)
+}
+
+func (r *Products) StockChanges(ctx context.Context, productID uint, since time.Time) ([]model.SizeStockObservation, error) {
+	return StockChanges(r.db.WithContext(ctx), productID, since)
+}
+
+func (r *Products) StockSizeLabels(ctx context.Context, productID uint) ([]string, error) {
+	return StockSizeLabels(r.db.WithContext(ctx), productID)
+}
+
+func (r *Products) StoreCoordinations(ctx context.Context, edges []model.CoordinationEdge) error {
+	return StoreCoordinationEdges(r.db.WithContext(ctx), edges)
+}
+
+func (r *Products) Coordinations(ctx context.Context, code string) ([]model.CoordinationEdge, error) {
+	return CoordinationNeighborhood(r.db.WithContext(ctx), code)
+}
+
+func (r *Products) SetKeywords(ctx context.Context, productID uint, kws []string) error {
+	return StoreProductKeywords(r.db.WithContext(ctx), productID, kws)
+}
+
+func (r *Products) ByKeyword(ctx context.Context, kw string, limit int) ([]model.Product, error) {
+	return ProductsByKeyword(r.db.WithContext(ctx), kw, limit)
+}
+
+func (r *Products) CategoryPaths(ctx context.Context, code string) ([]string, error) {
+	return CategoryPathsForProduct(r.db.WithContext(ctx), code)
+}
+
+func (r *Products) Search(ctx context.Context, terms []string, limit int) ([]model.ProductSearchHit, error) {
+	return SearchProducts(r.db.WithContext(ctx), terms, limit)
+}
+
+// ProductURLs implements service.ProductURLRepository.
+type ProductURLs struct {
+	db *gorm.DB
+}
+
+func NewProductURLs(db *gorm.DB) *ProductURLs {
+	return &ProductURLs{db: db}
+}
+
+func (r *ProductURLs) Store(ctx context.Context, urls []model.ProductURL) error {
+	return StoreProductURLs(r.db.WithContext(ctx), urls)
+}
+
+func (r *ProductURLs) Due(ctx context.Context, now time.Time, budget int) ([]model.ProductURL, error) {
+	return DueProductURLs(r.db.WithContext(ctx), now, budget)
+}
+
+func (r *ProductURLs) MissingFromListings(ctx context.Context, seeds []string, since time.Time, limit int) ([]model.ProductURL, error) {
+	return MissingFromListings(r.db.WithContext(ctx), seeds, since, limit)
+}
+
+func (r *ProductURLs) Schedule(ctx context.Context, productURLID uint) (*model.CrawlSchedule, error) {
+	return FindCrawlSchedule(r.db.WithContext(ctx), productURLID)
+}
+
+func (r *ProductURLs) SaveSchedule(ctx context.Context, s *model.CrawlSchedule) error {
+	return SaveCrawlSchedule(r.db.WithContext(ctx), s)
+}
+
+// CrawlRuns implements service.CrawlRunRepository.
+type CrawlRuns struct {
+	db *gorm.DB
+}
+
+func NewCrawlRuns(db *gorm.DB) *CrawlRuns {
+	return &CrawlRuns{db: db}
+}
+
+func (r *CrawlRuns) Create(ctx context.Context, run *model.CrawlRun) error {
+	return CreateCrawlRun(r.db.WithContext(ctx), run)
+}
+
+func (r *CrawlRuns) Save(ctx context.Context, run *model.CrawlRun) error {
+	return SaveCrawlRun(r.db.WithContext(ctx), run)
+}
+
+func (r *CrawlRuns) List(ctx context.Context, limit int) ([]model.CrawlRun, error) {
+	return ListCrawlRuns(r.db.WithContext(ctx), limit)
+}
+
+func (r *CrawlRuns) Find(ctx context.Context, id uint) (*model.CrawlRun, error) {
+	run, err := FindCrawlRun(r.db.WithContext(ctx), id)
+	return run, notFound(err)
+}
+
+// Reviews implements service.ReviewRepository.
+type Reviews struct {
+	db *gorm.DB
+}
+
+func NewReviews(db *gorm.DB) *Reviews {
+	return &Reviews{db: db}
+}
+
+func (r *Reviews) ForProduct(ctx context.Context, productID uint) ([]model.Review, error) {
+	return ReviewsForProduct(r.db.WithContext(ctx), productID)
+}
+
+func (r *Reviews) ReplaceTags(ctx context.Context, reviewIDs []uint, tags []model.ReviewTag) error {
+	return ReplaceReviewTags(r.db.WithContext(ctx), reviewIDs, tags)
+}
+
+func (r *Reviews) TagCounts(ctx context.Context, productID uint) ([]model.ReviewTagCount, error) {
+	return ReviewTagCounts(r.db.WithContext(ctx), productID)
+}
+
+func (r *Reviews) TopicSentimentCounts(ctx context.Context, productID uint) ([]model.TopicSentimentCount, error) {
+	return TopicSentimentCounts(r.db.WithContext(ctx), productID)
+}
@@ -1 +1,59 @@
 package postgres
+
+import (
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"gorm.io/gorm"
+)
+
+// ReviewsForProduct returns a product's stored reviews, newest first.
+func ReviewsForProduct(db *gorm.DB, productID uint) ([]model.Review, error) {
+	var reviews []model.Review
+	err := db.Where("product_id = ?", productID).Order("review_date DESC, id").Find(&reviews).Error
+	return reviews, err
+}
+
+// ReplaceReviewTags drops every tag of the given reviews and stores tags in
+// their place.
+func ReplaceReviewTags(db *gorm.DB, reviewIDs []uint, tags []model.ReviewTag) error {
+	if len(reviewIDs) == 0 {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("review_id IN ?", reviewIDs).Delete(&model.ReviewTag{}).Error; err != nil {
+			return err
+		}
+		if len(tags) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(tags, 500).Error
+	})
+}
+
+// ReviewTagCounts aggregates the tags of a product's reviews by kind and
+// value.
+func ReviewTagCounts(db *gorm.DB, productID uint) ([]model.ReviewTagCount, error) {
+	var counts []model.ReviewTagCount
+	err := db.Model(&model.ReviewTag{}).
+		Select("review_tags.kind, review_tags.value, count(DISTINCT review_tags.review_id) AS reviews, avg(review_tags.score) AS avg_score").
+		Joins("JOIN reviews ON reviews.id = review_tags.review_id").
+		Where("reviews.product_id = ?", productID).
+		Group("review_tags.kind, review_tags.value").
+		Order("reviews DESC, review_tags.value").
+		Scan(&counts).Error
+	return counts, err
+}
+
+// TopicSentimentCounts breaks down a product's reviews per topic by the
+// sentiment of the same review.
+func TopicSentimentCounts(db *gorm.DB, productID uint) ([]model.TopicSentimentCount, error) {
+	var counts []model.TopicSentimentCount
+	err := db.Table("review_tags AS topic").
+		Select("topic.value AS topic, sentiment.value AS sentiment, count(*) AS reviews").
+		Joins("JOIN review_tags AS sentiment ON sentiment.review_id = topic.review_id AND sentiment.kind = ?", model.ReviewTagSentiment).
+		Joins("JOIN reviews ON reviews.id = topic.review_id").
+		Where("topic.kind = ? AND reviews.product_id = ?", model.ReviewTagTopic, productID).
+		Group("topic.value, sentiment.value").
+		Order("topic.value, sentiment.value").
+		Scan(&counts).Error
+	return counts, err
+}
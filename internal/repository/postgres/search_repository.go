@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"gorm.io/gorm"
+)
+
+// SearchProducts returns up to limit products in which every term occurs,
+// either in the name and descriptions or in one of the product's reviews,
+// best match first. Terms must already be folded by search.Fold, as the
+// search_text columns are (see migration 0017).
+//
+// A term scores highest in the name, then in the descriptions, then by how
+// many reviews mention it; word_similarity breaks ties towards whole-word
+// matches.
+//
+// A term shorter than minTrigramTerm, such as "白" or "og", has no trigram
+// the index could look up, and LIKE would scan every product and review.
+// Such a term instead matches products that have it as a whole keyword
+// (see keyword.Tokenize), so it finds no substrings of longer words and is
+// not looked up in reviews.
+func SearchProducts(db *gorm.DB, terms []string, limit int) ([]model.ProductSearchHit, error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	var (
+		where, score, anyReview []string
+		whereArgs, scoreArgs    []interface{}
+		anyReviewArgs           []interface{}
+	)
+	for _, term := range terms {
+		pattern := "%" + escapeLike(term) + "%"
+		if utf8.RuneCountInString(term) < minTrigramTerm {
+			where = append(where, `p.id IN (SELECT pk.product_id FROM product_keywords pk
+				JOIN keywords k ON k.id = pk.keyword_id WHERE k.kw = ?)`)
+			whereArgs = append(whereArgs, term)
+		} else {
+			where = append(where, `(p.search_text LIKE ? OR EXISTS (
+			SELECT 1 FROM reviews r WHERE r.product_id = p.id AND r.search_text LIKE ?))`)
+			whereArgs = append(whereArgs, pattern, pattern)
+		}
+
+		score = append(score, `(CASE WHEN p.search_name LIKE ? THEN 3 ELSE 0 END
+			+ CASE WHEN p.search_text LIKE ? THEN 1 ELSE 0 END
+			+ word_similarity(?, p.search_text)
+			+ 0.2 * LEAST((SELECT count(*) FROM reviews r WHERE r.product_id = p.id AND r.search_text LIKE ?), 5))`)
+		scoreArgs = append(scoreArgs, pattern, pattern, term, pattern)
+
+		anyReview = append(anyReview, "r.search_text LIKE ?")
+		anyReviewArgs = append(anyReviewArgs, pattern)
+	}
+	reviewMatch := strings.Join(anyReview, " OR ")
+
+	query := `SELECT p.id AS product_id, p.product_code, p.name, p.title_description,
+			p.general_description, p.price_yen, p.details_url,
+			(SELECT count(*) FROM reviews r WHERE r.product_id = p.id AND (` + reviewMatch + `)) AS review_matches,
+			coalesce((SELECT r.body FROM reviews r WHERE r.product_id = p.id AND (` + reviewMatch + `)
+				ORDER BY r.review_date DESC LIMIT 1), '') AS review_body,
+			` + strings.Join(score, " + ") + ` AS score
+		FROM products p
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY score DESC, p.id
+		LIMIT ?`
+
+	var args []interface{}
+	args = append(args, anyReviewArgs...)
+	args = append(args, anyReviewArgs...)
+	args = append(args, scoreArgs...)
+	args = append(args, whereArgs...)
+	args = append(args, limit)
+
+	var hits []model.ProductSearchHit
+	err := db.Raw(query, args...).Scan(&hits).Error
+	return hits, err
+}
+
+// minTrigramTerm is the length in characters below which a LIKE pattern
+// contains no complete trigram.
+const minTrigramTerm = 3
+
+// escapeLike escapes the LIKE wildcards in s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/metrics"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"gorm.io/gorm"
+)
+
+// AddStockObservations appends per-size stock observations.
+func AddStockObservations(db *gorm.DB, obs []model.SizeStockObservation) error {
+	defer metrics.ObserveUpsert("stock_observations", time.Now())
+	if len(obs) == 0 {
+		return nil
+	}
+	return db.CreateInBatches(&obs, upsertBatchSize).Error
+}
+
+// StockChanges returns the stock observations of a product, or of every
+// product if productID is 0, that differ in status from the observation
+// before them of the same size: the first observation of each size and
+// every change after it. Observations before since are left out unless
+// since is zero. Rows are ordered by product, size and time.
+func StockChanges(db *gorm.DB, productID uint, since time.Time) ([]model.SizeStockObservation, error) {
+	window := db.Model(&model.SizeStockObservation{}).
+		Select("*, LAG(status) OVER (PARTITION BY product_id, size_label ORDER BY observed_at, id) AS prev_status")
+	if productID != 0 {
+		window = window.Where("product_id = ?", productID)
+	}
+	if !since.IsZero() {
+		window = window.Where("observed_at >= ?", since)
+	}
+	var obs []model.SizeStockObservation
+	err := db.Table("(?) AS o", window).
+		Select("id, product_id, size_label, status, quantity, observed_at").
+		Where("prev_status IS NULL OR prev_status <> status").
+		Order("product_id, size_label, observed_at, id").
+		Find(&obs).Error
+	return obs, err
+}
+
+// StockSizeLabels returns every size label ever observed for a product.
+func StockSizeLabels(db *gorm.DB, productID uint) ([]string, error) {
+	var labels []string
+	err := db.Model(&model.SizeStockObservation{}).
+		Where("product_id = ?", productID).
+		Distinct().Order("size_label").
+		Pluck("size_label", &labels).Error
+	return labels, err
+}
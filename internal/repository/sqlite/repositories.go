@@ -0,0 +1,64 @@
+// Package sqlite implements the service repositories on an SQLite
+// database migrated with migrations/sqlite. Most queries go through GORM
+// and are portable, so the Postgres implementations are reused for them;
+// only what relies on Postgres extensions is rewritten here.
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/repository"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/repository/postgres"
+	"gorm.io/gorm"
+)
+
+// Products implements service.ProductRepository. Search does without
+// pg_trgm and StoreBatch without COPY.
+type Products struct {
+	*postgres.Products
+	db *gorm.DB
+}
+
+func NewProducts(db *gorm.DB) *Products {
+	return &Products{Products: postgres.NewProducts(db), db: db}
+}
+
+func (r *Products) Search(ctx context.Context, terms []string, limit int) ([]model.ProductSearchHit, error) {
+	return SearchProducts(r.db.WithContext(ctx), terms, limit)
+}
+
+// StoreBatch stores the products one by one in a single transaction.
+func (r *Products) StoreBatch(ctx context.Context, products []*model.Product) ([]repository.StoreOutcome, error) {
+	outcomes := make([]repository.StoreOutcome, len(products))
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i, p := range products {
+			created, changed, err := postgres.StoreProductDetail(tx, p)
+			if err != nil {
+				return fmt.Errorf("product %s: %w", p.ProductCode, err)
+			}
+			outcomes[i] = repository.OutcomeOf(created, changed)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return outcomes, nil
+}
+
+// NewProductURLs returns the service.ProductURLRepository for SQLite.
+func NewProductURLs(db *gorm.DB) *postgres.ProductURLs {
+	return postgres.NewProductURLs(db)
+}
+
+// NewCrawlRuns returns the service.CrawlRunRepository for SQLite.
+func NewCrawlRuns(db *gorm.DB) *postgres.CrawlRuns {
+	return postgres.NewCrawlRuns(db)
+}
+
+// NewReviews returns the service.ReviewRepository for SQLite.
+func NewReviews(db *gorm.DB) *postgres.Reviews {
+	return postgres.NewReviews(db)
+}
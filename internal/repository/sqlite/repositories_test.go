@@ -0,0 +1,427 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/config"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/database"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/lifecycle"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/repository"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/repository/sqlite"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// openDB returns a freshly migrated in-memory database.
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := database.Open(&config.Config{DBDriver: "sqlite", DBPath: ":memory:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func product(code, name string) *model.Product {
+	return &model.Product{
+		ProductCode: code,
+		Name:        name,
+		Category:    "スニーカー",
+		PriceYen:    14300,
+		DetailsURL:  "https://www.adidas.jp/" + code + ".html",
+	}
+}
+
+func TestProductsStoreBatch(t *testing.T) {
+	ctx := context.Background()
+	products := sqlite.NewProducts(openDB(t))
+
+	batch := func(gazelleHash string) []*model.Product {
+		samba := product("JI2734", "Samba OG")
+		samba.ContentHash = "h1"
+		samba.ProductGroup = &model.ProductGroup{GroupKey: "IKM07", Name: "Samba OG"}
+		samba.Images = []model.ProductImage{{URL: "https://assets.adidas.com/JI2734.jpg", IsMain: true}}
+		samba.Sizes = []model.ProductSize{{SizeLabel: "27.0cm", Availability: 3}}
+		samba.Reviews = []model.Review{{ReviewDate: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Rating: 5, Body: "good"}}
+		samba.AspectRatings = []model.ReviewAspectRating{{Aspect: "Comfort", Rating: 90}}
+		samba.Coordinated = []model.CoordinatedItem{{ProductNumber: "IE3437", Name: "Gazelle"}}
+		black := product("B75807", "Samba OG")
+		black.ContentHash = "h2"
+		black.ProductGroup = &model.ProductGroup{GroupKey: "IKM07", Name: "サンバ OG"}
+		gazelle := product("IE3437", "Gazelle")
+		gazelle.ContentHash = gazelleHash
+		return []*model.Product{samba, black, gazelle}
+	}
+
+	tests := []struct {
+		name        string
+		gazelleHash string
+		want        []repository.StoreOutcome
+	}{
+		{"first store", "h3", []repository.StoreOutcome{repository.Created, repository.Created, repository.Created}},
+		{"same input", "h3", []repository.StoreOutcome{repository.Unchanged, repository.Unchanged, repository.Unchanged}},
+		{"one changed", "h4", []repository.StoreOutcome{repository.Unchanged, repository.Unchanged, repository.Updated}},
+	}
+	for _, tt := range tests {
+		got, err := products.StoreBatch(ctx, batch(tt.gazelleHash))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		for i := range tt.want {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: outcome %d = %v, want %v", tt.name, i, got[i], tt.want[i])
+			}
+		}
+	}
+
+	// both colorways share one group, keyed by its group key
+	variants, err := products.Variants(ctx, "JI2734")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(variants) != 2 || variants[0].ProductCode != "B75807" || variants[1].ProductCode != "JI2734" {
+		t.Errorf("variants = %+v, want B75807 and JI2734", variants)
+	}
+
+	listed, err := products.List(ctx, model.ProductFilter{GroupKey: "IKM07", WithDetails: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var samba model.Product
+	for _, p := range listed {
+		if p.ProductCode == "JI2734" {
+			samba = p
+		}
+	}
+	if len(samba.Images) != 1 || len(samba.Sizes) != 1 || len(samba.Reviews) != 1 ||
+		len(samba.AspectRatings) != 1 || len(samba.Coordinated) != 1 {
+		t.Errorf("details not stored: %+v", samba)
+	}
+
+	if _, err := products.FindByCode(ctx, "XX0000"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("FindByCode of an unknown code: %v, want ErrNotFound", err)
+	}
+}
+
+func TestProductURLsStoreAndSchedule(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	urls := sqlite.NewProductURLs(db)
+	products := sqlite.NewProducts(db)
+	day1 := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	mens := model.ProductURLSeed{Seed: "https://www.adidas.jp/men-shoes", CategoryPath: "men-shoes"}
+	womens := model.ProductURLSeed{Seed: "https://www.adidas.jp/women-shoes", CategoryPath: "women-shoes"}
+
+	input := func() []model.ProductURL {
+		return []model.ProductURL{
+			{Code: "JI2734", URL: "https://www.adidas.jp/samba-og/JI2734.html", LastModified: &day1, LastListedAt: &day1, Seeds: []model.ProductURLSeed{mens}},
+			{Code: "IE3437", URL: "https://www.adidas.jp/gazelle/IE3437.html", LastListedAt: &day2, Seeds: []model.ProductURLSeed{mens, womens}},
+			{Code: "JI2734", URL: "https://www.adidas.jp/samba-og/JI2734.html", Seeds: []model.ProductURLSeed{womens}},
+		}
+	}
+	first := input()
+	if err := urls.Store(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	again := input()
+	if err := urls.Store(ctx, again); err != nil {
+		t.Fatal(err)
+	}
+	for i := range first {
+		if first[i].ID == 0 || again[i].ID != first[i].ID {
+			t.Errorf("entry %d: IDs %d then %d", i, first[i].ID, again[i].ID)
+		}
+	}
+	if first[0].ID != first[2].ID {
+		t.Errorf("one URL got IDs %d and %d", first[0].ID, first[2].ID)
+	}
+	var seeds int64
+	db.Model(&model.ProductURLSeed{}).Count(&seeds)
+	if seeds != 4 {
+		t.Errorf("%d seeds stored, want 4", seeds)
+	}
+
+	// both are due before their first fetch
+	due, err := urls.Due(ctx, day2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 2 {
+		t.Fatalf("due = %+v, want both URLs", due)
+	}
+
+	schedule, err := urls.Schedule(ctx, first[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schedule.ID != 0 {
+		t.Fatalf("schedule of an unfetched URL has ID %d", schedule.ID)
+	}
+	schedule.LastFetchedAt, schedule.Interval, schedule.NextDueAt, schedule.FetchCount = &day2, 24*time.Hour, day2.AddDate(0, 0, 1), 1
+	for run := 0; run < 2; run++ {
+		if err := urls.SaveSchedule(ctx, schedule); err != nil {
+			t.Fatalf("save %d: %v", run, err)
+		}
+	}
+	// a second worker's new schedule for the same URL overwrites the first
+	other := &model.CrawlSchedule{ProductURLID: first[0].ID, LastFetchedAt: &day2, Interval: 48 * time.Hour, NextDueAt: day2.AddDate(0, 0, 2), FetchCount: 2}
+	if err := urls.SaveSchedule(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := urls.Schedule(ctx, first[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.ID != schedule.ID || stored.FetchCount != 2 || stored.Interval != 48*time.Hour {
+		t.Errorf("stored schedule %+v", stored)
+	}
+
+	due, err = urls.Due(ctx, day2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 1 || due[0].Code != "IE3437" {
+		t.Errorf("due after fetching JI2734 = %+v, want IE3437", due)
+	}
+
+	// only stored products that were not listed since can be missing
+	for _, p := range []*model.Product{product("JI2734", "Samba"), product("IE3437", "Gazelle")} {
+		if _, _, err := products.Store(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	missing, err := urls.MissingFromListings(ctx, []string{mens.Seed}, day2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 1 || missing[0].Code != "JI2734" {
+		t.Fatalf("missing = %+v, want JI2734", missing)
+	}
+	samba, err := products.FindByCode(ctx, "JI2734")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := products.SetStatus(ctx, samba.ID, samba.Status, lifecycle.StatusDelisted, "missing"); err != nil {
+		t.Fatal(err)
+	}
+	if missing, err = urls.MissingFromListings(ctx, []string{mens.Seed}, day2, 10); err != nil || len(missing) != 0 {
+		t.Errorf("missing after delisting = %+v, %v", missing, err)
+	}
+}
+
+func TestProductsFindByCodes(t *testing.T) {
+	ctx := context.Background()
+	products := sqlite.NewProducts(openDB(t))
+	if _, err := products.StoreBatch(ctx, []*model.Product{product("JI2734", "Samba OG"), product("IE3437", "Gazelle")}); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := products.FindByCodes(ctx, []string{"IE3437", "HQ4199", "JI2734"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 {
+		t.Errorf("FindByCodes = %+v, want JI2734 and IE3437", found)
+	}
+	if found, err := products.FindByCodes(ctx, nil); err != nil || found != nil {
+		t.Errorf("FindByCodes(nil) = %+v, %v", found, err)
+	}
+}
+
+func TestProductsLatestSnapshot(t *testing.T) {
+	ctx := context.Background()
+	products := sqlite.NewProducts(openDB(t))
+	p := product("JI2734", "Samba OG")
+	if _, err := products.StoreBatch(ctx, []*model.Product{p}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := products.LatestSnapshot(ctx, p.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("LatestSnapshot before any snapshot: %v, want ErrNotFound", err)
+	}
+	captured := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for _, hash := range []string{"h1", "h2"} {
+		s := &model.ProductSnapshot{ProductID: p.ID, Hash: hash, Data: datatypes.JSON(`{}`), CapturedAt: captured}
+		if err := products.AddSnapshot(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	last, err := products.LatestSnapshot(ctx, p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.Hash != "h2" {
+		t.Errorf("LatestSnapshot = %q, want h2", last.Hash)
+	}
+}
+
+func TestProductsStockChanges(t *testing.T) {
+	ctx := context.Background()
+	products := sqlite.NewProducts(openDB(t))
+	samba, gazelle := product("JI2734", "Samba OG"), product("IE3437", "Gazelle")
+	if _, err := products.StoreBatch(ctx, []*model.Product{samba, gazelle}); err != nil {
+		t.Fatal(err)
+	}
+
+	day := func(d int) time.Time { return time.Date(2024, 5, d, 0, 0, 0, 0, time.UTC) }
+	observe := func(p *model.Product, label, status string, d int) model.SizeStockObservation {
+		return model.SizeStockObservation{ProductID: p.ID, SizeLabel: label, Status: status, ObservedAt: day(d)}
+	}
+	err := products.AddStockObservations(ctx, []model.SizeStockObservation{
+		observe(samba, "27.0cm", model.StockInStock, 1),
+		observe(samba, "27.0cm", model.StockInStock, 2),
+		observe(samba, "27.0cm", model.StockOutOfStock, 3),
+		observe(samba, "27.0cm", model.StockOutOfStock, 4),
+		observe(samba, "27.0cm", model.StockInStock, 5),
+		observe(samba, "28.0cm", model.StockInStock, 1),
+		observe(samba, "28.0cm", model.StockInStock, 5),
+		observe(gazelle, "27.0cm", model.StockInStock, 2),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := products.StockChanges(ctx, samba.ID, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, o := range changes {
+		got = append(got, fmt.Sprintf("%s %s %d", o.SizeLabel, o.Status, o.ObservedAt.Day()))
+	}
+	want := []string{"27.0cm in_stock 1", "27.0cm out_of_stock 3", "27.0cm in_stock 5", "28.0cm in_stock 1"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("StockChanges = %v, want %v", got, want)
+	}
+
+	recent, err := products.StockChanges(ctx, 0, day(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 3 || recent[0].Status != model.StockOutOfStock || recent[0].ObservedAt.Day() != 4 {
+		t.Errorf("StockChanges since day 4 = %+v, want samba's 27.0cm from day 4 and 28.0cm", recent)
+	}
+
+	labels, err := products.StockSizeLabels(ctx, samba.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(labels) != "[27.0cm 28.0cm]" {
+		t.Errorf("StockSizeLabels = %v", labels)
+	}
+}
+
+func TestCrawlRuns(t *testing.T) {
+	ctx := context.Background()
+	runs := sqlite.NewCrawlRuns(openDB(t))
+
+	for i, mode := range []string{"full", "incremental"} {
+		run := &model.CrawlRun{
+			StartedAt:      time.Date(2024, 5, 1+i, 0, 0, 0, 0, time.UTC),
+			Mode:           mode,
+			Status:         model.CrawlRunRunning,
+			ConfigSnapshot: datatypes.JSON(`{"Workers":4}`),
+		}
+		if err := runs.Create(ctx, run); err != nil {
+			t.Fatal(err)
+		}
+		finished := run.StartedAt.Add(time.Hour)
+		run.Status, run.FinishedAt, run.Stored = model.CrawlRunSucceeded, &finished, 10*(i+1)
+		run.Errors = datatypes.JSON(`{"navigate":2}`)
+		if err := runs.Save(ctx, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list, err := runs.List(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Mode != "incremental" {
+		t.Fatalf("List(1) = %+v, want the latest run", list)
+	}
+	run, err := runs.Find(ctx, list[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != model.CrawlRunSucceeded || run.Stored != 20 || string(run.Errors) != `{"navigate":2}` {
+		t.Errorf("found %+v", run)
+	}
+	if _, err := runs.Find(ctx, 999); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Find of an unknown run: %v, want ErrNotFound", err)
+	}
+}
+
+func TestReviewsTags(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	products := sqlite.NewProducts(db)
+	reviews := sqlite.NewReviews(db)
+
+	p := product("JI2734", "Samba")
+	p.Reviews = []model.Review{
+		{ReviewDate: time.Now(), Rating: 5, Body: "comfortable"},
+		{ReviewDate: time.Now(), Rating: 2, Body: "runs small"},
+	}
+	if _, _, err := products.Store(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := reviews.ForProduct(ctx, p.ID)
+	if err != nil || len(stored) != 2 {
+		t.Fatalf("ForProduct = %+v, %v", stored, err)
+	}
+
+	tag := func(r model.Review, kind, value string, score float64) model.ReviewTag {
+		return model.ReviewTag{ReviewID: r.ID, Kind: kind, Value: value, Score: score, Classifier: "lexicon"}
+	}
+	tags := []model.ReviewTag{
+		tag(stored[0], model.ReviewTagSentiment, "positive", 1),
+		tag(stored[0], model.ReviewTagTopic, "comfort", 1),
+		tag(stored[1], model.ReviewTagSentiment, "negative", -1),
+		tag(stored[1], model.ReviewTagTopic, "sizing", 1),
+	}
+	ids := []uint{stored[0].ID, stored[1].ID}
+	// replacing twice leaves one set of tags
+	for run := 0; run < 2; run++ {
+		if err := reviews.ReplaceTags(ctx, ids, tags); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	counts, err := reviews.TagCounts(ctx, p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]int{}
+	for _, c := range counts {
+		got[c.Kind+":"+c.Value] = c.Reviews
+	}
+	want := map[string]int{"sentiment:positive": 1, "sentiment:negative": 1, "topic:comfort": 1, "topic:sizing": 1}
+	if len(got) != len(want) {
+		t.Fatalf("tag counts = %v, want %v", got, want)
+	}
+	for k, n := range want {
+		if got[k] != n {
+			t.Errorf("%s = %d, want %d", k, got[k], n)
+		}
+	}
+
+	breakdown, err := reviews.TopicSentimentCounts(ctx, p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(breakdown) != 2 || breakdown[0] != (model.TopicSentimentCount{Topic: "comfort", Sentiment: "positive", Reviews: 1}) {
+		t.Errorf("topic sentiment = %+v", breakdown)
+	}
+}
@@ -0,0 +1,67 @@
+package sqlite
+
+import (
+	"strings"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"gorm.io/gorm"
+)
+
+// SearchProducts is postgres.SearchProducts without word_similarity, which
+// comes from pg_trgm: a term scores highest in the name, then in the
+// descriptions, then by how many reviews mention it. Short terms match
+// substrings like any other, as SQLite scans the table either way.
+func SearchProducts(db *gorm.DB, terms []string, limit int) ([]model.ProductSearchHit, error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	var (
+		where, score, anyReview []string
+		whereArgs, scoreArgs    []interface{}
+		anyReviewArgs           []interface{}
+	)
+	for _, term := range terms {
+		pattern := "%" + escapeLike(term) + "%"
+		where = append(where, `(p.search_text LIKE ? ESCAPE '\' OR EXISTS (
+			SELECT 1 FROM reviews r WHERE r.product_id = p.id AND r.search_text LIKE ? ESCAPE '\'))`)
+		whereArgs = append(whereArgs, pattern, pattern)
+
+		score = append(score, `(CASE WHEN p.search_name LIKE ? ESCAPE '\' THEN 3 ELSE 0 END
+			+ CASE WHEN p.search_text LIKE ? ESCAPE '\' THEN 1 ELSE 0 END
+			+ 0.2 * min((SELECT count(*) FROM reviews r WHERE r.product_id = p.id AND r.search_text LIKE ? ESCAPE '\'), 5))`)
+		scoreArgs = append(scoreArgs, pattern, pattern, pattern)
+
+		anyReview = append(anyReview, `r.search_text LIKE ? ESCAPE '\'`)
+		anyReviewArgs = append(anyReviewArgs, pattern)
+	}
+	reviewMatch := strings.Join(anyReview, " OR ")
+
+	query := `SELECT p.id AS product_id, p.product_code, p.name, p.title_description,
+			p.general_description, p.price_yen, p.details_url,
+			(SELECT count(*) FROM reviews r WHERE r.product_id = p.id AND (` + reviewMatch + `)) AS review_matches,
+			coalesce((SELECT r.body FROM reviews r WHERE r.product_id = p.id AND (` + reviewMatch + `)
+				ORDER BY r.review_date DESC LIMIT 1), '') AS review_body,
+			` + strings.Join(score, " + ") + ` AS score
+		FROM products p
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY score DESC, p.id
+		LIMIT ?`
+
+	var args []interface{}
+	args = append(args, anyReviewArgs...)
+	args = append(args, anyReviewArgs...)
+	args = append(args, scoreArgs...)
+	args = append(args, whereArgs...)
+	args = append(args, limit)
+
+	var hits []model.ProductSearchHit
+	err := db.Raw(query, args...).Scan(&hits).Error
+	return hits, err
+}
+
+// escapeLike escapes the LIKE wildcards in s. SQLite has no default escape
+// character, hence the ESCAPE clauses above.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
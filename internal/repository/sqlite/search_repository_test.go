@@ -0,0 +1,48 @@
+package sqlite_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/repository/sqlite"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/search"
+)
+
+func TestSearchFoldsWidthAndCase(t *testing.T) {
+	ctx := context.Background()
+	products := sqlite.NewProducts(openDB(t))
+
+	samba := product("JI2734", "ＳＡＭＢＡ ＯＧ")
+	samba.TitleDescription = "ﾎﾜｲﾄのレザーアッパー"
+	samba.Reviews = []model.Review{{ReviewDate: time.Now(), Rating: 5, Title: "最高", Body: "ＣＯＭＦＯＲＴＡＢＬＥ all day"}}
+	gazelle := product("IE3437", "Gazelle")
+	gazelle.TitleDescription = "スエードアッパー"
+	for _, p := range []*model.Product{samba, gazelle} {
+		if _, _, err := products.Store(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"samba", "JI2734"},
+		{"Ｓａｍｂａ", "JI2734"},
+		{"ホワイト", "JI2734"},
+		{"comfortable", "JI2734"},
+		{"ｽｴｰﾄﾞ", "IE3437"},
+		{"GAZELLE", "IE3437"},
+	}
+	for _, tt := range tests {
+		hits, err := products.Search(ctx, search.Terms(tt.query), 10)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		if len(hits) != 1 || hits[0].ProductCode != tt.want {
+			t.Errorf("search %q = %+v, want only %s", tt.query, hits, tt.want)
+		}
+	}
+}
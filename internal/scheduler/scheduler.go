@@ -0,0 +1,66 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+)
+
+// Policy computes adaptive recrawl intervals: a product whose content changed
+// since the last fetch is revisited twice as soon, an unchanged one half as
+// often again, always staying within [Min, Max].
+type Policy struct {
+	Initial time.Duration
+	Min     time.Duration
+	Max     time.Duration
+}
+
+// RecordFetch updates s after a successful fetch that produced contentHash.
+func (p Policy) RecordFetch(s *model.CrawlSchedule, contentHash string, now time.Time) {
+	changed := s.ContentHash != contentHash
+
+	switch {
+	case s.Interval == 0:
+		s.Interval = p.Initial
+	case changed:
+		s.Interval /= 2
+	default:
+		s.Interval = s.Interval * 3 / 2
+	}
+	s.Interval = p.clamp(s.Interval)
+
+	if changed {
+		s.ContentHash = contentHash
+		s.LastChangedAt = &now
+		s.ChangeCount++
+	}
+	s.LastFetchedAt = &now
+	s.FetchCount++
+	s.FailCount = 0
+	s.NextDueAt = now.Add(s.Interval)
+}
+
+// RecordFailure pushes the next attempt back exponentially in the number of
+// consecutive failures, without touching the content interval.
+func (p Policy) RecordFailure(s *model.CrawlSchedule, now time.Time) {
+	if s.Interval == 0 {
+		s.Interval = p.Initial
+	}
+	s.FailCount++
+
+	backoff := p.Min
+	for i := 1; i < s.FailCount && backoff < p.Max; i++ {
+		backoff *= 2
+	}
+	s.NextDueAt = now.Add(p.clamp(backoff))
+}
+
+func (p Policy) clamp(d time.Duration) time.Duration {
+	if d < p.Min {
+		return p.Min
+	}
+	if d > p.Max {
+		return p.Max
+	}
+	return d
+}
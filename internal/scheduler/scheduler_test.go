@@ -0,0 +1,92 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+)
+
+var policy = Policy{Initial: 24 * time.Hour, Min: 6 * time.Hour, Max: 336 * time.Hour}
+
+func TestRecordFetch(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		interval time.Duration
+		hash     string
+		want     time.Duration
+		changes  int
+	}{
+		{"first fetch", 0, "a", 24 * time.Hour, 1},
+		{"changed", 24 * time.Hour, "b", 12 * time.Hour, 1},
+		{"unchanged", 24 * time.Hour, "a", 36 * time.Hour, 0},
+		{"changed at the minimum", 8 * time.Hour, "b", 6 * time.Hour, 1},
+		{"unchanged at the maximum", 300 * time.Hour, "a", 336 * time.Hour, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &model.CrawlSchedule{Interval: tt.interval, FailCount: 2}
+			if tt.interval != 0 {
+				s.ContentHash = "a"
+			}
+			policy.RecordFetch(s, tt.hash, now)
+
+			if s.Interval != tt.want {
+				t.Errorf("Interval = %s, want %s", s.Interval, tt.want)
+			}
+			if !s.NextDueAt.Equal(now.Add(tt.want)) {
+				t.Errorf("NextDueAt = %s, want %s", s.NextDueAt, now.Add(tt.want))
+			}
+			if s.ChangeCount != tt.changes || s.ContentHash != tt.hash {
+				t.Errorf("ChangeCount = %d, ContentHash = %q, want %d, %q", s.ChangeCount, s.ContentHash, tt.changes, tt.hash)
+			}
+			if (s.LastChangedAt != nil) != (tt.changes > 0) {
+				t.Errorf("LastChangedAt = %v with %d changes", s.LastChangedAt, tt.changes)
+			}
+			if s.FetchCount != 1 || s.FailCount != 0 || s.LastFetchedAt == nil {
+				t.Errorf("FetchCount = %d, FailCount = %d, LastFetchedAt = %v", s.FetchCount, s.FailCount, s.LastFetchedAt)
+			}
+		})
+	}
+}
+
+func TestRecordFailure(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		failures int
+		want     time.Duration
+	}{
+		{"first failure", 0, 6 * time.Hour},
+		{"second failure", 1, 12 * time.Hour},
+		{"third failure", 2, 24 * time.Hour},
+		{"sixth failure", 5, 192 * time.Hour},
+		{"capped", 6, 336 * time.Hour},
+		{"long after the cap", 40, 336 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &model.CrawlSchedule{Interval: 48 * time.Hour, FailCount: tt.failures}
+			policy.RecordFailure(s, now)
+
+			if got := s.NextDueAt.Sub(now); got != tt.want {
+				t.Errorf("backoff = %s, want %s", got, tt.want)
+			}
+			if s.FailCount != tt.failures+1 {
+				t.Errorf("FailCount = %d, want %d", s.FailCount, tt.failures+1)
+			}
+			if s.Interval != 48*time.Hour {
+				t.Errorf("Interval = %s, want it untouched", s.Interval)
+			}
+		})
+	}
+}
+
+func TestRecordFailureStartsAtInitial(t *testing.T) {
+	s := &model.CrawlSchedule{}
+	policy.RecordFailure(s, time.Now())
+	if s.Interval != policy.Initial {
+		t.Errorf("Interval = %s, want %s", s.Interval, policy.Initial)
+	}
+}
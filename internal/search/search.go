@@ -0,0 +1,137 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"golang.org/x/text/unicode/norm"
+)
+
+// snippetRadius is how many characters of context a highlight keeps on
+// each side of the first match.
+const snippetRadius = 40
+
+// Result is one product found by a search.
+type Result struct {
+	ProductCode   string  `json:"product_code"`
+	Name          string  `json:"name"`
+	PriceYen      float64 `json:"price_yen"`
+	DetailsURL    string  `json:"details_url"`
+	Score         float64 `json:"score"`
+	ReviewMatches int     `json:"review_matches"`
+	// Highlights maps "name", "description" and "review" to snippets with
+	// the matched terms wrapped in <mark></mark>. Fields without a match
+	// are left out.
+	Highlights map[string]string `json:"highlights"`
+}
+
+// Fold normalizes text for matching: NFKC folds full-width ASCII and
+// half-width katakana, then case is folded. The search_text columns hold
+// their text folded this way.
+func Fold(s string) string {
+	return strings.ToLower(norm.NFKC.String(s))
+}
+
+// Terms splits a query into search terms folded by Fold, so
+// "ｽﾆｰｶｰ　ＷＨＩＴＥ" and "スニーカー white" search the same.
+func Terms(q string) []string {
+	var terms []string
+	seen := map[string]bool{}
+	for _, t := range strings.Fields(Fold(q)) {
+		if !seen[t] {
+			seen[t] = true
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
+
+// Results turns repository hits into results with highlighted snippets of
+// the fields the terms matched in.
+func Results(hits []model.ProductSearchHit, terms []string) []Result {
+	results := make([]Result, 0, len(hits))
+	for _, h := range hits {
+		highlights := map[string]string{}
+		if s, ok := Highlight(h.Name, terms, snippetRadius); ok {
+			highlights["name"] = s
+		}
+		if s, ok := Highlight(h.TitleDescription+" "+h.GeneralDescription, terms, snippetRadius); ok {
+			highlights["description"] = s
+		}
+		if s, ok := Highlight(h.ReviewBody, terms, snippetRadius); ok {
+			highlights["review"] = s
+		}
+		results = append(results, Result{
+			ProductCode:   h.ProductCode,
+			Name:          h.Name,
+			PriceYen:      h.PriceYen,
+			DetailsURL:    h.DetailsURL,
+			Score:         h.Score,
+			ReviewMatches: h.ReviewMatches,
+			Highlights:    highlights,
+		})
+	}
+	return results
+}
+
+// Highlight returns the part of text around the first term match, cut to
+// radius characters either side, with every match wrapped in <mark></mark>
+// and the rest HTML-escaped. Overlapping and adjacent matches share one
+// mark. ok is false when no term occurs in text.
+func Highlight(text string, terms []string, radius int) (snippet string, ok bool) {
+	runes := []rune(norm.NFKC.String(text))
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// marked[i] is set for every rune inside a match
+	marked := make([]bool, len(runes))
+	first, firstLen := -1, 0
+	for _, term := range terms {
+		t := []rune(term)
+		if len(t) == 0 {
+			continue
+		}
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) != term {
+				continue
+			}
+			for j := i; j < i+len(t); j++ {
+				marked[j] = true
+			}
+			if first < 0 || i < first || (i == first && len(t) > firstLen) {
+				first, firstLen = i, len(t)
+			}
+		}
+	}
+	if first < 0 {
+		return "", false
+	}
+
+	start, end := max(first-radius, 0), min(first+firstLen+radius, len(runes))
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; {
+		j := i + 1
+		for j < end && marked[j] == marked[i] {
+			j++
+		}
+		if marked[i] {
+			b.WriteString("<mark>")
+		}
+		b.WriteString(html.EscapeString(string(runes[i:j])))
+		if marked[i] {
+			b.WriteString("</mark>")
+		}
+		i = j
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
@@ -0,0 +1,49 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	got := Terms("ｽﾆｰｶｰ　ＷＨＩＴＥ  スニーカー white")
+	if want := []string{"スニーカー", "white"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Terms = %q, want %q", got, want)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	long := strings.Repeat("あ", 50) + "白" + strings.Repeat("い", 50)
+
+	tests := []struct {
+		name   string
+		text   string
+		terms  []string
+		radius int
+		want   string
+		ok     bool
+	}{
+		{"single match", "サンバ OG / Samba OG", []string{"samba"}, 40, "サンバ OG / <mark>Samba</mark> OG", true},
+		{"every occurrence", "サンバ OG / Samba OG", []string{"og"}, 40, "サンバ <mark>OG</mark> / Samba <mark>OG</mark>", true},
+		{"several terms", "白のスニーカー、黒のスニーカー", []string{"黒", "白"}, 40, "<mark>白</mark>のスニーカー、<mark>黒</mark>のスニーカー", true},
+		{"overlapping terms", "ウルトラブースト 5", []string{"ブースト", "ウルトラブ"}, 40, "<mark>ウルトラブースト</mark> 5", true},
+		{"term inside another", "ウルトラブースト", []string{"ブー", "ウルトラブースト"}, 40, "<mark>ウルトラブースト</mark>", true},
+		{"adjacent terms", "コアブラック", []string{"コア", "ブラック"}, 40, "<mark>コアブラック</mark>", true},
+		{"full-width text", "ＳＡＭＢＡ ＯＧ", []string{"samba"}, 40, "<mark>SAMBA</mark> OG", true},
+		{"half-width katakana", "ｽﾆｰｶｰ", []string{"スニーカー"}, 40, "<mark>スニーカー</mark>", true},
+		{"cut by runes", long, []string{"白"}, 3, "…あああ<mark>白</mark>いいい…", true},
+		{"cut through a match", "ランニングシューズ", []string{"ランニング", "シューズ"}, 1, "<mark>ランニングシ</mark>…", true},
+		{"escaped", `<b>"Samba"</b> & co`, []string{"samba"}, 40, `&lt;b&gt;&#34;<mark>Samba</mark>&#34;&lt;/b&gt; &amp; co`, true},
+		{"no match", "サンバ OG", []string{"gazelle"}, 40, "", false},
+		{"empty term", "サンバ OG", []string{""}, 40, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Highlight(tt.text, tt.terms, tt.radius)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("Highlight = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
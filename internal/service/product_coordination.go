@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+)
+
+// RecordCoordinations adds the products a freshly fetched product is
+// coordinated with to the coordination graph, changed or not.
+func (s *ProductService) RecordCoordinations(ctx context.Context, p model.Product) error {
+	now := time.Now()
+	var edges []model.CoordinationEdge
+	for _, c := range p.Coordinated {
+		if c.ProductNumber == "" || c.ProductNumber == p.ProductCode {
+			continue
+		}
+		edges = append(edges, model.CoordinationEdge{
+			SourceProductID: p.ID,
+			SourceCode:      p.ProductCode,
+			TargetCode:      c.ProductNumber,
+			TargetURL:       c.ProductPageURL,
+			SeenCount:       1,
+			FirstSeenAt:     now,
+			LastSeenAt:      now,
+		})
+	}
+	return s.products.StoreCoordinations(ctx, edges)
+}
+
+// FrequentlyCoordinated ranks the products coordinated with a product
+// code, best first. A product scores a point for every fetch on which
+// either of the two listed the other and for every third product listing
+// both.
+func (s *ProductService) FrequentlyCoordinated(ctx context.Context, code string, limit int) ([]model.CoordinatedProduct, error) {
+	edges, err := s.products.Coordinations(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	ranked := rankCoordinated(code, edges)
+	if limit > 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	codes := make([]string, len(ranked))
+	for i, r := range ranked {
+		codes[i] = r.ProductCode
+	}
+	stored, err := s.products.FindByCodes(ctx, codes)
+	if err != nil {
+		return nil, err
+	}
+	byCode := make(map[string]model.Product, len(stored))
+	for _, p := range stored {
+		byCode[p.ProductCode] = p
+	}
+
+	for i := range ranked {
+		r := &ranked[i]
+		p, ok := byCode[r.ProductCode]
+		if !ok {
+			continue
+		}
+		r.Crawled, r.Name, r.PriceYen = true, p.Name, p.PriceYen
+		if r.URL == "" {
+			r.URL = p.DetailsURL
+		}
+	}
+	return ranked, nil
+}
+
+// EnqueueCoordinated records the coordinated products of a product code
+// that are not stored yet as product URLs, so that the next incremental
+// crawl fetches them, and returns them.
+func (s *ProductService) EnqueueCoordinated(ctx context.Context, code string) ([]model.ProductURL, error) {
+	ranked, err := s.FrequentlyCoordinated(ctx, code, 0)
+	if err != nil {
+		return nil, err
+	}
+	var urls []model.ProductURL
+	for _, r := range ranked {
+		if r.Crawled || r.URL == "" {
+			continue
+		}
+		urls = append(urls, model.ProductURL{Code: r.ProductCode, URL: r.URL, ScrapedAt: time.Now()})
+	}
+	if len(urls) == 0 {
+		return nil, nil
+	}
+	if err := s.urls.Store(ctx, urls); err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
+// rankCoordinated scores the neighbors of code in the edges returned by
+// ProductRepository.Coordinations.
+func rankCoordinated(code string, edges []model.CoordinationEdge) []model.CoordinatedProduct {
+	byCode := map[string]*model.CoordinatedProduct{}
+	neighbor := func(c string) *model.CoordinatedProduct {
+		n := byCode[c]
+		if n == nil {
+			n = &model.CoordinatedProduct{ProductCode: c}
+			byCode[c] = n
+		}
+		return n
+	}
+
+	listing := map[string]bool{}
+	for _, e := range edges {
+		switch {
+		case e.SourceCode == code:
+			n := neighbor(e.TargetCode)
+			n.Links += e.SeenCount
+			n.URL = e.TargetURL
+		case e.TargetCode == code:
+			neighbor(e.SourceCode).Links += e.SeenCount
+			listing[e.SourceCode] = true
+		}
+	}
+	for _, e := range edges {
+		if listing[e.SourceCode] && e.TargetCode != code {
+			n := neighbor(e.TargetCode)
+			n.SharedLooks++
+			if n.URL == "" {
+				n.URL = e.TargetURL
+			}
+		}
+	}
+
+	ranked := make([]model.CoordinatedProduct, 0, len(byCode))
+	for _, n := range byCode {
+		n.Score = n.Links + n.SharedLooks
+		ranked = append(ranked, *n)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].ProductCode < ranked[j].ProductCode
+	})
+	return ranked
+}
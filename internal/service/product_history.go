@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/repository"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/snapshot"
+)
+
+type crawlRunKey struct{}
+
+// WithCrawlRun returns a context whose stored products are attributed to
+// the given crawl run in their snapshots.
+func WithCrawlRun(ctx context.Context, runID uint) context.Context {
+	return context.WithValue(ctx, crawlRunKey{}, runID)
+}
+
+func crawlRunFrom(ctx context.Context) *uint {
+	if id, ok := ctx.Value(crawlRunKey{}).(uint); ok {
+		return &id
+	}
+	return nil
+}
+
+// ProductVersion is one stored snapshot of a product and how it differs
+// from the one before. Changes is empty for the first version.
+type ProductVersion struct {
+	CrawlRunID *uint             `json:"crawl_run_id,omitempty"`
+	CapturedAt time.Time         `json:"captured_at"`
+	Snapshot   snapshot.Snapshot `json:"snapshot"`
+	Changes    []snapshot.Change `json:"changes"`
+}
+
+// RecordSnapshots stores a snapshot of every created or updated product
+// whose attributes differ from its last snapshot. Subscribe it to the
+// ProductService. Failures are reported to onError.
+func (s *ProductService) RecordSnapshots(onError func(ProductEvent, error)) func(context.Context, ProductEvent) {
+	return func(ctx context.Context, ev ProductEvent) {
+		if ev.Product == nil || (ev.Type != ProductCreated && ev.Type != ProductUpdated) {
+			return
+		}
+		if err := s.RecordSnapshot(ctx, *ev.Product); err != nil {
+			onError(ev, err)
+		}
+	}
+}
+
+// RecordSnapshot appends a snapshot of a stored product unless it equals
+// the last one. It is attributed to the crawl run set with WithCrawlRun.
+func (s *ProductService) RecordSnapshot(ctx context.Context, p model.Product) error {
+	snap := snapshot.Of(p)
+	hash := snap.Hash()
+
+	last, err := s.products.LatestSnapshot(ctx, p.ID)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+	case err != nil:
+		return err
+	case last.Hash == hash:
+		return nil
+	}
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	return s.products.AddSnapshot(ctx, &model.ProductSnapshot{
+		ProductID:  p.ID,
+		CrawlRunID: crawlRunFrom(ctx),
+		Hash:       hash,
+		Data:       data,
+		CapturedAt: time.Now(),
+	})
+}
+
+// History returns the stored versions of a product's attributes, oldest
+// first, each with its changes from the version before.
+func (s *ProductService) History(ctx context.Context, code string) ([]ProductVersion, error) {
+	p, err := s.products.FindByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := s.products.Snapshots(ctx, p.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]ProductVersion, 0, len(snapshots))
+	for i, stored := range snapshots {
+		v := ProductVersion{CrawlRunID: stored.CrawlRunID, CapturedAt: stored.CapturedAt, Changes: []snapshot.Change{}}
+		if err := json.Unmarshal(stored.Data, &v.Snapshot); err != nil {
+			return nil, fmt.Errorf("snapshot %d: %w", stored.ID, err)
+		}
+		if i > 0 {
+			if changes := snapshot.Diff(versions[i-1].Snapshot, v.Snapshot); changes != nil {
+				v.Changes = changes
+			}
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// DiffRuns returns the changes of a product's attributes between the end
+// of crawl run runA and the end of runB, or now if runB is still running.
+// It returns repository.ErrNotFound when a run does not exist or the
+// product had no snapshot yet when runA ended.
+func (s *ProductService) DiffRuns(ctx context.Context, code string, runA, runB uint) ([]snapshot.Change, error) {
+	versions, err := s.History(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	from, err := s.versionAsOf(ctx, code, versions, runA)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.versionAsOf(ctx, code, versions, runB)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Diff(from.Snapshot, to.Snapshot), nil
+}
+
+// versionAsOf returns the latest version captured by the end of a crawl
+// run.
+func (s *ProductService) versionAsOf(ctx context.Context, code string, versions []ProductVersion, runID uint) (ProductVersion, error) {
+	run, err := s.runs.Find(ctx, runID)
+	if err != nil {
+		return ProductVersion{}, fmt.Errorf("crawl run %d: %w", runID, err)
+	}
+	cutoff := time.Now()
+	if run.FinishedAt != nil {
+		cutoff = *run.FinishedAt
+	}
+
+	var found *ProductVersion
+	for i, v := range versions {
+		if v.CapturedAt.After(cutoff) && (v.CrawlRunID == nil || *v.CrawlRunID != runID) {
+			break
+		}
+		found = &versions[i]
+	}
+	if found == nil {
+		return ProductVersion{}, fmt.Errorf("%w: no snapshot of %s as of crawl run %d", repository.ErrNotFound, code, runID)
+	}
+	return *found, nil
+}
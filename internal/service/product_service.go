@@ -1 +1,331 @@
 package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/keyword"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/repository"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/search"
+)
+
+// ProductRepository stores products and what hangs off them. Lookups of a
+// missing product return repository.ErrNotFound.
+type ProductRepository interface {
+	// Store upserts p with its images, sizes, reviews and coordinated items
+	// and fills in p.ID. An unchanged ContentHash only marks p as seen.
+	Store(ctx context.Context, p *model.Product) (created, changed bool, err error)
+	// StoreBatch stores many products at once, like Store, and returns
+	// what storing each of them did.
+	StoreBatch(ctx context.Context, products []*model.Product) ([]repository.StoreOutcome, error)
+	FindByCode(ctx context.Context, code string) (*model.Product, error)
+	// FindByCodes returns the stored products among codes, without
+	// details and in no particular order.
+	FindByCodes(ctx context.Context, codes []string) ([]model.Product, error)
+	List(ctx context.Context, f model.ProductFilter) ([]model.Product, error)
+	InBatches(ctx context.Context, size int, fn func([]model.Product) error) error
+	Variants(ctx context.Context, code string) ([]model.Product, error)
+	SetStatus(ctx context.Context, productID uint, from, to, reason string) error
+	StatusHistory(ctx context.Context, productID uint) ([]model.ProductStatusChange, error)
+	// AddSnapshot appends an attribute snapshot; Snapshots returns a
+	// product's snapshots oldest first and LatestSnapshot only the last.
+	AddSnapshot(ctx context.Context, s *model.ProductSnapshot) error
+	Snapshots(ctx context.Context, productID uint) ([]model.ProductSnapshot, error)
+	LatestSnapshot(ctx context.Context, productID uint) (*model.ProductSnapshot, error)
+	AddStockObservations(ctx context.Context, obs []model.SizeStockObservation) error
+	// StockChanges returns the observations of a product, or of every
+	// product if productID is 0, since the given time (all if zero), that
+	// change the status of their size. StockSizeLabels returns every size
+	// ever observed for a product.
+	StockChanges(ctx context.Context, productID uint, since time.Time) ([]model.SizeStockObservation, error)
+	StockSizeLabels(ctx context.Context, productID uint) ([]string, error)
+	StoreCoordinations(ctx context.Context, edges []model.CoordinationEdge) error
+	// Coordinations returns the edges from and to a product code and every
+	// edge from the products that list it.
+	Coordinations(ctx context.Context, code string) ([]model.CoordinationEdge, error)
+	SetKeywords(ctx context.Context, productID uint, kws []string) error
+	ByKeyword(ctx context.Context, kw string, limit int) ([]model.Product, error)
+	CategoryPaths(ctx context.Context, code string) ([]string, error)
+	Search(ctx context.Context, terms []string, limit int) ([]model.ProductSearchHit, error)
+}
+
+// ProductURLRepository stores discovered product URLs and their recrawl
+// schedules.
+type ProductURLRepository interface {
+	Store(ctx context.Context, urls []model.ProductURL) error
+	Due(ctx context.Context, now time.Time, budget int) ([]model.ProductURL, error)
+	MissingFromListings(ctx context.Context, seeds []string, since time.Time, limit int) ([]model.ProductURL, error)
+	// Schedule returns the URL's schedule, or a new unsaved one.
+	Schedule(ctx context.Context, productURLID uint) (*model.CrawlSchedule, error)
+	SaveSchedule(ctx context.Context, s *model.CrawlSchedule) error
+}
+
+// CrawlRunRepository is the ledger of crawl runs.
+type CrawlRunRepository interface {
+	Create(ctx context.Context, run *model.CrawlRun) error
+	Save(ctx context.Context, run *model.CrawlRun) error
+	List(ctx context.Context, limit int) ([]model.CrawlRun, error)
+	Find(ctx context.Context, id uint) (*model.CrawlRun, error)
+}
+
+// Product event types.
+const (
+	ProductCreated       = "created"
+	ProductUpdated       = "updated"
+	ProductStatusChanged = "status_changed"
+)
+
+// ProductEvent tells subscribers that a stored product changed.
+type ProductEvent struct {
+	Type        string
+	ProductID   uint
+	ProductCode string
+	At          time.Time
+	// FromStatus and ToStatus are set for ProductStatusChanged.
+	FromStatus string
+	ToStatus   string
+	// Product is the stored product for ProductCreated and ProductUpdated.
+	Product *model.Product
+}
+
+// ProductService is the domain layer between the crawler, the API and the
+// exporter on one side and storage on the other.
+type ProductService struct {
+	products ProductRepository
+	urls     ProductURLRepository
+	runs     CrawlRunRepository
+
+	mu          sync.RWMutex
+	subscribers []func(context.Context, ProductEvent)
+}
+
+func NewProductService(products ProductRepository, urls ProductURLRepository, runs CrawlRunRepository) *ProductService {
+	return &ProductService{products: products, urls: urls, runs: runs}
+}
+
+// Subscribe registers fn to be called, synchronously, with every product
+// change event.
+func (s *ProductService) Subscribe(fn func(context.Context, ProductEvent)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers = append(s.subscribers, fn)
+}
+
+func (s *ProductService) publish(ctx context.Context, ev ProductEvent) {
+	s.mu.RLock()
+	subscribers := s.subscribers
+	s.mu.RUnlock()
+	for _, fn := range subscribers {
+		fn(ctx, ev)
+	}
+}
+
+// Upsert stores a parsed product and, if it changed, relinks its keywords
+// and publishes ProductCreated or ProductUpdated. changed is false when the
+// content fingerprint matched the stored product.
+func (s *ProductService) Upsert(ctx context.Context, p *model.Product) (changed bool, err error) {
+	created, changed, err := s.products.Store(ctx, p)
+	if err != nil || !changed {
+		return changed, err
+	}
+	return true, s.changed(ctx, p, created)
+}
+
+// UpsertResult is what upserting one product of a batch did. Err is set
+// when the product was stored but relinking its keywords failed.
+type UpsertResult struct {
+	Outcome repository.StoreOutcome
+	Err     error
+}
+
+// UpsertBatch stores many parsed products in one go and then, like Upsert,
+// relinks keywords and publishes events for those that changed. The error
+// is set only when the batch could not be stored.
+func (s *ProductService) UpsertBatch(ctx context.Context, products []*model.Product) ([]UpsertResult, error) {
+	outcomes, err := s.products.StoreBatch(ctx, products)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]UpsertResult, len(products))
+	for i, p := range products {
+		results[i].Outcome = outcomes[i]
+		if outcomes[i] != repository.Unchanged {
+			results[i].Err = s.changed(ctx, p, outcomes[i] == repository.Created)
+		}
+	}
+	return results, nil
+}
+
+// changed relinks the keywords of a created or updated product and
+// publishes the matching event.
+func (s *ProductService) changed(ctx context.Context, p *model.Product, created bool) error {
+	paths, err := s.products.CategoryPaths(ctx, p.ProductCode)
+	if err != nil {
+		return err
+	}
+	if err := s.products.SetKeywords(ctx, p.ID, keyword.Extract(*p, paths)); err != nil {
+		return err
+	}
+
+	ev := ProductEvent{Type: ProductUpdated, ProductID: p.ID, ProductCode: p.ProductCode, At: time.Now(), Product: p}
+	if created {
+		ev.Type = ProductCreated
+	}
+	s.publish(ctx, ev)
+	return nil
+}
+
+// SetStatus moves a product to a new lifecycle status, records the
+// transition and publishes ProductStatusChanged. Setting the current status
+// is a no-op.
+func (s *ProductService) SetStatus(ctx context.Context, p model.Product, to, reason string) error {
+	if p.Status == to {
+		return nil
+	}
+	if err := s.products.SetStatus(ctx, p.ID, p.Status, to, reason); err != nil {
+		return err
+	}
+	s.publish(ctx, ProductEvent{
+		Type:        ProductStatusChanged,
+		ProductID:   p.ID,
+		ProductCode: p.ProductCode,
+		At:          time.Now(),
+		FromStatus:  p.Status,
+		ToStatus:    to,
+	})
+	return nil
+}
+
+// Product returns the stored product with the given code.
+func (s *ProductService) Product(ctx context.Context, code string) (*model.Product, error) {
+	return s.products.FindByCode(ctx, code)
+}
+
+// Products lists the stored products matching f.
+func (s *ProductService) Products(ctx context.Context, f model.ProductFilter) ([]model.Product, error) {
+	return s.products.List(ctx, f)
+}
+
+// EachProduct calls fn with every stored product, a batch at a time.
+func (s *ProductService) EachProduct(ctx context.Context, fn func([]model.Product) error) error {
+	return s.products.InBatches(ctx, 200, fn)
+}
+
+// EachProductWithDetails calls fn with every stored product and its
+// details, a page at a time, so that an export never holds the whole
+// catalog in one query.
+func (s *ProductService) EachProductWithDetails(ctx context.Context, fn func([]model.Product) error) error {
+	const pageSize = 200
+	for offset := 0; ; offset += pageSize {
+		page, err := s.products.List(ctx, model.ProductFilter{WithDetails: true, Limit: pageSize, Offset: offset})
+		if err != nil {
+			return err
+		}
+		if len(page) > 0 {
+			if err := fn(page); err != nil {
+				return err
+			}
+		}
+		if len(page) < pageSize {
+			return nil
+		}
+	}
+}
+
+// Variants returns every colorway of the model a product belongs to, or
+// none for an ungrouped product.
+func (s *ProductService) Variants(ctx context.Context, code string) ([]model.Product, error) {
+	return s.products.Variants(ctx, code)
+}
+
+// StatusHistory returns a product's lifecycle transitions, oldest first.
+func (s *ProductService) StatusHistory(ctx context.Context, code string) ([]model.ProductStatusChange, error) {
+	p, err := s.products.FindByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	return s.products.StatusHistory(ctx, p.ID)
+}
+
+// ByKeyword returns products linked to a keyword, normalized first.
+func (s *ProductService) ByKeyword(ctx context.Context, kw string, limit int) ([]model.Product, error) {
+	return s.products.ByKeyword(ctx, keyword.Normalize(kw), limit)
+}
+
+// ReindexKeywords re-extracts and relinks the keywords of every stored
+// product and returns how many products were processed.
+func (s *ProductService) ReindexKeywords(ctx context.Context) (int, error) {
+	count := 0
+	err := s.EachProduct(ctx, func(products []model.Product) error {
+		for _, p := range products {
+			paths, err := s.products.CategoryPaths(ctx, p.ProductCode)
+			if err != nil {
+				return err
+			}
+			if err := s.products.SetKeywords(ctx, p.ID, keyword.Extract(p, paths)); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Search finds products whose text or reviews contain every term of q.
+func (s *ProductService) Search(ctx context.Context, q string, limit int) ([]search.Result, error) {
+	terms := search.Terms(q)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	hits, err := s.products.Search(ctx, terms, limit)
+	if err != nil {
+		return nil, err
+	}
+	return search.Results(hits, terms), nil
+}
+
+// StoreURLs records discovered product URLs and fills in their IDs.
+func (s *ProductService) StoreURLs(ctx context.Context, urls []model.ProductURL) error {
+	return s.urls.Store(ctx, urls)
+}
+
+// DueURLs returns up to budget known URLs whose recrawl is due.
+func (s *ProductService) DueURLs(ctx context.Context, now time.Time, budget int) ([]model.ProductURL, error) {
+	return s.urls.Due(ctx, now, budget)
+}
+
+// MissingFromListings returns URLs of live products listed under seeds
+// before since but not since.
+func (s *ProductService) MissingFromListings(ctx context.Context, seeds []string, since time.Time, limit int) ([]model.ProductURL, error) {
+	return s.urls.MissingFromListings(ctx, seeds, since, limit)
+}
+
+// Schedule returns a URL's recrawl schedule, or a new unsaved one.
+func (s *ProductService) Schedule(ctx context.Context, productURLID uint) (*model.CrawlSchedule, error) {
+	return s.urls.Schedule(ctx, productURLID)
+}
+
+func (s *ProductService) SaveSchedule(ctx context.Context, schedule *model.CrawlSchedule) error {
+	return s.urls.SaveSchedule(ctx, schedule)
+}
+
+// StartRun records a new crawl run and fills in its ID.
+func (s *ProductService) StartRun(ctx context.Context, run *model.CrawlRun) error {
+	return s.runs.Create(ctx, run)
+}
+
+func (s *ProductService) SaveRun(ctx context.Context, run *model.CrawlRun) error {
+	return s.runs.Save(ctx, run)
+}
+
+// Runs returns the most recent crawl runs first.
+func (s *ProductService) Runs(ctx context.Context, limit int) ([]model.CrawlRun, error) {
+	return s.runs.List(ctx, limit)
+}
+
+func (s *ProductService) Run(ctx context.Context, id uint) (*model.CrawlRun, error) {
+	return s.runs.Find(ctx, id)
+}
@@ -0,0 +1,307 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/repository"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/repository/memory"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/service"
+)
+
+func newService() (*service.ProductService, *memory.ProductRepository) {
+	products := memory.NewProductRepository()
+	return service.NewProductService(products, memory.NewProductURLRepository(products), memory.NewCrawlRunRepository()), products
+}
+
+func product(code, name string) *model.Product {
+	return &model.Product{
+		ProductCode: code,
+		Name:        name,
+		Category:    "スニーカー",
+		PriceYen:    14300,
+		DetailsURL:  "https://www.adidas.jp/" + code + ".html",
+		ContentHash: "hash-" + code,
+	}
+}
+
+func TestUpsertPublishesOnlyChanges(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newService()
+	var events []string
+	svc.Subscribe(func(_ context.Context, ev service.ProductEvent) {
+		events = append(events, ev.Type+" "+ev.ProductCode)
+	})
+
+	p := product("JI2734", "サンバ OG / Samba OG")
+	if changed, err := svc.Upsert(ctx, p); err != nil || !changed {
+		t.Fatalf("first Upsert = %v, %v", changed, err)
+	}
+	if p.ID == 0 {
+		t.Fatal("Upsert left the product ID unset")
+	}
+	if changed, err := svc.Upsert(ctx, product("JI2734", "サンバ OG / Samba OG")); err != nil || changed {
+		t.Fatalf("Upsert of an unchanged product = %v, %v", changed, err)
+	}
+	updated := product("JI2734", "サンバ OG / Samba OG")
+	updated.ContentHash = "hash-2"
+	if changed, err := svc.Upsert(ctx, updated); err != nil || !changed {
+		t.Fatalf("Upsert of a changed product = %v, %v", changed, err)
+	}
+
+	want := []string{"created JI2734", "updated JI2734"}
+	if fmt.Sprint(events) != fmt.Sprint(want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+
+	byKeyword, err := svc.ByKeyword(ctx, "ＳＡＭＢＡ", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byKeyword) != 1 || byKeyword[0].ProductCode != "JI2734" {
+		t.Errorf("ByKeyword(ＳＡＭＢＡ) = %+v, want JI2734", byKeyword)
+	}
+}
+
+func TestUpsertBatchOutcomes(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newService()
+	if _, err := svc.Upsert(ctx, product("JI2734", "Samba")); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := product("IE3437", "Gazelle")
+	if _, err := svc.Upsert(ctx, changed); err != nil {
+		t.Fatal(err)
+	}
+	changed = product("IE3437", "Gazelle Indoor")
+	changed.ContentHash = "hash-2"
+
+	results, err := svc.UpsertBatch(ctx, []*model.Product{product("JI2734", "Samba"), changed, product("B75806", "Samba Classic")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []repository.StoreOutcome{repository.Unchanged, repository.Updated, repository.Created}
+	for i, r := range results {
+		if r.Outcome != want[i] || r.Err != nil {
+			t.Errorf("result %d = %+v, want %v", i, r, want[i])
+		}
+	}
+}
+
+func TestSetStatusRecordsTransitions(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newService()
+	p := product("JI2734", "Samba")
+	if _, err := svc.Upsert(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+	var events []service.ProductEvent
+	svc.Subscribe(func(_ context.Context, ev service.ProductEvent) { events = append(events, ev) })
+
+	if err := svc.SetStatus(ctx, *p, "delisted", "missing from listings"); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := svc.Product(ctx, "JI2734")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.SetStatus(ctx, *stored, "delisted", "again"); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := svc.StatusHistory(ctx, "JI2734")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].ToStatus != "delisted" || history[0].Reason != "missing from listings" {
+		t.Errorf("history = %+v, want one transition to delisted", history)
+	}
+	if len(events) != 1 || events[0].Type != service.ProductStatusChanged || events[0].ToStatus != "delisted" {
+		t.Errorf("events = %+v, want one status change", events)
+	}
+	if _, err := svc.StatusHistory(ctx, "XX0000"); err != repository.ErrNotFound {
+		t.Errorf("StatusHistory of an unknown code: %v, want ErrNotFound", err)
+	}
+}
+
+func TestEachProductWithDetailsPages(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newService()
+	const total = 450
+	for i := 0; i < total; i++ {
+		p := product(fmt.Sprintf("AA%04d", i), "Product")
+		p.Sizes = []model.ProductSize{{SizeLabel: "27.0cm", Availability: 3}}
+		p.AspectRatings = []model.ReviewAspectRating{{Aspect: "Comfort", Rating: 80}}
+		if _, err := svc.Upsert(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var pages []int
+	seen := map[string]bool{}
+	err := svc.EachProductWithDetails(ctx, func(products []model.Product) error {
+		pages = append(pages, len(products))
+		for _, p := range products {
+			if seen[p.ProductCode] {
+				t.Errorf("%s passed twice", p.ProductCode)
+			}
+			seen[p.ProductCode] = true
+			if len(p.Sizes) != 1 || len(p.AspectRatings) != 1 || p.AspectRatings[0].ProductID != p.ID {
+				t.Errorf("%s without its details: %+v %+v", p.ProductCode, p.Sizes, p.AspectRatings)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(pages) != "[200 200 50]" || len(seen) != total {
+		t.Errorf("pages %v covering %d products, want [200 200 50] covering %d", pages, len(seen), total)
+	}
+}
+
+func TestSearchFoldsQuery(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newService()
+	samba := product("JI2734", "ＳＡＭＢＡ OG")
+	samba.Reviews = []model.Review{{ReviewDate: time.Now(), Rating: 5, Body: "とても履きやすいスニーカー"}}
+	for _, p := range []*model.Product{samba, product("IE3437", "Gazelle")} {
+		if _, err := svc.Upsert(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := svc.Search(ctx, "samba ｽﾆｰｶｰ", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].ProductCode != "JI2734" || results[0].ReviewMatches != 1 {
+		t.Fatalf("results = %+v, want JI2734 with one matching review", results)
+	}
+	if results[0].Highlights["name"] == "" || results[0].Highlights["review"] == "" {
+		t.Errorf("highlights = %v, want name and review", results[0].Highlights)
+	}
+}
+
+func TestRecordSnapshotSkipsUnchanged(t *testing.T) {
+	ctx := context.Background()
+	svc, products := newService()
+	p := product("JI2734", "Samba")
+	if _, err := svc.Upsert(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		if err := svc.RecordSnapshot(ctx, *p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p.PriceYen = 12100
+	if err := svc.RecordSnapshot(ctx, *p); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots, err := products.Snapshots(ctx, p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("stored %d snapshots, want 2", len(snapshots))
+	}
+	last, err := products.LatestSnapshot(ctx, p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.ID != snapshots[1].ID {
+		t.Errorf("LatestSnapshot = %d, want %d", last.ID, snapshots[1].ID)
+	}
+}
+
+func TestRecordStockMarksMissingSizesSoldOut(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newService()
+	p := product("JI2734", "Samba")
+	if _, err := svc.Upsert(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+
+	record := func(sizes ...string) {
+		t.Helper()
+		p.Sizes = nil
+		for _, label := range sizes {
+			p.Sizes = append(p.Sizes, model.ProductSize{SizeLabel: label, Availability: 1})
+		}
+		if err := svc.RecordStock(ctx, *p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	record("26.5cm", "27.0cm")
+	record("26.5cm")
+	record() // a page without sizes says nothing about the stock
+
+	periods, err := svc.StockHistory(ctx, "JI2734")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, period := range periods {
+		got = append(got, period.SizeLabel+" "+period.Status)
+	}
+	want := []string{"26.5cm in_stock", "27.0cm in_stock", "27.0cm out_of_stock"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("periods = %v, want %v", got, want)
+	}
+
+	stats, err := svc.SellThrough(ctx, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 || stats[0].SizeLabel != "27.0cm" || stats[0].SellOuts != 1 || stats[0].OutOfStockNow != 1 {
+		t.Errorf("sell-through = %+v, want 27.0cm sold out once", stats)
+	}
+}
+
+func TestFrequentlyCoordinated(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newService()
+	samba, gazelle := product("JI2734", "Samba"), product("IE3437", "Gazelle")
+	for _, p := range []*model.Product{samba, gazelle} {
+		if _, err := svc.Upsert(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	samba.Coordinated = []model.CoordinatedItem{
+		{ProductNumber: "IE3437", ProductPageURL: "https://www.adidas.jp/IE3437.html"},
+		{ProductNumber: "HQ4199", ProductPageURL: "https://www.adidas.jp/HQ4199.html"},
+	}
+	for range 2 {
+		if err := svc.RecordCoordinations(ctx, *samba); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gazelle.Coordinated = []model.CoordinatedItem{{ProductNumber: "JI2734", ProductPageURL: samba.DetailsURL}}
+	if err := svc.RecordCoordinations(ctx, *gazelle); err != nil {
+		t.Fatal(err)
+	}
+
+	ranked, err := svc.FrequentlyCoordinated(ctx, "JI2734", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranked) != 2 || ranked[0].ProductCode != "IE3437" || !ranked[0].Crawled || ranked[0].Name != "Gazelle" ||
+		ranked[1].ProductCode != "HQ4199" || ranked[1].Crawled {
+		t.Fatalf("ranked = %+v, want crawled IE3437 then uncrawled HQ4199", ranked)
+	}
+
+	queued, err := svc.EnqueueCoordinated(ctx, "JI2734")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queued) != 1 || queued[0].Code != "HQ4199" || queued[0].URL != "https://www.adidas.jp/HQ4199.html" {
+		t.Errorf("queued = %+v, want HQ4199", queued)
+	}
+}
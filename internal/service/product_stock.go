@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/stock"
+)
+
+// RecordStock stores the stock of every size of a freshly fetched, stored
+// product, changed or not. Sizes seen before that the page no longer lists
+// are recorded out of stock.
+func (s *ProductService) RecordStock(ctx context.Context, p model.Product) error {
+	known, err := s.products.StockSizeLabels(ctx, p.ID)
+	if err != nil {
+		return err
+	}
+	return s.products.AddStockObservations(ctx, stock.Observe(p, known, time.Now()))
+}
+
+// StockHistory returns the stock periods of every size of a product.
+func (s *ProductService) StockHistory(ctx context.Context, code string) ([]model.SizeStockPeriod, error) {
+	p, err := s.products.FindByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	obs, err := s.products.StockChanges(ctx, p.ID, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	return stock.Periods(obs), nil
+}
+
+// SellThrough returns per-size sell-out and restock figures over every
+// product observed since the given time, the sizes that sell out first
+// first. Stretches that began before since are counted from since.
+func (s *ProductService) SellThrough(ctx context.Context, since time.Time) ([]model.SizeSellThrough, error) {
+	obs, err := s.products.StockChanges(ctx, 0, since)
+	if err != nil {
+		return nil, err
+	}
+	return stock.SellThrough(obs), nil
+}
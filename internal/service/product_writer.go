@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/metrics"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/repository"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ProductWriter buffers parsed products and upserts them in batches with
+// ProductService.UpsertBatch. A batch is flushed when it is full or when
+// the flush interval passes. While a batch is being written the buffer
+// fills up and Add blocks, which slows the fetch workers down to what the
+// database keeps up with.
+type ProductWriter struct {
+	products *ProductService
+	size     int
+	interval time.Duration
+	onStored func(ctx context.Context, p *model.Product, res UpsertResult, err error)
+
+	in   chan pendingProduct
+	done chan struct{}
+
+	inserted, updated, unchanged, failed, batches atomic.Int64
+}
+
+type pendingProduct struct {
+	ctx context.Context
+	p   *model.Product
+}
+
+// WriterStats counts the products a ProductWriter has written so far.
+type WriterStats struct {
+	Inserted  int64
+	Updated   int64
+	Unchanged int64
+	Failed    int64
+	Batches   int64
+}
+
+// NewProductWriter starts a writer flushing every size products or every
+// interval. onStored, if set, is called on the writer goroutine for every
+// product once its batch was written, with err set when the batch failed.
+func NewProductWriter(products *ProductService, size int, interval time.Duration, onStored func(ctx context.Context, p *model.Product, res UpsertResult, err error)) *ProductWriter {
+	if size < 1 {
+		size = 1
+	}
+	w := &ProductWriter{
+		products: products,
+		size:     size,
+		interval: interval,
+		onStored: onStored,
+		in:       make(chan pendingProduct, size),
+		done:     make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Add queues p to be written with the next batch. It blocks while the
+// buffer is full and returns ctx.Err() if ctx is done first. p must not be
+// touched until onStored has been called for it. Add must not be called
+// after Close.
+func (w *ProductWriter) Add(ctx context.Context, p *model.Product) error {
+	select {
+	case w.in <- pendingProduct{ctx: ctx, p: p}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close writes the products still buffered and stops the writer.
+func (w *ProductWriter) Close() {
+	close(w.in)
+	<-w.done
+}
+
+func (w *ProductWriter) Stats() WriterStats {
+	return WriterStats{
+		Inserted:  w.inserted.Load(),
+		Updated:   w.updated.Load(),
+		Unchanged: w.unchanged.Load(),
+		Failed:    w.failed.Load(),
+		Batches:   w.batches.Load(),
+	}
+}
+
+func (w *ProductWriter) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	batch := make([]pendingProduct, 0, w.size)
+	for {
+		select {
+		case pp, ok := <-w.in:
+			if !ok {
+				w.flush(batch)
+				return
+			}
+			batch = append(batch, pp)
+			if len(batch) < w.size {
+				continue
+			}
+		case <-ticker.C:
+		}
+		w.flush(batch)
+		batch = batch[:0]
+	}
+}
+
+func (w *ProductWriter) count(o repository.StoreOutcome) {
+	switch o {
+	case repository.Created:
+		w.inserted.Add(1)
+	case repository.Updated:
+		w.updated.Add(1)
+	default:
+		w.unchanged.Add(1)
+	}
+	metrics.StoredProducts.WithLabelValues(o.String()).Inc()
+}
+
+// flush writes one batch. The products were fetched already, so the batch
+// is written even when the crawl that queued it is canceled meanwhile.
+func (w *ProductWriter) flush(batch []pendingProduct) {
+	if len(batch) == 0 {
+		return
+	}
+	ctx := context.WithoutCancel(batch[0].ctx)
+	ctx, span := tracing.Start(ctx, "service.ProductService.UpsertBatch", trace.WithAttributes(attribute.Int("count", len(batch))))
+	products := make([]*model.Product, len(batch))
+	for i, pp := range batch {
+		products[i] = pp.p
+	}
+	results, err := w.products.UpsertBatch(ctx, products)
+	tracing.End(span, err)
+	w.batches.Add(1)
+
+	for i, pp := range batch {
+		var res UpsertResult
+		if err != nil {
+			w.failed.Add(1)
+			metrics.StoredProducts.WithLabelValues("failed").Inc()
+		} else {
+			res = results[i]
+			w.count(res.Outcome)
+		}
+		if w.onStored != nil {
+			w.onStored(context.WithoutCancel(pp.ctx), pp.p, res, err)
+		}
+	}
+}
@@ -1 +1,400 @@
 package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Sentiments a Classifier assigns.
+const (
+	SentimentPositive = "positive"
+	SentimentNeutral  = "neutral"
+	SentimentNegative = "negative"
+)
+
+// Analysis is what a Classifier found in one review.
+type Analysis struct {
+	Sentiment string
+	Score     float64 // polarity in [-1, 1]
+	// Topics maps each topic the review talks about to how many of its
+	// terms were found.
+	Topics map[string]int
+}
+
+// Classifier labels review text. Implementations must be safe for
+// concurrent use.
+type Classifier interface {
+	Name() string
+	Classify(text string) Analysis
+}
+
+var classifiers = map[string]func() Classifier{
+	"lexicon": func() Classifier { return NewLexiconClassifier() },
+}
+
+// RegisterClassifier makes a classifier available to NewClassifier under
+// name, replacing any classifier registered under it before.
+func RegisterClassifier(name string, factory func() Classifier) {
+	classifiers[name] = factory
+}
+
+// NewClassifier returns the classifier registered under name.
+func NewClassifier(name string) (Classifier, error) {
+	factory, ok := classifiers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown review classifier %q", name)
+	}
+	return factory(), nil
+}
+
+// LexiconClassifier scores sentiment by counting Japanese and English
+// polarity terms and finds topics by keyword lists. A negation right after
+// a term (良くない, not good) flips it.
+//
+// Latin terms match whole words only; a trailing "*" lets a term match the
+// start of a word ("durab*"). Japanese text has no word boundaries, so each
+// list takes the longest of its terms at every position ("かわいい" is not
+// also read as "いい"), and a kanji term never matches before the
+// repetition mark 々 ("色" in "色々").
+type LexiconClassifier struct {
+	Positive  []string
+	Negative  []string
+	Negators  []string
+	Topics    map[string][]string
+	Threshold float64 // |score| below this is neutral
+}
+
+// NewLexiconClassifier returns a LexiconClassifier with the built-in
+// lexicon.
+func NewLexiconClassifier() *LexiconClassifier {
+	return &LexiconClassifier{
+		Positive: []string{
+			"良い", "良く", "良かった", "いい", "よい", "よかった", "最高", "満足", "快適", "気に入", "お気に入り",
+			"おすすめ", "オススメ", "素晴らしい", "かっこいい", "カッコいい", "可愛い", "かわいい", "綺麗", "きれい",
+			"軽い", "楽", "丈夫", "しっかり", "好き", "嬉しい", "ぴったり", "ピッタリ",
+			"good", "great", "love", "loved", "loves", "perfect", "comfortable", "comfy", "excellent", "nice",
+			"recommend*", "happy", "amazing",
+		},
+		Negative: []string{
+			"悪い", "悪かった", "残念", "最悪", "不満", "痛い", "きつい", "キツい", "小さすぎ", "大きすぎ",
+			"破れ", "剥がれ", "壊れ", "ほつれ", "汚れ", "臭い", "重い", "微妙", "返品", "がっかり", "ガッカリ",
+			"bad", "poor", "disappoint*", "terrible", "uncomfortable", "broke", "broken", "ripped", "return",
+			"returned", "worst", "tight",
+		},
+		Negators: []string{"ない", "なかった", "ません", "ず", "not ", "n't ", "never "},
+		Topics: map[string][]string{
+			"sizing": {
+				"サイズ", "大きめ", "小さめ", "大きい", "小さい", "きつ", "キツ", "ゆったり", "ぴったり", "ピッタリ",
+				"幅", "甲高", "ワンサイズ", "cm", "size", "sizes", "sizing", "fit", "fits", "fitting", "tight",
+				"loose", "small", "large", "narrow", "wide",
+			},
+			"comfort": {
+				"履き心地", "着心地", "快適", "クッション", "柔らか", "疲れ", "痛", "楽", "軽い", "軽量",
+				"comfort*", "uncomfortable", "comfy", "cushion*", "soft", "pain", "painful", "light", "lightweight",
+			},
+			"durability": {
+				"耐久", "丈夫", "長持ち", "破れ", "剥がれ", "壊れ", "ほつれ", "すり減", "穴", "劣化",
+				"durab*", "lasted", "lasts", "long lasting", "long-lasting", "wear out", "wore out", "worn out",
+				"broke", "broken", "ripped", "tear", "torn",
+			},
+			"color": {
+				"色", "カラー", "白", "黒", "赤", "青", "緑", "グレー", "ベージュ", "ネイビー", "色落ち",
+				"color", "colors", "colour", "colours", "white", "black", "red", "blue", "green", "grey", "gray",
+			},
+		},
+		Threshold: 0.2,
+	}
+}
+
+func (c *LexiconClassifier) Name() string { return "lexicon" }
+
+// Classify implements Classifier.
+func (c *LexiconClassifier) Classify(text string) Analysis {
+	text = strings.ToLower(norm.NFKC.String(text))
+
+	// a negated positive term counts as negative and vice versa
+	pos, posNegated := c.count(text, c.Positive)
+	neg, negNegated := c.count(text, c.Negative)
+	pos, neg = pos+negNegated, neg+posNegated
+	score := 0.0
+	if total := pos + neg; total > 0 {
+		score = (pos - neg) / total
+	}
+
+	sentiment := SentimentNeutral
+	switch {
+	case score >= c.Threshold:
+		sentiment = SentimentPositive
+	case score <= -c.Threshold:
+		sentiment = SentimentNegative
+	}
+
+	topics := map[string]int{}
+	for topic, terms := range c.Topics {
+		if n := len(matchTerms(text, terms)); n > 0 {
+			topics[topic] = n
+		}
+	}
+	return Analysis{Sentiment: sentiment, Score: score, Topics: topics}
+}
+
+// count returns how often terms occur in text, plainly and negated.
+func (c *LexiconClassifier) count(text string, terms []string) (plain, negated float64) {
+	for _, m := range matchTerms(text, terms) {
+		if c.negated(text[:m.start], text[m.end:]) {
+			negated++
+		} else {
+			plain++
+		}
+	}
+	return plain, negated
+}
+
+// negated reports whether a negator closely follows (Japanese) or precedes
+// (English) a term.
+func (c *LexiconClassifier) negated(before, after string) bool {
+	for _, neg := range c.Negators {
+		if strings.HasSuffix(neg, " ") {
+			if strings.HasSuffix(before, neg) {
+				return true
+			}
+			continue
+		}
+		// allow a short inflection between the stem and the negator (良く|ない)
+		if i := strings.Index(after, neg); i >= 0 && len([]rune(after[:i])) <= 2 {
+			return true
+		}
+	}
+	return false
+}
+
+// termMatch is the byte range of one term occurrence.
+type termMatch struct{ start, end int }
+
+// matchTerms returns the occurrences of terms in text, scanning left to
+// right and taking the longest term that matches at each position; see
+// LexiconClassifier for the rules. text must be folded like Classify does.
+func matchTerms(text string, terms []string) []termMatch {
+	var matches []termMatch
+	for i := 0; i < len(text); {
+		end := -1
+		for _, term := range terms {
+			if e := matchAt(text, i, strings.ToLower(term)); e > end {
+				end = e
+			}
+		}
+		if end > i {
+			matches = append(matches, termMatch{i, end})
+			i = end
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+	}
+	return matches
+}
+
+// matchAt returns where term ends if it occurs in text at byte i, or -1.
+func matchAt(text string, i int, term string) int {
+	prefix := strings.HasSuffix(term, "*")
+	term = strings.TrimSuffix(term, "*")
+	if term == "" || !strings.HasPrefix(text[i:], term) {
+		return -1
+	}
+	end := i + len(term)
+
+	first, _ := utf8.DecodeRuneInString(term)
+	last, _ := utf8.DecodeLastRuneInString(term)
+	prev, _ := utf8.DecodeLastRuneInString(text[:i])
+	next, _ := utf8.DecodeRuneInString(text[end:])
+	if isLatinLetter(first) && isLatinLetter(prev) {
+		return -1
+	}
+	if isLatinLetter(last) {
+		if !prefix && isLatinLetter(next) {
+			return -1
+		}
+		for prefix && isLatinLetter(next) {
+			end += utf8.RuneLen(next)
+			next, _ = utf8.DecodeRuneInString(text[end:])
+		}
+	}
+	if unicode.Is(unicode.Han, last) && next == '々' {
+		return -1
+	}
+	return end
+}
+
+func isLatinLetter(r rune) bool {
+	return r >= 'a' && r <= 'z'
+}
+
+// ReviewSummary aggregates the tags of a product's reviews.
+type ReviewSummary struct {
+	ProductCode string         `json:"product_code"`
+	Reviews     int            `json:"reviews"`
+	Sentiment   map[string]int `json:"sentiment"`
+	AvgScore    float64        `json:"avg_score"`
+	Topics      []TopicSummary `json:"topics"`
+}
+
+// TopicSummary is how many reviews mention a topic and how they felt.
+type TopicSummary struct {
+	Topic     string         `json:"topic"`
+	Reviews   int            `json:"reviews"`
+	Sentiment map[string]int `json:"sentiment"`
+}
+
+// ReviewRepository reads reviews and stores the tags put on them.
+type ReviewRepository interface {
+	ForProduct(ctx context.Context, productID uint) ([]model.Review, error)
+	// ReplaceTags drops every tag of reviewIDs and stores tags instead.
+	ReplaceTags(ctx context.Context, reviewIDs []uint, tags []model.ReviewTag) error
+	TagCounts(ctx context.Context, productID uint) ([]model.ReviewTagCount, error)
+	TopicSentimentCounts(ctx context.Context, productID uint) ([]model.TopicSentimentCount, error)
+}
+
+// ReviewService tags reviews with sentiment and topics and aggregates the
+// tags per product.
+type ReviewService struct {
+	reviews    ReviewRepository
+	products   ProductRepository
+	classifier Classifier
+}
+
+func NewReviewService(reviews ReviewRepository, products ProductRepository, classifier Classifier) *ReviewService {
+	return &ReviewService{reviews: reviews, products: products, classifier: classifier}
+}
+
+// OnProductEvent re-tags the reviews of a created or updated product; its
+// reviews were rewritten with it. Subscribe it to a ProductService.
+// Failures are reported to onError.
+func (s *ReviewService) OnProductEvent(onError func(ProductEvent, error)) func(context.Context, ProductEvent) {
+	return func(ctx context.Context, ev ProductEvent) {
+		if ev.Type != ProductCreated && ev.Type != ProductUpdated {
+			return
+		}
+		if ev.Product != nil && len(ev.Product.Reviews) == 0 {
+			return
+		}
+		if _, err := s.AnalyzeProduct(ctx, ev.ProductID); err != nil {
+			onError(ev, err)
+		}
+	}
+}
+
+// AnalyzeProduct classifies every stored review of a product, replacing
+// earlier tags, and returns how many reviews were tagged.
+func (s *ReviewService) AnalyzeProduct(ctx context.Context, productID uint) (int, error) {
+	reviews, err := s.reviews.ForProduct(ctx, productID)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make([]uint, 0, len(reviews))
+	var tags []model.ReviewTag
+	for _, r := range reviews {
+		ids = append(ids, r.ID)
+		tags = append(tags, s.tags(r)...)
+	}
+	if err := s.reviews.ReplaceTags(ctx, ids, tags); err != nil {
+		return 0, err
+	}
+	return len(reviews), nil
+}
+
+// AnalyzeAll re-tags the reviews of every stored product and returns how
+// many reviews were tagged.
+func (s *ReviewService) AnalyzeAll(ctx context.Context) (int, error) {
+	total := 0
+	err := s.products.InBatches(ctx, 200, func(products []model.Product) error {
+		for _, p := range products {
+			n, err := s.AnalyzeProduct(ctx, p.ID)
+			if err != nil {
+				return fmt.Errorf("product %s: %w", p.ProductCode, err)
+			}
+			total += n
+		}
+		return nil
+	})
+	return total, err
+}
+
+// ProductSummary aggregates the stored tags of a product's reviews.
+func (s *ReviewService) ProductSummary(ctx context.Context, code string) (*ReviewSummary, error) {
+	product, err := s.products.FindByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.reviews.TagCounts(ctx, product.ID)
+	if err != nil {
+		return nil, err
+	}
+	breakdown, err := s.reviews.TopicSentimentCounts(ctx, product.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &ReviewSummary{ProductCode: code, Sentiment: map[string]int{}}
+	var weighted float64
+	topics := map[string]*TopicSummary{}
+	for _, c := range counts {
+		switch c.Kind {
+		case model.ReviewTagSentiment:
+			summary.Sentiment[c.Value] = c.Reviews
+			summary.Reviews += c.Reviews
+			weighted += c.AvgScore * float64(c.Reviews)
+		case model.ReviewTagTopic:
+			topics[c.Value] = &TopicSummary{Topic: c.Value, Reviews: c.Reviews, Sentiment: map[string]int{}}
+		}
+	}
+	if summary.Reviews > 0 {
+		summary.AvgScore = weighted / float64(summary.Reviews)
+	}
+	for _, b := range breakdown {
+		if t, ok := topics[b.Topic]; ok {
+			t.Sentiment[b.Sentiment] = b.Reviews
+		}
+	}
+
+	summary.Topics = make([]TopicSummary, 0, len(topics))
+	for _, t := range topics {
+		summary.Topics = append(summary.Topics, *t)
+	}
+	sort.Slice(summary.Topics, func(i, j int) bool {
+		if summary.Topics[i].Reviews != summary.Topics[j].Reviews {
+			return summary.Topics[i].Reviews > summary.Topics[j].Reviews
+		}
+		return summary.Topics[i].Topic < summary.Topics[j].Topic
+	})
+	return summary, nil
+}
+
+// tags classifies one review into its sentiment tag and one tag per topic.
+func (s *ReviewService) tags(r model.Review) []model.ReviewTag {
+	a := s.classifier.Classify(r.Title + "\n" + r.Body)
+	tags := []model.ReviewTag{{
+		ReviewID:   r.ID,
+		Kind:       model.ReviewTagSentiment,
+		Value:      a.Sentiment,
+		Score:      a.Score,
+		Classifier: s.classifier.Name(),
+	}}
+	for topic, hits := range a.Topics {
+		tags = append(tags, model.ReviewTag{
+			ReviewID:   r.ID,
+			Kind:       model.ReviewTagTopic,
+			Value:      topic,
+			Score:      float64(hits),
+			Classifier: s.classifier.Name(),
+		})
+	}
+	return tags
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLexiconTopics(t *testing.T) {
+	c := NewLexiconClassifier()
+	tests := []struct {
+		text string
+		want map[string]int
+	}{
+		{"The plastic heel cap feels cheap", map[string]int{}},
+		{"Arrived at last, and they lasted two years", map[string]int{"durability": 1}},
+		{"Great with any outfit", map[string]int{}},
+		{"Fits true to size", map[string]int{"sizing": 2}},
+		{"色々なコーデに合わせやすい", map[string]int{}},
+		{"色が綺麗で、マルチカラーも良い", map[string]int{"color": 2}},
+		{"25.5cmでぴったりでした", map[string]int{"sizing": 2}},
+		{"ＬＩＧＨＴＷＥＩＧＨＴ and very comfortable", map[string]int{"comfort": 2}},
+		{"Highly durable", map[string]int{"durability": 1}},
+	}
+	for _, tt := range tests {
+		if got := c.Classify(tt.text).Topics; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Classify(%q).Topics = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestLexiconCount(t *testing.T) {
+	c := NewLexiconClassifier()
+	tests := []struct {
+		text           string
+		terms          []string
+		plain, negated float64
+	}{
+		{"かわいい", c.Positive, 1, 0},
+		{"かっこいいし、履き心地もいい", c.Positive, 2, 0},
+		{"良くないです", c.Positive, 0, 1},
+		{"uncomfortable after an hour", c.Positive, 0, 0},
+		{"uncomfortable after an hour", c.Negative, 1, 0},
+		{"would not recommend", c.Positive, 0, 1},
+		{"i recommended them to friends", c.Positive, 1, 0},
+		{"a goodly sum", c.Positive, 0, 0},
+	}
+	for _, tt := range tests {
+		plain, negated := c.count(tt.text, tt.terms)
+		if plain != tt.plain || negated != tt.negated {
+			t.Errorf("count(%q) = %v plain, %v negated; want %v, %v", tt.text, plain, negated, tt.plain, tt.negated)
+		}
+	}
+}
+
+func TestLexiconSentiment(t *testing.T) {
+	c := NewLexiconClassifier()
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"とてもかわいい", SentimentPositive},
+		{"Uncomfortable and the sole broke", SentimentNegative},
+		{"期待したほど良くなかった", SentimentNegative},
+		{"届きました", SentimentNeutral},
+	}
+	for _, tt := range tests {
+		if got := c.Classify(tt.text).Sentiment; got != tt.want {
+			t.Errorf("Classify(%q).Sentiment = %s, want %s", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Package snapshot reduces a product to the attributes whose history is
+// kept in product_snapshots and compares two such snapshots field by field.
+package snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/fingerprint"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+)
+
+// Snapshot is the normalized state of a product's attributes. Reviews,
+// ratings, stock and lifecycle status are left out; they have their own
+// history.
+type Snapshot struct {
+	Name                       string   `json:"name"`
+	Category                   string   `json:"category"`
+	PriceYen                   float64  `json:"price_yen"`
+	SenseOfSize                string   `json:"sense_of_size"`
+	TitleDescription           string   `json:"title_description"`
+	GeneralDescription         string   `json:"general_description"`
+	ItemGeneralDescription     string   `json:"item_general_description"`
+	SpecialFunctionDescription string   `json:"special_function_description"`
+	Color                      string   `json:"color"`
+	Sizes                      []Size   `json:"sizes"`
+	Images                     []string `json:"images"`
+}
+
+// Size is one size offered, without its availability.
+type Size struct {
+	Label             string  `json:"label"`
+	ChestCM           float64 `json:"chest_cm"`
+	BackLengthCM      float64 `json:"back_length_cm"`
+	OtherMeasurements string  `json:"other_measurements"`
+	SpecialFunctions  string  `json:"special_functions"`
+}
+
+// Of returns the snapshot of p. Text is whitespace-normalized, image URLs
+// are reduced to fingerprint.ImageKey and collections are sorted, so that an
+// unchanged page yields an equal snapshot.
+func Of(p model.Product) Snapshot {
+	s := Snapshot{
+		Name:                       clean(p.Name),
+		Category:                   clean(p.Category),
+		PriceYen:                   p.PriceYen,
+		SenseOfSize:                clean(p.SenseOfSize),
+		TitleDescription:           clean(p.TitleDescription),
+		GeneralDescription:         clean(p.GeneralDescription),
+		ItemGeneralDescription:     clean(p.ItemGeneralDescription),
+		SpecialFunctionDescription: clean(p.SpecialFunctionDescription),
+		Color:                      clean(p.Color),
+		Sizes:                      []Size{},
+		Images:                     []string{},
+	}
+
+	seen := map[string]bool{}
+	for _, ps := range p.Sizes {
+		label := clean(ps.SizeLabel)
+		if seen[label] {
+			continue
+		}
+		seen[label] = true
+		s.Sizes = append(s.Sizes, Size{
+			Label:             label,
+			ChestCM:           ps.ChestCM,
+			BackLengthCM:      ps.BackLengthCM,
+			OtherMeasurements: clean(ps.OtherMeasurements),
+			SpecialFunctions:  clean(ps.SpecialFunctions),
+		})
+	}
+	sort.Slice(s.Sizes, func(i, j int) bool { return s.Sizes[i].Label < s.Sizes[j].Label })
+
+	images := map[string]bool{}
+	for _, img := range p.Images {
+		images[fingerprint.ImageKey(img.URL)] = true
+	}
+	for u := range images {
+		s.Images = append(s.Images, u)
+	}
+	sort.Strings(s.Images)
+	return s
+}
+
+// Hash returns a stable hash of s, to tell whether it differs from the
+// last stored snapshot.
+func (s Snapshot) Hash() string {
+	raw, _ := json.Marshal(s)
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// Change is one field that differs between two snapshots. Scalar fields
+// have From and To; images and the set of sizes have Added and Removed.
+// Measurements of a size offered in both are reported as
+// "sizes[<label>].<field>".
+type Change struct {
+	Field   string   `json:"field"`
+	From    any      `json:"from,omitempty"`
+	To      any      `json:"to,omitempty"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// Diff returns the changes from a to b, in field order. It is empty when
+// they are equal.
+func Diff(a, b Snapshot) []Change {
+	var changes []Change
+	scalar := func(field string, from, to any) {
+		if from != to {
+			changes = append(changes, Change{Field: field, From: from, To: to})
+		}
+	}
+	scalar("name", a.Name, b.Name)
+	scalar("category", a.Category, b.Category)
+	scalar("price_yen", a.PriceYen, b.PriceYen)
+	scalar("sense_of_size", a.SenseOfSize, b.SenseOfSize)
+	scalar("title_description", a.TitleDescription, b.TitleDescription)
+	scalar("general_description", a.GeneralDescription, b.GeneralDescription)
+	scalar("item_general_description", a.ItemGeneralDescription, b.ItemGeneralDescription)
+	scalar("special_function_description", a.SpecialFunctionDescription, b.SpecialFunctionDescription)
+	scalar("color", a.Color, b.Color)
+
+	sizesA := make(map[string]Size, len(a.Sizes))
+	labelsA := make([]string, 0, len(a.Sizes))
+	for _, s := range a.Sizes {
+		sizesA[s.Label] = s
+		labelsA = append(labelsA, s.Label)
+	}
+	labelsB := make([]string, 0, len(b.Sizes))
+	for _, s := range b.Sizes {
+		labelsB = append(labelsB, s.Label)
+	}
+	if added, removed := setDiff(labelsA, labelsB); len(added)+len(removed) > 0 {
+		changes = append(changes, Change{Field: "sizes", Added: added, Removed: removed})
+	}
+	for _, sb := range b.Sizes {
+		sa, ok := sizesA[sb.Label]
+		if !ok {
+			continue
+		}
+		prefix := fmt.Sprintf("sizes[%s].", sb.Label)
+		scalar(prefix+"chest_cm", sa.ChestCM, sb.ChestCM)
+		scalar(prefix+"back_length_cm", sa.BackLengthCM, sb.BackLengthCM)
+		scalar(prefix+"other_measurements", sa.OtherMeasurements, sb.OtherMeasurements)
+		scalar(prefix+"special_functions", sa.SpecialFunctions, sb.SpecialFunctions)
+	}
+
+	if added, removed := setDiff(a.Images, b.Images); len(added)+len(removed) > 0 {
+		changes = append(changes, Change{Field: "images", Added: added, Removed: removed})
+	}
+	return changes
+}
+
+// setDiff returns the elements only in b and those only in a, sorted.
+func setDiff(a, b []string) (added, removed []string) {
+	inA := make(map[string]bool, len(a))
+	for _, v := range a {
+		inA[v] = true
+	}
+	inB := make(map[string]bool, len(b))
+	for _, v := range b {
+		inB[v] = true
+		if !inA[v] {
+			added = append(added, v)
+		}
+	}
+	for _, v := range a {
+		if !inB[v] {
+			removed = append(removed, v)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+func clean(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
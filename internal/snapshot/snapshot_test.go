@@ -0,0 +1,125 @@
+package snapshot
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+)
+
+const cdn = "https://assets.adidas.com/images/"
+
+func samba() Snapshot {
+	return Of(model.Product{
+		Name:     "サンバ OG / Samba OG",
+		Category: "オリジナルス シューズ",
+		PriceYen: 14300,
+		Color:    "コアブラック",
+		Sizes: []model.ProductSize{
+			{SizeLabel: "27.0cm", Availability: 0},
+			{SizeLabel: "26.0cm", Availability: 3},
+		},
+		Images: []model.ProductImage{
+			{URL: cdn + "w_600,f_auto,q_auto/54c6/Samba_OG_01.jpg?sh=1"},
+			{URL: cdn + "w_600,f_auto,q_auto/8d1e/Samba_OG_02.jpg"},
+		},
+	})
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		a, b   Snapshot
+		modify func(s *Snapshot)
+		want   []Change
+	}{
+		{
+			name: "unchanged",
+			a:    samba(), b: samba(),
+			want: nil,
+		},
+		{
+			name: "empty previous snapshot",
+			a:    Snapshot{}, b: samba(),
+			want: []Change{
+				{Field: "name", From: "", To: "サンバ OG / Samba OG"},
+				{Field: "category", From: "", To: "オリジナルス シューズ"},
+				{Field: "price_yen", From: 0.0, To: 14300.0},
+				{Field: "color", From: "", To: "コアブラック"},
+				{Field: "sizes", Added: []string{"26.0cm", "27.0cm"}},
+				{Field: "images", Added: []string{cdn + "54c6/Samba_OG_01.jpg", cdn + "8d1e/Samba_OG_02.jpg"}},
+			},
+		},
+		{
+			name: "empty product against the zero snapshot",
+			a:    Of(model.Product{}), b: Snapshot{},
+			want: nil,
+		},
+		{
+			name: "price and color",
+			a:    samba(), b: samba(),
+			modify: func(s *Snapshot) {
+				s.PriceYen = 12870
+				s.Color = "クラウドホワイト"
+			},
+			want: []Change{
+				{Field: "price_yen", From: 14300.0, To: 12870.0},
+				{Field: "color", From: "コアブラック", To: "クラウドホワイト"},
+			},
+		},
+		{
+			name: "sizes added, removed and measured",
+			a:    samba(), b: samba(),
+			modify: func(s *Snapshot) {
+				s.Sizes[0].ChestCM = 98
+				s.Sizes[1] = Size{Label: "28.0cm"}
+			},
+			want: []Change{
+				{Field: "sizes", Added: []string{"28.0cm"}, Removed: []string{"27.0cm"}},
+				{Field: "sizes[26.0cm].chest_cm", From: 0.0, To: 98.0},
+			},
+		},
+		{
+			name: "image replaced",
+			a:    samba(), b: samba(),
+			modify: func(s *Snapshot) {
+				s.Images[1] = cdn + "9f3a/Samba_OG_03.jpg"
+			},
+			want: []Change{
+				{Field: "images", Added: []string{cdn + "9f3a/Samba_OG_03.jpg"}, Removed: []string{cdn + "8d1e/Samba_OG_02.jpg"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.modify != nil {
+				tt.modify(&tt.b)
+			}
+			if got := Diff(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOfIgnoresOrderAndImageTransformations(t *testing.T) {
+	a := samba()
+	b := Of(model.Product{
+		Name:     " サンバ OG /  Samba OG",
+		Category: "オリジナルス シューズ",
+		PriceYen: 14300,
+		Color:    "コアブラック",
+		Sizes: []model.ProductSize{
+			{SizeLabel: "26.0cm", Availability: 0},
+			{SizeLabel: "27.0cm", Availability: 5},
+			{SizeLabel: "27.0cm"},
+		},
+		Images: []model.ProductImage{
+			{URL: cdn + "h_840,f_webp/8d1e/Samba_OG_02.jpg"},
+			{URL: cdn + "54c6/Samba_OG_01.jpg"},
+		},
+	})
+	if a.Hash() != b.Hash() {
+		t.Errorf("snapshots differ: %+v", Diff(a, b))
+	}
+}
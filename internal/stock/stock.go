@@ -0,0 +1,179 @@
+// Package stock turns the sizes of fetched products into stock
+// observations and derives per-size stock periods and sell-through
+// figures from them.
+package stock
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+)
+
+// Observe returns one observation per size of p. A size is in stock when
+// its Availability is positive; the DOM only tells available from sold
+// out, the availability API also gives a quantity. Sizes in known, the
+// sizes observed before, that p no longer lists are out of stock, unless
+// p lists no sizes at all, which says more about the fetch than the stock.
+func Observe(p model.Product, known []string, at time.Time) []model.SizeStockObservation {
+	obs := make([]model.SizeStockObservation, 0, max(len(p.Sizes), len(known)))
+	seen := map[string]bool{}
+	for _, s := range p.Sizes {
+		if seen[s.SizeLabel] {
+			continue
+		}
+		seen[s.SizeLabel] = true
+		status := model.StockOutOfStock
+		if s.Availability > 0 {
+			status = model.StockInStock
+		}
+		obs = append(obs, model.SizeStockObservation{
+			ProductID:  p.ID,
+			SizeLabel:  s.SizeLabel,
+			Status:     status,
+			Quantity:   s.Availability,
+			ObservedAt: at,
+		})
+	}
+	if len(p.Sizes) == 0 {
+		return obs
+	}
+	for _, label := range known {
+		if !seen[label] {
+			obs = append(obs, model.SizeStockObservation{
+				ProductID:  p.ID,
+				SizeLabel:  label,
+				Status:     model.StockOutOfStock,
+				ObservedAt: at,
+			})
+		}
+	}
+	return obs
+}
+
+// Periods collapses the observations of one product into stretches of
+// unchanged status per size, ordered by size label and time.
+func Periods(obs []model.SizeStockObservation) []model.SizeStockPeriod {
+	var periods []model.SizeStockPeriod
+	for _, series := range bySize(obs) {
+		for _, o := range series {
+			if n := len(periods); n > 0 && periods[n-1].SizeLabel == o.SizeLabel {
+				if periods[n-1].Status == o.Status {
+					continue
+				}
+				until := o.ObservedAt
+				periods[n-1].Until = &until
+			}
+			periods = append(periods, model.SizeStockPeriod{SizeLabel: o.SizeLabel, Status: o.Status, Since: o.ObservedAt})
+		}
+	}
+	return periods
+}
+
+// SellThrough aggregates observations of any number of products per size
+// label. A sell-out is a size seen out of stock after being seen in stock;
+// its duration runs from the start of that in-stock stretch. A restock is
+// the reverse. Sizes that sell out first most often come first.
+func SellThrough(obs []model.SizeStockObservation) []model.SizeSellThrough {
+	type totals struct {
+		model.SizeSellThrough
+		sellOutHours, restockHours float64
+	}
+	bySizeLabel := map[string]*totals{}
+	firstSellOut := map[uint]time.Time{}        // product -> earliest sell-out
+	sellOuts := map[uint]map[string]time.Time{} // product -> size -> first sell-out
+
+	for _, series := range bySize(obs) {
+		label, productID := series[0].SizeLabel, series[0].ProductID
+		t := bySizeLabel[label]
+		if t == nil {
+			t = &totals{SizeSellThrough: model.SizeSellThrough{SizeLabel: label}}
+			bySizeLabel[label] = t
+		}
+		t.Products++
+
+		since := series[0].ObservedAt
+		for i := 1; i < len(series); i++ {
+			prev, cur := series[i-1], series[i]
+			if prev.Status == cur.Status {
+				continue
+			}
+			hours := cur.ObservedAt.Sub(since).Hours()
+			if cur.Status == model.StockOutOfStock {
+				t.SellOuts++
+				t.sellOutHours += hours
+				if sellOuts[productID] == nil {
+					sellOuts[productID] = map[string]time.Time{}
+				}
+				if _, ok := sellOuts[productID][label]; !ok {
+					sellOuts[productID][label] = cur.ObservedAt
+				}
+				if first, ok := firstSellOut[productID]; !ok || cur.ObservedAt.Before(first) {
+					firstSellOut[productID] = cur.ObservedAt
+				}
+			} else {
+				t.Restocks++
+				t.restockHours += hours
+			}
+			since = cur.ObservedAt
+		}
+		if series[len(series)-1].Status == model.StockOutOfStock {
+			t.OutOfStockNow++
+		}
+	}
+
+	for productID, sizes := range sellOuts {
+		for label, at := range sizes {
+			if at.Equal(firstSellOut[productID]) {
+				bySizeLabel[label].FirstSellOuts++
+			}
+		}
+	}
+
+	out := make([]model.SizeSellThrough, 0, len(bySizeLabel))
+	for _, t := range bySizeLabel {
+		if t.SellOuts > 0 {
+			t.AvgHoursToSellOut = t.sellOutHours / float64(t.SellOuts)
+		}
+		if t.Restocks > 0 {
+			t.AvgHoursToRestock = t.restockHours / float64(t.Restocks)
+		}
+		out = append(out, t.SizeSellThrough)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.FirstSellOuts != b.FirstSellOuts {
+			return a.FirstSellOuts > b.FirstSellOuts
+		}
+		if a.SellOuts != b.SellOuts {
+			return a.SellOuts > b.SellOuts
+		}
+		return a.SizeLabel < b.SizeLabel
+	})
+	return out
+}
+
+// bySize groups observations into one time-ordered series per product and
+// size, ordered by product and size label.
+func bySize(obs []model.SizeStockObservation) [][]model.SizeStockObservation {
+	sorted := append([]model.SizeStockObservation(nil), obs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.ProductID != b.ProductID {
+			return a.ProductID < b.ProductID
+		}
+		if a.SizeLabel != b.SizeLabel {
+			return a.SizeLabel < b.SizeLabel
+		}
+		return a.ObservedAt.Before(b.ObservedAt)
+	})
+
+	var series [][]model.SizeStockObservation
+	for i, o := range sorted {
+		if i == 0 || o.ProductID != sorted[i-1].ProductID || o.SizeLabel != sorted[i-1].SizeLabel {
+			series = append(series, nil)
+		}
+		series[len(series)-1] = append(series[len(series)-1], o)
+	}
+	return series
+}
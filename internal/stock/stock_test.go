@@ -0,0 +1,171 @@
+package stock
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+)
+
+var t0 = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func at(hours int) time.Time { return t0.Add(time.Duration(hours) * time.Hour) }
+
+// fetch returns the observations of a fetch of product id listing sizes,
+// each label mapped to its availability, after known sizes were seen.
+func fetch(id uint, sizes map[string]float64, known []string, hours int) []model.SizeStockObservation {
+	p := model.Product{ID: id}
+	for label, availability := range sizes {
+		p.Sizes = append(p.Sizes, model.ProductSize{SizeLabel: label, Availability: availability})
+	}
+	return Observe(p, known, at(hours))
+}
+
+func TestObserve(t *testing.T) {
+	tests := []struct {
+		name  string
+		sizes []model.ProductSize
+		known []string
+		want  map[string]string
+	}{
+		{"in and out of stock", []model.ProductSize{{SizeLabel: "26.0cm", Availability: 2}, {SizeLabel: "27.0cm"}}, nil,
+			map[string]string{"26.0cm": model.StockInStock, "27.0cm": model.StockOutOfStock}},
+		{"vanished size", []model.ProductSize{{SizeLabel: "26.0cm", Availability: 1}}, []string{"26.0cm", "27.0cm"},
+			map[string]string{"26.0cm": model.StockInStock, "27.0cm": model.StockOutOfStock}},
+		{"no sizes listed", nil, []string{"26.0cm", "27.0cm"}, map[string]string{}},
+		{"duplicate label", []model.ProductSize{{SizeLabel: "26.0cm", Availability: 1}, {SizeLabel: "26.0cm"}}, nil,
+			map[string]string{"26.0cm": model.StockInStock}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[string]string{}
+			for _, o := range Observe(model.Product{ID: 1, Sizes: tt.sizes}, tt.known, t0) {
+				if o.ProductID != 1 || !o.ObservedAt.Equal(t0) {
+					t.Errorf("observation %+v not of product 1 at %s", o, t0)
+				}
+				got[o.SizeLabel] = o.Status
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Observe = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPeriods(t *testing.T) {
+	until := func(hours int) *time.Time { u := at(hours); return &u }
+	sizes := []string{"26.0cm", "27.0cm"}
+
+	tests := []struct {
+		name string
+		obs  [][]model.SizeStockObservation
+		want []model.SizeStockPeriod
+	}{
+		{
+			name: "unchanged",
+			obs: [][]model.SizeStockObservation{
+				fetch(1, map[string]float64{"26.0cm": 1}, nil, 0),
+				fetch(1, map[string]float64{"26.0cm": 3}, []string{"26.0cm"}, 1),
+			},
+			want: []model.SizeStockPeriod{{SizeLabel: "26.0cm", Status: model.StockInStock, Since: at(0)}},
+		},
+		{
+			name: "sold out and restocked",
+			obs: [][]model.SizeStockObservation{
+				fetch(1, map[string]float64{"26.0cm": 1, "27.0cm": 1}, nil, 0),
+				fetch(1, map[string]float64{"26.0cm": 0, "27.0cm": 1}, sizes, 1),
+				fetch(1, map[string]float64{"26.0cm": 2, "27.0cm": 1}, sizes, 3),
+			},
+			want: []model.SizeStockPeriod{
+				{SizeLabel: "26.0cm", Status: model.StockInStock, Since: at(0), Until: until(1)},
+				{SizeLabel: "26.0cm", Status: model.StockOutOfStock, Since: at(1), Until: until(3)},
+				{SizeLabel: "26.0cm", Status: model.StockInStock, Since: at(3)},
+				{SizeLabel: "27.0cm", Status: model.StockInStock, Since: at(0)},
+			},
+		},
+		{
+			name: "vanished size",
+			obs: [][]model.SizeStockObservation{
+				fetch(1, map[string]float64{"26.0cm": 1, "27.0cm": 1}, nil, 0),
+				fetch(1, map[string]float64{"26.0cm": 1}, sizes, 2),
+				fetch(1, map[string]float64{"26.0cm": 1}, sizes, 4),
+			},
+			want: []model.SizeStockPeriod{
+				{SizeLabel: "26.0cm", Status: model.StockInStock, Since: at(0)},
+				{SizeLabel: "27.0cm", Status: model.StockInStock, Since: at(0), Until: until(2)},
+				{SizeLabel: "27.0cm", Status: model.StockOutOfStock, Since: at(2)},
+			},
+		},
+		{
+			name: "empty fetch",
+			obs: [][]model.SizeStockObservation{
+				fetch(1, map[string]float64{"26.0cm": 1}, nil, 0),
+				fetch(1, nil, []string{"26.0cm"}, 1),
+			},
+			want: []model.SizeStockPeriod{{SizeLabel: "26.0cm", Status: model.StockInStock, Since: at(0)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var obs []model.SizeStockObservation
+			for _, o := range tt.obs {
+				obs = append(obs, o...)
+			}
+			if got := Periods(obs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Periods =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSellThrough(t *testing.T) {
+	sizes := []string{"26.0cm", "27.0cm"}
+	tests := []struct {
+		name string
+		obs  [][]model.SizeStockObservation
+		want []model.SizeSellThrough
+	}{
+		{
+			name: "no observations",
+			want: []model.SizeSellThrough{},
+		},
+		{
+			name: "sell-outs, a restock and a vanished size",
+			obs: [][]model.SizeStockObservation{
+				// product 1: 26.0cm sells out after 1h and is restocked 2h
+				// later, 27.0cm vanishes after 2h
+				fetch(1, map[string]float64{"26.0cm": 1, "27.0cm": 1}, nil, 0),
+				fetch(1, map[string]float64{"26.0cm": 0, "27.0cm": 1}, sizes, 1),
+				fetch(1, map[string]float64{"26.0cm": 0}, sizes, 2),
+				fetch(1, map[string]float64{"26.0cm": 4}, sizes, 3),
+				// product 2: 27.0cm sells out after 1h, 26.0cm stays
+				fetch(2, map[string]float64{"26.0cm": 1, "27.0cm": 1}, nil, 0),
+				fetch(2, map[string]float64{"26.0cm": 1, "27.0cm": 0}, sizes, 1),
+			},
+			want: []model.SizeSellThrough{
+				{SizeLabel: "27.0cm", Products: 2, SellOuts: 2, FirstSellOuts: 1, AvgHoursToSellOut: 1.5, OutOfStockNow: 2},
+				{SizeLabel: "26.0cm", Products: 2, SellOuts: 1, FirstSellOuts: 1, AvgHoursToSellOut: 1, Restocks: 1, AvgHoursToRestock: 2},
+			},
+		},
+		{
+			name: "sold out from the first observation",
+			obs: [][]model.SizeStockObservation{
+				fetch(1, map[string]float64{"26.0cm": 0}, nil, 0),
+				fetch(1, map[string]float64{"26.0cm": 0}, []string{"26.0cm"}, 5),
+			},
+			want: []model.SizeSellThrough{{SizeLabel: "26.0cm", Products: 1, OutOfStockNow: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var obs []model.SizeStockObservation
+			for _, o := range tt.obs {
+				obs = append(obs, o...)
+			}
+			if got := SellThrough(obs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SellThrough =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
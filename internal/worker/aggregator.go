@@ -1 +1,83 @@
 package worker
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Aggregator collects counters, an error breakdown and per-item durations
+// from concurrent crawl workers.
+type Aggregator struct {
+	mu        sync.Mutex
+	counts    map[string]int
+	errors    map[string]int
+	durations []time.Duration
+}
+
+func NewAggregator() *Aggregator {
+	return &Aggregator{
+		counts: map[string]int{},
+		errors: map[string]int{},
+	}
+}
+
+// Add increments the named counter by n.
+func (a *Aggregator) Add(counter string, n int) {
+	a.mu.Lock()
+	a.counts[counter] += n
+	a.mu.Unlock()
+}
+
+// Error records one failure of the given kind.
+func (a *Aggregator) Error(kind string) {
+	a.mu.Lock()
+	a.errors[kind]++
+	a.mu.Unlock()
+}
+
+// Observe records how long one item took.
+func (a *Aggregator) Observe(d time.Duration) {
+	a.mu.Lock()
+	a.durations = append(a.durations, d)
+	a.mu.Unlock()
+}
+
+func (a *Aggregator) Count(counter string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.counts[counter]
+}
+
+// Errors returns a copy of the error breakdown.
+func (a *Aggregator) Errors() map[string]int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make(map[string]int, len(a.errors))
+	for k, v := range a.errors {
+		out[k] = v
+	}
+	return out
+}
+
+// Percentile returns the p-th percentile (0-100) of the observed durations,
+// or 0 if nothing was observed.
+func (a *Aggregator) Percentile(p float64) time.Duration {
+	a.mu.Lock()
+	sorted := append([]time.Duration(nil), a.durations...)
+	a.mu.Unlock()
+
+	if len(sorted) == 0 {
+		return 0
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	idx := int(p/100*float64(len(sorted))+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
@@ -0,0 +1,79 @@
+package worker
+
+import (
+	"context"
+	"sync"
+)
+
+// Pool runs tasks on a fixed number of goroutines. Tasks may submit further
+// tasks, and each task receives the context it was submitted with so that
+// deadlines and trace spans follow the work onto the worker goroutine.
+type Pool struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []task
+	closed  bool
+	pending sync.WaitGroup
+	workers sync.WaitGroup
+}
+
+type task struct {
+	ctx context.Context
+	fn  func(context.Context)
+}
+
+// NewPool starts size workers (at least one).
+func NewPool(size int) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	p := &Pool{}
+	p.cond = sync.NewCond(&p.mu)
+	p.workers.Add(size)
+	for i := 0; i < size; i++ {
+		go p.run()
+	}
+	return p
+}
+
+// Submit queues fn to run with ctx. It never blocks.
+func (p *Pool) Submit(ctx context.Context, fn func(context.Context)) {
+	p.pending.Add(1)
+	p.mu.Lock()
+	p.queue = append(p.queue, task{ctx: ctx, fn: fn})
+	p.mu.Unlock()
+	p.cond.Signal()
+}
+
+// Wait blocks until every submitted task, including tasks submitted by other
+// tasks, has finished, then stops the workers.
+func (p *Pool) Wait() {
+	p.pending.Wait()
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	p.cond.Broadcast()
+	p.workers.Wait()
+}
+
+func (p *Pool) run() {
+	defer p.workers.Done()
+	for {
+		p.mu.Lock()
+		for len(p.queue) == 0 && !p.closed {
+			p.cond.Wait()
+		}
+		if len(p.queue) == 0 {
+			p.mu.Unlock()
+			return
+		}
+		t := p.queue[0]
+		p.queue = p.queue[1:]
+		p.mu.Unlock()
+
+		if t.ctx.Err() == nil {
+			t.fn(t.ctx)
+		}
+		p.pending.Done()
+	}
+}
@@ -1,71 +1,21 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
+	"os"
+	"time"
 
+	"github.com/jakib01/web-crawiling-golang-colly/internal/config"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/database"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/model"
+	"github.com/jakib01/web-crawiling-golang-colly/internal/service"
 	"github.com/xuri/excelize/v2"
 )
 
-// --- your data models ---
-type Product struct {
-	ID                         int            `json:"ID"`
-	ProductCode                string         `json:"ProductCode"`
-	Name                       string         `json:"Name"`
-	Category                   string         `json:"Category"`
-	PriceYen                   int            `json:"PriceYen"`
-	SenseOfSize                string         `json:"SenseOfSize"`
-	DetailsURL                 string         `json:"DetailsURL"`
-	TotalReviews               int            `json:"TotalReviews"`
-	OverallRating              float64        `json:"OverallRating"`
-	TitleDescription           string         `json:"TitleDescription"`
-	GeneralDescription         string         `json:"GeneralDescription"`
-	ItemGeneralDescription     string         `json:"ItemGeneralDescription"`
-	SpecialFunctionDescription string         `json:"SpecialFunctionDescription"`
-	Images                     []Image        `json:"Images"`
-	Sizes                      []Size         `json:"Sizes"`
-	Reviews                    []Review       `json:"Reviews"`
-	AspectRatings              []AspectRating `json:"AspectRatings"`
-	Coordinated                []Coordinated  `json:"Coordinated"`
-}
-
-type Image struct {
-	ProductID int    `json:"ProductID"`
-	URL       string `json:"URL"`
-	IsMain    bool   `json:"IsMain"`
-}
-
-type Size struct {
-	ProductID    int    `json:"ProductID"`
-	SizeLabel    string `json:"SizeLabel"`
-	Availability int    `json:"Availability"`
-}
-
-type Review struct {
-	ProductID  int     `json:"ProductID"`
-	ReviewDate string  `json:"ReviewDate"`
-	Rating     float64 `json:"Rating"`
-	Title      string  `json:"Title"`
-	Body       string  `json:"Body"`
-}
-
-type AspectRating struct {
-	ReviewID int     `json:"ReviewID"`
-	Aspect   string  `json:"Aspect"`
-	Rating   float64 `json:"Rating"`
-}
-
-type Coordinated struct {
-	SourceProductID int    `json:"SourceProductID"`
-	ProductNumber   string `json:"ProductNumber"`
-	Name            string `json:"Name"`
-	PriceYen        int    `json:"PriceYen"`
-	ImageURL        string `json:"ImageURL"`
-	ProductPageURL  string `json:"ProductPageURL"`
-}
-
 // --- helper to write one sheet ---
 func writeSheet(f *excelize.File, sheet string, header []string, rows [][]interface{}) error {
 	// Create sheet
@@ -90,46 +40,104 @@ func writeSheet(f *excelize.File, sheet string, header []string, rows [][]interf
 	return nil
 }
 
-func main() {
-	// 1) load JSON
-	data, err := ioutil.ReadFile("all_products.json")
+// loadProducts passes every stored product with its details to add, a
+// page at a time, and returns the per-size sell-through since the given
+// time, through the product service.
+func loadProducts(envFile string, since time.Time, add func([]model.Product) error) ([]model.SizeSellThrough, error) {
+	cfg, err := config.Load(envFile)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	var products []Product
-	if err := json.Unmarshal(data, &products); err != nil {
-		log.Fatal(err)
+	db, err := database.Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+	repos := database.NewRepositories(cfg, db)
+	products := service.NewProductService(repos.Products, repos.ProductURLs, repos.CrawlRuns)
+
+	ctx := context.Background()
+	if err := products.EachProductWithDetails(ctx, add); err != nil {
+		return nil, err
 	}
+	return products.SellThrough(ctx, since)
+}
+
+// loadProductsJSON reads products from a JSON dump such as all_products.json.
+func loadProductsJSON(path string) ([]model.Product, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var products []model.Product
+	err = json.Unmarshal(data, &products)
+	return products, err
+}
+
+func main() {
+	envFile := flag.String("env", ".env", "path to env file")
+	jsonFile := flag.String("json", "", "export this JSON dump instead of the database")
+	out := flag.String("out", "product_details.xlsx", "path of the workbook to write")
+	sellThroughDays := flag.Int("sell-through-days", 90, "days of stock history the SellThrough sheet covers")
+	flag.Parse()
 
-	// 2) prepare rows for each sheet
+	// 1) prepare rows for each sheet as products are loaded
 	var (
-		prodRows, imgRows, sizeRows, revRows, aspectRows, coordRows [][]interface{}
+		prodRows, imgRows, sizeRows, revRows, aspectRows, coordRows, sellRows [][]interface{}
+		count                                                                 int
 	)
-	for _, p := range products {
-		// product row
-		prodRows = append(prodRows, []interface{}{
-			p.ID, p.ProductCode, p.Name, p.Category, p.PriceYen, p.DetailsURL, p.TotalReviews, p.OverallRating,
-		})
-		// images
-		for _, img := range p.Images {
-			imgRows = append(imgRows, []interface{}{p.ID, img.URL, img.IsMain})
-		}
-		// sizes
-		for _, s := range p.Sizes {
-			sizeRows = append(sizeRows, []interface{}{p.ID, s.SizeLabel, s.Availability})
-		}
-		// reviews
-		for _, r := range p.Reviews {
-			revRows = append(revRows, []interface{}{p.ID, r.ReviewDate, r.Rating, r.Title})
-		}
-		// aspect ratings
-		for _, a := range p.AspectRatings {
-			aspectRows = append(aspectRows, []interface{}{a.ReviewID, a.Aspect, a.Rating})
+	addRows := func(products []model.Product) error {
+		for _, p := range products {
+			count++
+			// product row
+			prodRows = append(prodRows, []interface{}{
+				p.ID, p.ProductCode, p.Name, p.Category, p.PriceYen, p.DetailsURL, p.TotalReviews, p.OverallRating,
+			})
+			// images
+			for _, img := range p.Images {
+				imgRows = append(imgRows, []interface{}{p.ID, img.URL, img.IsMain})
+			}
+			// sizes
+			for _, s := range p.Sizes {
+				sizeRows = append(sizeRows, []interface{}{p.ID, s.SizeLabel, s.Availability})
+			}
+			// reviews
+			for _, r := range p.Reviews {
+				revRows = append(revRows, []interface{}{p.ID, r.ReviewDate.Format("2006-01-02"), r.Rating, r.Title})
+			}
+			// aspect ratings
+			for _, a := range p.AspectRatings {
+				aspectRows = append(aspectRows, []interface{}{p.ID, a.Aspect, a.Rating})
+			}
+			// coordinated
+			for _, c := range p.Coordinated {
+				coordRows = append(coordRows, []interface{}{p.ID, c.ProductNumber, c.Name, c.PriceYen, c.ImageURL})
+			}
 		}
-		// coordinated
-		for _, c := range p.Coordinated {
-			coordRows = append(coordRows, []interface{}{p.ID, c.ProductNumber, c.Name, c.PriceYen, c.ImageURL})
+		return nil
+	}
+
+	// 2) load products; a JSON dump has no stock history
+	var (
+		sellThrough []model.SizeSellThrough
+		err         error
+	)
+	if *jsonFile != "" {
+		var products []model.Product
+		if products, err = loadProductsJSON(*jsonFile); err == nil {
+			err = addRows(products)
 		}
+	} else {
+		sellThrough, err = loadProducts(*envFile, time.Now().AddDate(0, 0, -*sellThroughDays), addRows)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, st := range sellThrough {
+		sellRows = append(sellRows, []interface{}{
+			st.SizeLabel, st.Products, st.SellOuts, st.FirstSellOuts, st.AvgHoursToSellOut,
+			st.Restocks, st.AvgHoursToRestock, st.OutOfStockNow,
+		})
 	}
 
 	// 3) create workbook & sheets
@@ -160,7 +168,7 @@ func main() {
 		log.Fatal(err)
 	}
 	if err := writeSheet(f, "AspectRatings",
-		[]string{"ReviewID", "Aspect", "Rating"},
+		[]string{"ProductID", "Aspect", "Rating"},
 		aspectRows,
 	); err != nil {
 		log.Fatal(err)
@@ -172,9 +180,16 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if err := writeSheet(f, "SellThrough",
+		[]string{"SizeLabel", "Products", "SellOuts", "FirstSellOuts", "AvgHoursToSellOut", "Restocks", "AvgHoursToRestock", "OutOfStockNow"},
+		sellRows,
+	); err != nil {
+		log.Fatal(err)
+	}
+
 	// 4) save file
-	if err := f.SaveAs("product_details.xlsx"); err != nil {
+	if err := f.SaveAs(*out); err != nil {
 		log.Fatal(err)
 	}
-	fmt.Println("Wrote product_details.xlsx")
+	fmt.Printf("Wrote %d products to %s\n", count, *out)
 }
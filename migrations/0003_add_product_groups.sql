@@ -0,0 +1,13 @@
+CREATE TABLE product_groups
+(
+    id         SERIAL PRIMARY KEY,
+    group_key  VARCHAR(255) NOT NULL UNIQUE,
+    name       VARCHAR(500) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE products
+    ADD COLUMN product_group_id INT REFERENCES product_groups (id),
+    ADD COLUMN color            VARCHAR(100);
+CREATE INDEX idx_products_group ON products (product_group_id);
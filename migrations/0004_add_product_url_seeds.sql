@@ -0,0 +1,13 @@
+ALTER TABLE product_urls
+    ADD COLUMN seed          TEXT,
+    ADD COLUMN category_path TEXT;
+
+CREATE TABLE product_url_seeds
+(
+    id             SERIAL PRIMARY KEY,
+    product_url_id INT  NOT NULL REFERENCES product_urls (id),
+    seed           TEXT NOT NULL,
+    category_path  TEXT,
+    UNIQUE (product_url_id, seed)
+);
+CREATE INDEX idx_url_seeds_url ON product_url_seeds (product_url_id);
@@ -0,0 +1,2 @@
+ALTER TABLE product_urls
+    ADD COLUMN last_modified TIMESTAMP;
@@ -0,0 +1,14 @@
+CREATE TABLE crawl_schedules
+(
+    id              SERIAL PRIMARY KEY,
+    product_url_id  INT       NOT NULL UNIQUE REFERENCES product_urls (id),
+    last_fetched_at TIMESTAMP,
+    last_changed_at TIMESTAMP,
+    content_hash    VARCHAR(64),
+    interval        BIGINT    NOT NULL,
+    next_due_at     TIMESTAMP NOT NULL,
+    fetch_count     INT DEFAULT 0,
+    change_count    INT DEFAULT 0,
+    fail_count      INT DEFAULT 0
+);
+CREATE INDEX idx_crawl_schedules_due ON crawl_schedules (next_due_at);
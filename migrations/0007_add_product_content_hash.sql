@@ -0,0 +1,3 @@
+ALTER TABLE products
+    ADD COLUMN content_hash VARCHAR(64),
+    ADD COLUMN last_seen_at TIMESTAMP;
@@ -0,0 +1,21 @@
+CREATE TABLE crawl_runs
+(
+    id              SERIAL PRIMARY KEY,
+    started_at      TIMESTAMP   NOT NULL,
+    finished_at     TIMESTAMP,
+    mode            VARCHAR(20) NOT NULL,
+    status          VARCHAR(20) NOT NULL,
+    config_snapshot JSONB,
+    seeds           JSONB,
+    discovered      INT DEFAULT 0,
+    fetched         INT DEFAULT 0,
+    parsed          INT DEFAULT 0,
+    failed          INT DEFAULT 0,
+    stored          INT DEFAULT 0,
+    errors          JSONB,
+    duration_p50_ms BIGINT,
+    duration_p90_ms BIGINT,
+    duration_p99_ms BIGINT,
+    error_message   TEXT
+);
+CREATE INDEX idx_crawl_runs_started ON crawl_runs (started_at);
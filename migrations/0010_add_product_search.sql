@@ -0,0 +1,13 @@
+-- Trigram search over products and reviews. Postgres has no Japanese
+-- text search configuration, and trigrams work for any script.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE products
+    ADD COLUMN search_text TEXT GENERATED ALWAYS AS
+        (lower(name || ' ' || title_description || ' ' || general_description)) STORED;
+CREATE INDEX idx_products_search_trgm ON products USING gin (search_text gin_trgm_ops);
+
+ALTER TABLE reviews
+    ADD COLUMN search_text TEXT GENERATED ALWAYS AS
+        (lower(coalesce(title, '') || ' ' || coalesce(body, ''))) STORED;
+CREATE INDEX idx_reviews_search_trgm ON reviews USING gin (search_text gin_trgm_ops);
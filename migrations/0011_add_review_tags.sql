@@ -0,0 +1,12 @@
+CREATE TABLE review_tags
+(
+    id         SERIAL PRIMARY KEY,
+    review_id  INT          NOT NULL REFERENCES reviews (id) ON DELETE CASCADE,
+    kind       VARCHAR(20)  NOT NULL,
+    value      VARCHAR(50)  NOT NULL,
+    score      DOUBLE PRECISION,
+    classifier VARCHAR(50)  NOT NULL,
+    created_at TIMESTAMP
+);
+CREATE INDEX idx_review_tags_review ON review_tags (review_id);
+CREATE INDEX idx_review_tags_kind_value ON review_tags (kind, value);
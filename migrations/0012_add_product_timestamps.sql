@@ -0,0 +1,5 @@
+-- model.Product has always written these; databases created from the
+-- migrations alone lacked them.
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS created_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP;
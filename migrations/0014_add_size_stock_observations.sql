@@ -0,0 +1,10 @@
+CREATE TABLE size_stock_observations
+(
+    id          SERIAL PRIMARY KEY,
+    product_id  INT          NOT NULL REFERENCES products (id),
+    size_label  VARCHAR(20)  NOT NULL,
+    status      VARCHAR(20)  NOT NULL,
+    quantity    NUMERIC(5, 2),
+    observed_at TIMESTAMP    NOT NULL
+);
+CREATE INDEX idx_size_stock_product ON size_stock_observations (product_id, size_label, observed_at);
@@ -0,0 +1,8 @@
+-- The aspect ratings on a product page summarize all of its reviews, so
+-- they belong to the product rather than to a single review. They are
+-- scraped as percentages, which NUMERIC(3, 2) cannot hold.
+ALTER TABLE review_aspect_ratings
+    ADD COLUMN product_id INT REFERENCES products (id),
+    ALTER COLUMN review_id DROP NOT NULL,
+    ALTER COLUMN rating TYPE NUMERIC(5, 2);
+CREATE INDEX idx_aspect_product ON review_aspect_ratings (product_id);
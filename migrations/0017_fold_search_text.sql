@@ -0,0 +1,19 @@
+-- search_text was generated with lower() only, while queries are folded by
+-- NFKC as well (search.Fold), so full-width and half-width text never
+-- matched. The application now fills the columns with search.Fold; rows
+-- stored before are folded here the same way. search_name holds the folded
+-- name alone for scoring.
+ALTER TABLE products DROP COLUMN search_text;
+ALTER TABLE products
+    ADD COLUMN search_name TEXT NOT NULL DEFAULT '',
+    ADD COLUMN search_text TEXT NOT NULL DEFAULT '';
+UPDATE products
+SET search_name = lower(normalize(name, NFKC)),
+    search_text = lower(normalize(name || ' ' || title_description || ' ' || general_description, NFKC));
+CREATE INDEX idx_products_search_trgm ON products USING gin (search_text gin_trgm_ops);
+
+ALTER TABLE reviews DROP COLUMN search_text;
+ALTER TABLE reviews ADD COLUMN search_text TEXT NOT NULL DEFAULT '';
+UPDATE reviews
+SET search_text = lower(normalize(coalesce(title, '') || ' ' || coalesce(body, ''), NFKC));
+CREATE INDEX idx_reviews_search_trgm ON reviews USING gin (search_text gin_trgm_ops);
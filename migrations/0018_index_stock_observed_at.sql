@@ -0,0 +1,3 @@
+-- Sell-through reads the observations of every product within a time
+-- window.
+CREATE INDEX idx_size_stock_observed_at ON size_stock_observations (observed_at);
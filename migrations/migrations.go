@@ -0,0 +1,18 @@
+// Package migrations embeds the SQL migrations that are applied from Go.
+// The Postgres migrations in this directory are applied by hand, in order;
+// the SQLite ones in sqlite/ mirror them one for one and are applied by
+// database.Open.
+package migrations
+
+import "embed"
+
+// Postgres holds the Postgres migrations, *.sql, for tests that build a
+// schema from them.
+//
+//go:embed *.sql
+var Postgres embed.FS
+
+// SQLite holds sqlite/*.sql.
+//
+//go:embed sqlite/*.sql
+var SQLite embed.FS
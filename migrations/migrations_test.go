@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"io/fs"
+	"path"
+	"reflect"
+	"testing"
+)
+
+// TestSQLiteMirrorsPostgres checks that every Postgres migration has an
+// SQLite counterpart of the same name and no SQLite migration is extra.
+func TestSQLiteMirrorsPostgres(t *testing.T) {
+	names := func(fsys fs.FS, pattern string) []string {
+		files, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := make([]string, len(files))
+		for i, f := range files {
+			out[i] = path.Base(f)
+		}
+		return out
+	}
+
+	pg := names(Postgres, "*.sql")
+	lite := names(SQLite, "sqlite/*.sql")
+	if len(pg) == 0 {
+		t.Fatal("no Postgres migrations embedded")
+	}
+	if !reflect.DeepEqual(pg, lite) {
+		t.Errorf("SQLite migrations %v do not mirror the Postgres ones %v", lite, pg)
+	}
+}
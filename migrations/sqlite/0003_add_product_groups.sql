@@ -0,0 +1,13 @@
+CREATE TABLE product_groups
+(
+    id         INTEGER PRIMARY KEY,
+    group_key  VARCHAR(255) NOT NULL UNIQUE,
+    name       VARCHAR(500) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- SQLite adds one column per ALTER TABLE
+ALTER TABLE products ADD COLUMN product_group_id INT REFERENCES product_groups (id);
+ALTER TABLE products ADD COLUMN color VARCHAR(100);
+CREATE INDEX idx_products_group ON products (product_group_id);
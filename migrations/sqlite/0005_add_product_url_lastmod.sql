@@ -0,0 +1 @@
+ALTER TABLE product_urls ADD COLUMN last_modified TIMESTAMP;
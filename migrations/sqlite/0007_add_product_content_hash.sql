@@ -0,0 +1,2 @@
+ALTER TABLE products ADD COLUMN content_hash VARCHAR(64);
+ALTER TABLE products ADD COLUMN last_seen_at TIMESTAMP;
@@ -0,0 +1,15 @@
+ALTER TABLE products ADD COLUMN status VARCHAR(20);
+ALTER TABLE products ADD COLUMN status_changed_at TIMESTAMP;
+
+ALTER TABLE product_urls ADD COLUMN last_listed_at TIMESTAMP;
+
+CREATE TABLE product_status_history
+(
+    id          INTEGER PRIMARY KEY,
+    product_id  INT         NOT NULL REFERENCES products (id),
+    from_status VARCHAR(20),
+    to_status   VARCHAR(20) NOT NULL,
+    reason      TEXT,
+    changed_at  TIMESTAMP   NOT NULL
+);
+CREATE INDEX idx_status_history_product ON product_status_history (product_id);
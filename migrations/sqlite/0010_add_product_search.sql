@@ -0,0 +1,8 @@
+-- Substring search over products and reviews. SQLite has no trigram
+-- indexes, so searches scan; ALTER TABLE can only add VIRTUAL generated
+-- columns, and lower() folds ASCII letters only.
+ALTER TABLE products ADD COLUMN search_text TEXT GENERATED ALWAYS AS
+    (lower(name || ' ' || title_description || ' ' || general_description)) VIRTUAL;
+
+ALTER TABLE reviews ADD COLUMN search_text TEXT GENERATED ALWAYS AS
+    (lower(coalesce(title, '') || ' ' || coalesce(body, ''))) VIRTUAL;
@@ -0,0 +1,2 @@
+ALTER TABLE products ADD COLUMN created_at TIMESTAMP;
+ALTER TABLE products ADD COLUMN updated_at TIMESTAMP;
@@ -0,0 +1,11 @@
+CREATE TABLE product_snapshots
+(
+    id           INTEGER PRIMARY KEY,
+    product_id   INT         NOT NULL REFERENCES products (id),
+    crawl_run_id INT REFERENCES crawl_runs (id),
+    hash         VARCHAR(64) NOT NULL,
+    data         TEXT        NOT NULL,
+    captured_at  TIMESTAMP   NOT NULL
+);
+CREATE INDEX idx_product_snapshots_product ON product_snapshots (product_id, captured_at);
+CREATE INDEX idx_product_snapshots_run ON product_snapshots (crawl_run_id);
@@ -0,0 +1,14 @@
+CREATE TABLE coordination_edges
+(
+    id                INTEGER PRIMARY KEY,
+    source_product_id INT         NOT NULL REFERENCES products (id),
+    source_code       VARCHAR(50) NOT NULL,
+    target_code       VARCHAR(50) NOT NULL,
+    target_url        TEXT        NOT NULL,
+    seen_count        INT         NOT NULL DEFAULT 1,
+    first_seen_at     TIMESTAMP   NOT NULL,
+    last_seen_at      TIMESTAMP   NOT NULL
+);
+CREATE UNIQUE INDEX idx_coordination_pair ON coordination_edges (source_code, target_code);
+CREATE INDEX idx_coordination_edges_source_product ON coordination_edges (source_product_id);
+CREATE INDEX idx_coordination_edges_target ON coordination_edges (target_code);
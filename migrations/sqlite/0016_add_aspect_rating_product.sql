@@ -0,0 +1,16 @@
+-- SQLite cannot drop NOT NULL in place, so the table is rebuilt. Ratings
+-- are percentages, hence NUMERIC(5, 2).
+CREATE TABLE review_aspect_ratings_new
+(
+    id         INTEGER PRIMARY KEY,
+    product_id INT REFERENCES products (id),
+    review_id  INT REFERENCES reviews (id),
+    aspect     VARCHAR(100)  NOT NULL,
+    rating     NUMERIC(5, 2) NOT NULL
+);
+INSERT INTO review_aspect_ratings_new (id, review_id, aspect, rating)
+SELECT id, review_id, aspect, rating FROM review_aspect_ratings;
+DROP TABLE review_aspect_ratings;
+ALTER TABLE review_aspect_ratings_new RENAME TO review_aspect_ratings;
+CREATE INDEX idx_aspect_review ON review_aspect_ratings (review_id);
+CREATE INDEX idx_aspect_product ON review_aspect_ratings (product_id);
@@ -0,0 +1,14 @@
+-- The application now fills search_text with search.Fold, which folds by
+-- NFKC as well as case. SQLite has no NFKC, so rows stored before are only
+-- lower()-folded here until their content changes and they are rewritten.
+ALTER TABLE products DROP COLUMN search_text;
+ALTER TABLE products ADD COLUMN search_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE products ADD COLUMN search_text TEXT NOT NULL DEFAULT '';
+UPDATE products
+SET search_name = lower(name),
+    search_text = lower(name || ' ' || title_description || ' ' || general_description);
+
+ALTER TABLE reviews DROP COLUMN search_text;
+ALTER TABLE reviews ADD COLUMN search_text TEXT NOT NULL DEFAULT '';
+UPDATE reviews
+SET search_text = lower(coalesce(title, '') || ' ' || coalesce(body, ''));
@@ -0,0 +1,3 @@
+-- Short search terms and keyword listings look products up by keyword;
+-- the primary key only serves lookups by product.
+CREATE INDEX idx_prod_kw_keyword ON product_keywords (keyword_id);